@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+// accountCredentials reads an exchange's read-only API key and secret from
+// ARB_<EXCHANGE>_API_KEY and ARB_<EXCHANGE>_API_SECRET.
+func accountCredentials(name string) arbitrage.APICredentials {
+	prefix := "ARB_" + strings.ToUpper(name) + "_API_"
+	creds := arbitrage.APICredentials{Key: os.Getenv(prefix + "KEY"), Secret: os.Getenv(prefix + "SECRET")}
+	if (creds.Key == "") != (creds.Secret == "") {
+		slog.Warn("Ignoring incomplete API credentials, both the key and the secret are needed", "exchange", name,
+			"key_var", prefix+"KEY", "secret_var", prefix+"SECRET")
+		return arbitrage.APICredentials{}
+	}
+	return creds
+}
+
+// applyAccountFees replaces the configured fees of every exchange with API
+// credentials by the rates of the account's fee tier. An exchange whose rates
+// cannot be fetched keeps its configured fees.
+func applyAccountFees(ctx context.Context, exchanges []arbitrage.Exchange, fees map[string]arbitrage.ExchangeFees) {
+	for _, exchange := range exchanges {
+		provider, ok := exchange.(arbitrage.AccountFeeProvider)
+		if !ok {
+			continue
+		}
+		creds := accountCredentials(exchange.Name())
+		if !creds.Set() {
+			continue
+		}
+		accountFees, err := provider.AccountFees(ctx, creds)
+		if err != nil {
+			slog.Warn("Failed to fetch account fees, using the configured fees", "exchange", exchange.Name(), "err", err)
+			continue
+		}
+		slog.Info("Using account fees", "exchange", exchange.Name(), "taker", accountFees.Taker, "maker", accountFees.Maker)
+		fees[exchange.Name()] = accountFees
+	}
+}
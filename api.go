@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+// apiPairs is the body served by /pairs/{exchange}.
+type apiPairs struct {
+	Exchange  string                             `json:"exchange"`
+	FetchedAt time.Time                          `json:"fetched_at"`
+	Pairs     map[string]arbitrage.ExchangePrice `json:"pairs"`
+}
+
+// apiError is the body of every failed API request.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// apiHandler serves GET /opportunities, the latest cycle's opportunities as
+// the -output json document, and GET /pairs/{exchange}, the prices that
+// cycle compared for one exchange, from store, so requests never fetch
+// anything. Both answer 503 until the first cycle has finished.
+func apiHandler(store *arbitrage.Store) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /opportunities", func(w http.ResponseWriter, r *http.Request) {
+		opportunities, fetchedAt := store.Opportunities()
+		if fetchedAt.IsZero() {
+			writeAPIJSON(w, http.StatusServiceUnavailable, apiError{"no cycle has finished yet"})
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, newJSONOutput(opportunities, nil, fetchedAt))
+	})
+	mux.HandleFunc("GET /pairs/{exchange}", func(w http.ResponseWriter, r *http.Request) {
+		if store.UpdatedAt().IsZero() {
+			writeAPIJSON(w, http.StatusServiceUnavailable, apiError{"no cycle has finished yet"})
+			return
+		}
+		requested := r.PathValue("exchange")
+		name, pairs, fetchedAt, ok := store.Pairs(requested)
+		if !ok {
+			writeAPIJSON(w, http.StatusNotFound, apiError{"no pairs from " + requested + " in the latest cycle"})
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, apiPairs{Exchange: name, FetchedAt: fetchedAt.UTC(), Pairs: pairs})
+	})
+	return mux
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	// The data is public market data, so any web frontend may read it.
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Debug("Failed to write API response", "err", err)
+	}
+}
+
+// startAPIServer serves the API on addr in the background.
+func startAPIServer(addr string, store *arbitrage.Store) {
+	go func() {
+		slog.Info("Serving the opportunities API", "url", addr+"/opportunities")
+		if err := http.ListenAndServe(addr, apiHandler(store)); err != nil {
+			slog.Error("API server stopped", "err", err)
+		}
+	}()
+}
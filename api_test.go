@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+func TestAPI(t *testing.T) {
+	store := &arbitrage.Store{}
+	handler := apiHandler(store)
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		return recorder
+	}
+
+	if recorder := get("/opportunities"); recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("before the first cycle: status = %d, want 503", recorder.Code)
+	}
+
+	fetchedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	store.Update(map[string]map[string]arbitrage.ExchangePrice{
+		"Binance": {"BTC/USDT": {Symbol: "BTCUSDT", BidPrice: mustDecimal(t, "60000"), AskPrice: mustDecimal(t, "60001")}},
+	}, []arbitrage.ArbitrageOpportunity{{Symbol: "BTC/USDT", BuyExchange: "Binance", SellExchange: "Kraken"}}, fetchedAt)
+
+	recorder := get("/opportunities")
+	var output jsonOutput
+	if err := json.Unmarshal(recorder.Body.Bytes(), &output); err != nil {
+		t.Fatalf("decoding /opportunities: %v\n%s", err, recorder.Body.String())
+	}
+	if recorder.Code != http.StatusOK || output.Version != jsonOutputVersion || !output.GeneratedAt.Equal(fetchedAt) ||
+		len(output.Opportunities) != 1 || output.Opportunities[0].Symbol != "BTC/USDT" {
+		t.Errorf("/opportunities = %d %s", recorder.Code, recorder.Body.String())
+	}
+	if origin := recorder.Header().Get("Access-Control-Allow-Origin"); origin != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", origin)
+	}
+
+	recorder = get("/pairs/binance")
+	var pairs apiPairs
+	if err := json.Unmarshal(recorder.Body.Bytes(), &pairs); err != nil {
+		t.Fatalf("decoding /pairs/binance: %v\n%s", err, recorder.Body.String())
+	}
+	if recorder.Code != http.StatusOK || pairs.Exchange != "Binance" || pairs.Pairs["BTC/USDT"].Symbol != "BTCUSDT" {
+		t.Errorf("/pairs/binance = %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	if recorder := get("/pairs/Kraken"); recorder.Code != http.StatusNotFound {
+		t.Errorf("/pairs/Kraken without Kraken pairs: status = %d, want 404", recorder.Code)
+	}
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/opportunities", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /opportunities: status = %d, want 405", recorder.Code)
+	}
+}
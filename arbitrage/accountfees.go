@@ -0,0 +1,163 @@
+package arbitrage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// accountRecvWindow is how long, in milliseconds, a signed request stays
+// valid after its timestamp. It is generous enough to cover the retries of
+// getWithHeaders.
+const accountRecvWindow = "10000"
+
+// APICredentials is a read-only API key and its secret. Neither is ever
+// printed: formatting or logging the credentials only reveals whether they
+// are set.
+type APICredentials struct {
+	Key    string
+	Secret string
+}
+
+// Set reports whether both the key and the secret are present.
+func (c APICredentials) Set() bool {
+	return c.Key != "" && c.Secret != ""
+}
+
+func (c APICredentials) String() string {
+	if c.Set() {
+		return "[redacted]"
+	}
+	return "[unset]"
+}
+
+func (c APICredentials) LogValue() slog.Value {
+	return slog.StringValue(c.String())
+}
+
+// AccountFeeProvider is implemented by exchanges whose authenticated API
+// reports the account's own fee tier, which can be lower than the default
+// rates thanks to trading volume or discounts.
+type AccountFeeProvider interface {
+	Exchange
+	AccountFees(ctx context.Context, creds APICredentials) (ExchangeFees, error)
+}
+
+// sign returns the hex HMAC-SHA256 of payload keyed with secret, the
+// signature both Binance and Bybit expect.
+func sign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// nowMillis is the timestamp of signed requests, replaceable in tests.
+var nowMillis = func() int64 {
+	return time.Now().UnixMilli()
+}
+
+// BinanceAccount is the part of GET /api/v3/account that holds the
+// account's commission rates, as fractions.
+type BinanceAccount struct {
+	CommissionRates struct {
+		Maker string `json:"maker"`
+		Taker string `json:"taker"`
+	} `json:"commissionRates"`
+}
+
+// AccountFees reads the account's spot commission rates from Binance.
+func (*BinanceExchange) AccountFees(ctx context.Context, creds APICredentials) (ExchangeFees, error) {
+	query := url.Values{}
+	query.Set("omitZeroBalances", "true")
+	query.Set("recvWindow", accountRecvWindow)
+	query.Set("timestamp", strconv.FormatInt(nowMillis(), 10))
+	encoded := query.Encode()
+	apiURL := BinanceBaseURL + "/api/v3/account?" + encoded + "&signature=" + sign(creds.Secret, encoded)
+	header := http.Header{"X-Mbx-Apikey": {creds.Key}}
+
+	var account BinanceAccount
+	if err := getSignedJSON(ctx, ExchangeBinance, apiURL, "Binance account", header, &account); err != nil {
+		return ExchangeFees{}, err
+	}
+	return parseAccountFees(ExchangeBinance, account.CommissionRates.Taker, account.CommissionRates.Maker)
+}
+
+// BybitFeeRates is the response of GET /v5/account/fee-rate, one entry per
+// symbol of the category.
+type BybitFeeRates struct {
+	RetCode int    `json:"retCode"`
+	RetMsg  string `json:"retMsg"`
+	Result  struct {
+		List []struct {
+			Symbol       string `json:"symbol"`
+			TakerFeeRate string `json:"takerFeeRate"`
+			MakerFeeRate string `json:"makerFeeRate"`
+		} `json:"list"`
+	} `json:"result"`
+}
+
+// AccountFees reads the account's fee rates for BybitCategory from Bybit.
+// The rates can differ between symbols, so the highest of each is returned
+// to keep the fee-adjusted profits conservative.
+func (*BybitExchange) AccountFees(ctx context.Context, creds APICredentials) (ExchangeFees, error) {
+	query := "category=" + url.QueryEscape(BybitCategory)
+	timestamp := strconv.FormatInt(nowMillis(), 10)
+	header := http.Header{
+		"X-Bapi-Api-Key":     {creds.Key},
+		"X-Bapi-Timestamp":   {timestamp},
+		"X-Bapi-Recv-Window": {accountRecvWindow},
+		"X-Bapi-Sign":        {sign(creds.Secret, timestamp+creds.Key+accountRecvWindow+query)},
+	}
+
+	var rates BybitFeeRates
+	if err := getSignedJSON(ctx, ExchangeBybit, BybitBaseURL+"/v5/account/fee-rate?"+query, "Bybit fee rates", header, &rates); err != nil {
+		return ExchangeFees{}, err
+	}
+	if rates.RetCode != 0 {
+		return ExchangeFees{}, fmt.Errorf("Bybit returned error %d: %s", rates.RetCode, rates.RetMsg)
+	}
+	if len(rates.Result.List) == 0 {
+		return ExchangeFees{}, fmt.Errorf("Bybit returned no fee rates")
+	}
+
+	var highest ExchangeFees
+	for i, entry := range rates.Result.List {
+		fees, err := parseAccountFees(ExchangeBybit, entry.TakerFeeRate, entry.MakerFeeRate)
+		if err != nil {
+			return ExchangeFees{}, err
+		}
+		if i == 0 {
+			highest = fees
+			continue
+		}
+		highest.Taker = decimal.Max(highest.Taker, fees.Taker)
+		highest.Maker = decimal.Max(highest.Maker, fees.Maker)
+	}
+	return highest, nil
+}
+
+// parseAccountFees parses the taker and maker rates reported by exchange.
+// Maker rates can be negative rebates, taker rates cannot.
+func parseAccountFees(exchange, taker, maker string) (ExchangeFees, error) {
+	takerRate, err := decimal.NewFromString(taker)
+	if err != nil {
+		return ExchangeFees{}, fmt.Errorf("error parsing %s taker fee %q: %v", exchange, taker, err)
+	}
+	makerRate, err := decimal.NewFromString(maker)
+	if err != nil {
+		return ExchangeFees{}, fmt.Errorf("error parsing %s maker fee %q: %v", exchange, maker, err)
+	}
+	if takerRate.IsNegative() {
+		return ExchangeFees{}, fmt.Errorf("%s reported a negative taker fee %s", exchange, taker)
+	}
+	return ExchangeFees{Taker: takerRate, Maker: makerRate}, nil
+}
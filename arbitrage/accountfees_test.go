@@ -0,0 +1,109 @@
+package arbitrage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSignMatchesBinanceExample(t *testing.T) {
+	// The example from Binance's API documentation.
+	secret := "NhqPtmdSJYdKjVHjA7PZj4Mge3R5YNiP1e3UZjInClVN65XAbvqqM6A7H5fATj0j"
+	query := "symbol=LTCBTC&side=BUY&type=LIMIT&timeInForce=GTC&quantity=1&price=0.1&recvWindow=5000&timestamp=1499827319559"
+	if got, want := sign(secret, query), "c8db56825ae71d6d79447849e617115f4a920fa2acdcab2b053c4b2838bd6b71"; got != want {
+		t.Errorf("sign = %s, want %s", got, want)
+	}
+}
+
+func TestAPICredentialsAreRedacted(t *testing.T) {
+	creds := APICredentials{Key: "my-key", Secret: "my-secret"}
+	for _, formatted := range []string{fmt.Sprint(creds), fmt.Sprintf("%v", creds), creds.LogValue().String()} {
+		if strings.Contains(formatted, "my-key") || strings.Contains(formatted, "my-secret") {
+			t.Errorf("credentials formatted as %q", formatted)
+		}
+	}
+	if got := fmt.Sprint(APICredentials{}); got != "[unset]" {
+		t.Errorf("empty credentials formatted as %q, want [unset]", got)
+	}
+}
+
+func TestBinanceAccountFees(t *testing.T) {
+	defer func(old func() int64) { nowMillis = old }(nowMillis)
+	nowMillis = func() int64 { return 1700000000000 }
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/account" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		if got := r.Header.Get("X-MBX-APIKEY"); got != "key" {
+			t.Errorf("X-MBX-APIKEY = %q, want key", got)
+		}
+		query, signature, _ := strings.Cut(r.URL.RawQuery, "&signature=")
+		if want := sign("secret", query); signature != want {
+			t.Errorf("signature = %s, want %s", signature, want)
+		}
+		if r.URL.Query().Get("timestamp") != "1700000000000" {
+			t.Errorf("timestamp = %s", r.URL.Query().Get("timestamp"))
+		}
+		w.Write([]byte(`{"commissionRates":{"maker":"0.00075000","taker":"0.00090000","buyer":"0","seller":"0"}}`))
+	}))
+	defer server.Close()
+	defer func(old string) { BinanceBaseURL = old }(BinanceBaseURL)
+	BinanceBaseURL = server.URL
+
+	fees, err := (&BinanceExchange{}).AccountFees(context.Background(), APICredentials{Key: "key", Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fees.Taker.Equal(mustDecimal(t, "0.0009")) || !fees.Maker.Equal(mustDecimal(t, "0.00075")) {
+		t.Errorf("fees = %s taker, %s maker, want 0.0009 and 0.00075", fees.Taker, fees.Maker)
+	}
+}
+
+func TestBybitAccountFeesTakesTheHighestRates(t *testing.T) {
+	defer func(old func() int64) { nowMillis = old }(nowMillis)
+	nowMillis = func() int64 { return 1700000000000 }
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v5/account/fee-rate" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		if r.Header.Get("X-BAPI-API-KEY") != "key" || r.Header.Get("X-BAPI-TIMESTAMP") != "1700000000000" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		payload := r.Header.Get("X-BAPI-TIMESTAMP") + "key" + r.Header.Get("X-BAPI-RECV-WINDOW") + r.URL.RawQuery
+		if got, want := r.Header.Get("X-BAPI-SIGN"), sign("secret", payload); got != want {
+			t.Errorf("X-BAPI-SIGN = %s, want %s", got, want)
+		}
+		w.Write([]byte(`{"retCode":0,"retMsg":"OK","result":{"list":[
+			{"symbol":"BTCUSDT","takerFeeRate":"0.0006","makerFeeRate":"-0.0001"},
+			{"symbol":"ETHUSDT","takerFeeRate":"0.0008","makerFeeRate":"-0.0002"}]}}`))
+	}))
+	defer server.Close()
+	defer func(old string) { BybitBaseURL = old }(BybitBaseURL)
+	BybitBaseURL = server.URL
+
+	fees, err := (&BybitExchange{}).AccountFees(context.Background(), APICredentials{Key: "key", Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fees.Taker.Equal(mustDecimal(t, "0.0008")) || !fees.Maker.Equal(mustDecimal(t, "-0.0001")) {
+		t.Errorf("fees = %s taker, %s maker, want 0.0008 and -0.0001", fees.Taker, fees.Maker)
+	}
+}
+
+func TestBybitAccountFeesReportsAPIErrors(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/v5/account/fee-rate": `{"retCode":10003,"retMsg":"API key is invalid.","result":{}}`,
+	})
+	defer func(old string) { BybitBaseURL = old }(BybitBaseURL)
+	BybitBaseURL = server.URL
+
+	_, err := (&BybitExchange{}).AccountFees(context.Background(), APICredentials{Key: "key", Secret: "secret"})
+	if err == nil || !strings.Contains(err.Error(), "API key is invalid") {
+		t.Errorf("err = %v, want the Bybit error message", err)
+	}
+}
@@ -0,0 +1,55 @@
+package arbitrage
+
+import (
+	"log/slog"
+
+	"github.com/shopspring/decimal"
+)
+
+// baseQuantityPrecision is the number of decimal places the base quantity of
+// a hypothetical trade is rounded down to.
+const baseQuantityPrecision = 8
+
+// ApplyAmount fills in the absolute figures of an opportunity for a stake of
+// amount in quote currency. The base quantity is rounded down, never up, and
+// any quote left over from the rounding is simply not invested, so the
+// reported net profit can only understate what the prices allow.
+func ApplyAmount(opportunity ArbitrageOpportunity, amount decimal.Decimal) ArbitrageOpportunity {
+	if !opportunity.BuyPrice.IsPositive() {
+		return opportunity
+	}
+
+	// BuyPrice and SellPrice already include the taker fees.
+	quantity := amount.DivRound(opportunity.BuyPrice, DivisionPrecision).Truncate(baseQuantityPrecision)
+	cost := quantity.Mul(opportunity.BuyPrice)
+	proceeds := quantity.Mul(opportunity.SellPrice)
+
+	opportunity.Amount = amount
+	opportunity.BaseQuantity = quantity
+	opportunity.Proceeds = proceeds
+	opportunity.NetProfit = proceeds.Sub(cost)
+	return withRoundTrip(opportunity)
+}
+
+// FilterByMinNotional drops opportunities where a stake of amount is below
+// the minimum order value of either exchange, since neither leg could be
+// placed. Markets without a known minimum always pass.
+func FilterByMinNotional(opportunities []ArbitrageOpportunity, pairs map[string]map[string]ExchangePrice, amount decimal.Decimal) []ArbitrageOpportunity {
+	kept := opportunities[:0]
+	for _, opportunity := range opportunities {
+		dropped := false
+		for _, name := range []string{opportunity.BuyExchange, opportunity.SellExchange} {
+			if minimum := pairs[name][opportunity.Symbol].MinNotional; amount.LessThan(minimum) {
+				slog.Info("Dropping opportunity below the minimum order value", "symbol", opportunity.Symbol,
+					"buy_exchange", opportunity.BuyExchange, "sell_exchange", opportunity.SellExchange,
+					"exchange", name, "amount", amount, "min_notional", minimum)
+				dropped = true
+				break
+			}
+		}
+		if !dropped {
+			kept = append(kept, opportunity)
+		}
+	}
+	return kept
+}
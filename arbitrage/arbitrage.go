@@ -0,0 +1,170 @@
+// Package arbitrage fetches spot prices from several exchanges and finds
+// fee-adjusted spreads between them. The scanner command is built on it, and
+// other programs can import it to run the same fetchers and comparisons.
+//
+// Settings that the command exposes as flags, such as MakerLeg, Slippage and
+// BybitCategory, are package variables that apply to every call.
+package arbitrage
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ExchangePrice is the best bid and ask for one market. Symbol is the
+// exchange's own name for the market; Base and Quote are the canonical asset
+// codes used to match it against other exchanges. QuoteVolume is the traded
+// volume over the last 24 hours in quote currency.
+type ExchangePrice struct {
+	Symbol      string          `json:"symbol"`
+	Base        string          `json:"base"`
+	Quote       string          `json:"quote"`
+	BidPrice    decimal.Decimal `json:"bid_price"`
+	AskPrice    decimal.Decimal `json:"ask_price"`
+	QuoteVolume decimal.Decimal `json:"quote_volume"`
+	// MinNotional is the smallest order value, in quote currency, the
+	// exchange accepts on this market. Zero means no known minimum.
+	MinNotional decimal.Decimal `json:"min_notional"`
+	// ListedAt is when the market opened, for exchanges that report it.
+	ListedAt time.Time `json:"listed_at"`
+	// UpdatedAt is when the exchange last updated the market's bid or
+	// ask, for exchanges and streams that report it. Zero means unknown.
+	UpdatedAt time.Time `json:"updated_at"`
+	// TickSize is the smallest price increment of the market. Zero means
+	// the exchange doesn't report one.
+	TickSize decimal.Decimal `json:"tick_size"`
+	// RawBidPrice and RawAskPrice are the fetched prices when a
+	// PriceSmoother has replaced BidPrice and AskPrice with moving
+	// averages, and zero otherwise.
+	RawBidPrice decimal.Decimal `json:"-"`
+	RawAskPrice decimal.Decimal `json:"-"`
+}
+
+// ArbitrageOpportunity is a fee-adjusted spread that clears the profit
+// threshold: buying Symbol on BuyExchange at BuyPrice and selling it on
+// SellExchange at SellPrice. Prices already include the fee of the respective
+// exchange: the taker fee, or the maker fee on the legs named by MakerLeg
+// (buy, sell or both), which are limit orders that may not fill.
+// ProfitPercentage is expressed in percent (1.5 is 1.5%) and ProfitBps in
+// basis points (150). GrossProfitPercentage is the profit before fees and
+// slippage, from the quoted prices or, after a depth check, the average
+// fills, so the difference to ProfitPercentage is what fees and slippage
+// cost; withdrawal fees are not included either. Spread is SellPrice minus
+// BuyPrice, the fee-adjusted profit per unit of the base asset in quote
+// currency. BuyTickSize and SellTickSize are the price increments of the two
+// markets, zero if unknown.
+// Amount, BaseQuantity, Proceeds and NetProfit describe a trade of a fixed
+// quote amount and are only set when one was requested. TransferCost is the
+// withdrawal fees of moving the base and quote assets between the exchanges,
+// in quote currency; TransferCostUnknown is set when no fee data was
+// available for one of them. TimestampSkew is how far apart the two
+// exchanges' prices were taken; Stale is set when it exceeds -max-skew.
+// AvailableDepth is only set by the depth check: the quote value of the
+// smaller of the two sides of the books that is still profitable after fees.
+// RawAsk and RawBid are only set when prices are smoothed: the other prices
+// are then derived from moving averages, and these are the last ask quoted
+// on BuyExchange and bid quoted on SellExchange, before fees.
+// Decimal fields marshal to JSON as strings so no precision is lost.
+type ArbitrageOpportunity struct {
+	Symbol           string          `json:"symbol"`
+	Base             string          `json:"base"`
+	Quote            string          `json:"quote"`
+	BuyExchange      string          `json:"buy_exchange"`
+	SellExchange     string          `json:"sell_exchange"`
+	BuyPrice         decimal.Decimal `json:"buy_price"`
+	SellPrice        decimal.Decimal `json:"sell_price"`
+	BuyTickSize      decimal.Decimal `json:"buy_tick_size"`
+	SellTickSize     decimal.Decimal `json:"sell_tick_size"`
+	ProfitPercentage decimal.Decimal `json:"profit_percentage"`
+	ProfitBps        decimal.Decimal `json:"profit_bps"`
+	Spread           decimal.Decimal `json:"spread"`
+	Amount           decimal.Decimal `json:"amount"`
+	BaseQuantity     decimal.Decimal `json:"base_quantity"`
+	Proceeds         decimal.Decimal `json:"proceeds"`
+	NetProfit        decimal.Decimal `json:"net_profit"`
+	AvailableDepth   decimal.Decimal `json:"available_depth"`
+
+	GrossProfitPercentage decimal.Decimal `json:"gross_profit_percentage"`
+
+	// RoundTrip is what one unit of starting capital ends as after every
+	// fee of the trade, including withdrawal fees when they are known, and
+	// RoundTripPct is the same as a percentage gain.
+	RoundTrip    decimal.Decimal `json:"round_trip"`
+	RoundTripPct decimal.Decimal `json:"round_trip_pct"`
+
+	TransferCost        decimal.Decimal `json:"transfer_cost"`
+	TransferCostUnknown bool            `json:"transfer_cost_unknown"`
+
+	TimestampSkew Duration `json:"timestamp_skew"`
+	Stale         bool     `json:"stale"`
+
+	MakerLeg string `json:"maker_leg,omitempty"`
+
+	// SellQuote is set when the sell market is quoted in a different
+	// stablecoin than the buy market's Quote, which only happens with
+	// -treat-stables-equal. Such an opportunity carries the risk of the
+	// two stablecoins departing from the assumed rate.
+	SellQuote string `json:"sell_quote,omitempty"`
+
+	RawAsk *decimal.Decimal `json:"raw_ask,omitempty"`
+	RawBid *decimal.Decimal `json:"raw_bid,omitempty"`
+}
+
+// ExchangeFees holds the fee rates charged by an exchange, as fractions
+// (0.001 is 0.1%).
+type ExchangeFees struct {
+	Taker decimal.Decimal `json:"taker"`
+	Maker decimal.Decimal `json:"maker"`
+}
+
+const transactionFee = 0.001 // 0.1% transaction fee per exchange
+
+// DivisionPrecision is the number of decimal places kept when dividing. The
+// decimal package's default of 16 is too coarse once prices of 1e-8 and below
+// are divided, so every division here rounds to this larger precision
+// instead, leaving the package-wide decimal.DivisionPrecision untouched.
+const DivisionPrecision = 32
+
+const (
+	ExchangeBybit    = "Bybit"
+	ExchangeBinance  = "Binance"
+	ExchangeKraken   = "Kraken"
+	ExchangeOKX      = "OKX"
+	ExchangeKuCoin   = "KuCoin"
+	ExchangeCoinbase = "Coinbase"
+)
+
+// Base URLs of the exchange REST APIs and streams. The defaults are the
+// production endpoints; -base-url and -stream-url override them, and tests
+// point them at a local server.
+var (
+	BybitBaseURL    = "https://api.bybit.com"
+	BinanceBaseURL  = "https://api.binance.com"
+	KrakenBaseURL   = "https://api.kraken.com"
+	OKXBaseURL      = "https://www.okx.com"
+	KuCoinBaseURL   = "https://api.kucoin.com"
+	CoinbaseBaseURL = "https://api.exchange.coinbase.com"
+
+	BinanceFuturesBaseURL = "https://fapi.binance.com"
+
+	BinanceStreamURL   = "wss://stream.binance.com:9443/ws/!bookTicker"
+	BybitStreamBaseURL = "wss://stream.bybit.com"
+)
+
+// DefaultExchangeFees returns the fee table used when nothing more specific
+// is known: Bybit, Binance and KuCoin charge transactionFee for both makers
+// and takers.
+func DefaultExchangeFees() map[string]ExchangeFees {
+	fee := decimal.NewFromFloat(transactionFee)
+	return map[string]ExchangeFees{
+		ExchangeBybit:   {Taker: fee, Maker: fee},
+		ExchangeBinance: {Taker: fee, Maker: fee},
+		// Kraken's entry-level spot tier is noticeably more expensive.
+		ExchangeKraken: {Taker: decimal.NewFromFloat(0.004), Maker: decimal.NewFromFloat(0.0025)},
+		ExchangeOKX:    {Taker: decimal.NewFromFloat(0.001), Maker: decimal.NewFromFloat(0.0008)},
+		ExchangeKuCoin: {Taker: fee, Maker: fee},
+		// Coinbase's lowest tier costs several times more than the others.
+		ExchangeCoinbase: {Taker: decimal.NewFromFloat(0.006), Maker: decimal.NewFromFloat(0.004)},
+	}
+}
@@ -0,0 +1,733 @@
+package arbitrage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// newTestServer serves canned bodies keyed by request path and fails the
+// test on any other request.
+func newTestServer(t *testing.T, responses map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			t.Errorf("unexpected request to %s", r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func mustDecimal(t *testing.T, value string) decimal.Decimal {
+	t.Helper()
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		t.Fatalf("invalid decimal %q: %v", value, err)
+	}
+	return d
+}
+
+func assertPrice(t *testing.T, pairs map[string]ExchangePrice, key, symbol, bid, ask string) {
+	t.Helper()
+	price, ok := pairs[key]
+	if !ok {
+		t.Fatalf("missing pair %s in %v", key, pairs)
+	}
+	if price.Symbol != symbol {
+		t.Errorf("%s: symbol = %q, want %q", key, price.Symbol, symbol)
+	}
+	if !price.BidPrice.Equal(mustDecimal(t, bid)) {
+		t.Errorf("%s: bid = %s, want %s", key, price.BidPrice, bid)
+	}
+	if !price.AskPrice.Equal(mustDecimal(t, ask)) {
+		t.Errorf("%s: ask = %s, want %s", key, price.AskPrice, ask)
+	}
+}
+
+func TestGetBybitPairs(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/v5/market/instruments-info": `{"result":{"list":[
+			{"symbol":"BTCUSDT","baseCoin":"BTC","quoteCoin":"USDT","status":"Trading","lotSizeFilter":{"minOrderAmt":"5"},"priceFilter":{"tickSize":"0.10"}},
+			{"symbol":"ETHUSDT","baseCoin":"ETH","quoteCoin":"USDT","status":"Trading","launchTime":"1690000000000"},
+			{"symbol":"OLDUSDT","baseCoin":"OLD","quoteCoin":"USDT","status":"Closed"},
+			{"symbol":"ZEROUSDT","baseCoin":"ZERO","quoteCoin":"USDT","status":"Trading"},
+			{"symbol":"BTC3LUSDT","baseCoin":"BTC3L","quoteCoin":"USDT","status":"Trading"}
+		]}}`,
+		"/v5/market/tickers": `{"time":1700000000123,"result":{"list":[
+			{"symbol":"BTCUSDT","bid1Price":"60000.5","ask1Price":"60001","turnover24h":"123456789.5"},
+			{"symbol":"ETHUSDT","bid1Price":"3000","ask1Price":"3000.1","turnover24h":"1000"},
+			{"symbol":"OLDUSDT","bid1Price":"1","ask1Price":"1.1","turnover24h":"1"},
+			{"symbol":"ZEROUSDT","bid1Price":"0","ask1Price":"1","turnover24h":"1"},
+			{"symbol":"NEWUSDT","bid1Price":"1","ask1Price":"1.1","turnover24h":"1"},
+			{"symbol":"BTC3LUSDT","bid1Price":"2","ask1Price":"2.1","turnover24h":"1"}
+		]}}`,
+	})
+	defer func(old string) { BybitBaseURL = old }(BybitBaseURL)
+	BybitBaseURL = server.URL
+
+	pairs, serverTime, err := getBybitPairs(context.Background())
+	if err != nil {
+		t.Fatalf("getBybitPairs: %v", err)
+	}
+	if len(pairs) != 2 {
+		t.Fatalf("got %d pairs, want 2: %v", len(pairs), pairs)
+	}
+	assertPrice(t, pairs, "BTC/USDT", "BTCUSDT", "60000.5", "60001")
+	assertPrice(t, pairs, "ETH/USDT", "ETHUSDT", "3000", "3000.1")
+	if got := pairs["BTC/USDT"].QuoteVolume; !got.Equal(mustDecimal(t, "123456789.5")) {
+		t.Errorf("BTC/USDT quote volume = %s", got)
+	}
+	if got := pairs["BTC/USDT"].MinNotional; !got.Equal(mustDecimal(t, "5")) {
+		t.Errorf("BTC/USDT min notional = %s, want 5", got)
+	}
+	if got := pairs["ETH/USDT"].MinNotional; !got.IsZero() {
+		t.Errorf("ETH/USDT min notional = %s, want 0", got)
+	}
+	if got := pairs["BTC/USDT"].TickSize; !got.Equal(mustDecimal(t, "0.1")) {
+		t.Errorf("BTC/USDT tick size = %s, want 0.1", got)
+	}
+	if got := pairs["ETH/USDT"].ListedAt; !got.Equal(time.Unix(1690000000, 0)) {
+		t.Errorf("ETH/USDT listed at %s", got)
+	}
+	if got := pairs["BTC/USDT"].ListedAt; !got.IsZero() {
+		t.Errorf("BTC/USDT listed at %s, want unknown", got)
+	}
+	if want := time.Unix(1700000000, 123000000); !serverTime.Equal(want) {
+		t.Errorf("server time = %s, want %s", serverTime, want)
+	}
+}
+
+func TestBybitInstrumentsCache(t *testing.T) {
+	instrumentsRequests := 0
+	failTickers := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v5/market/instruments-info":
+			instrumentsRequests++
+			w.Write([]byte(`{"result":{"list":[{"symbol":"BTCUSDT","baseCoin":"BTC","quoteCoin":"USDT","status":"Trading"}]}}`))
+		case "/v5/market/tickers":
+			if failTickers {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"result":{"list":[{"symbol":"BTCUSDT","bid1Price":"60000","ask1Price":"60001"}]}}`))
+		}
+	}))
+	defer server.Close()
+	defer func(old string) { BybitBaseURL = old }(BybitBaseURL)
+	BybitBaseURL = server.URL
+
+	fetch := func() {
+		t.Helper()
+		if _, _, err := getBybitPairs(context.Background()); err != nil {
+			t.Fatalf("getBybitPairs: %v", err)
+		}
+	}
+
+	fetch()
+	fetch()
+	if instrumentsRequests != 1 {
+		t.Fatalf("instruments fetched %d times within the TTL, want 1", instrumentsRequests)
+	}
+
+	failTickers = true
+	if _, _, err := getBybitPairs(context.Background()); err == nil {
+		t.Fatal("expected an error when the tickers fail")
+	}
+	failTickers = false
+	before := instrumentsRequests
+	fetch()
+	if instrumentsRequests != before+1 {
+		t.Errorf("instruments not refetched after a tickers failure")
+	}
+
+	defer func(old time.Duration) { InstrumentsTTL = old }(InstrumentsTTL)
+	InstrumentsTTL = 0
+	before = instrumentsRequests
+	fetch()
+	if instrumentsRequests != before+1 {
+		t.Errorf("instruments cached with a zero TTL")
+	}
+}
+
+func TestGetBybitInstrumentPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			w.Write([]byte(`{"result":{"list":[{"symbol":"BTCUSDT","baseCoin":"BTC","quoteCoin":"USDT","status":"Trading"}],"nextPageCursor":"page2"}}`))
+		case "page2":
+			w.Write([]byte(`{"result":{"list":[{"symbol":"ETHUSDT","baseCoin":"ETH","quoteCoin":"USDT","status":"Trading"}],"nextPageCursor":""}}`))
+		default:
+			t.Errorf("unexpected cursor in %s", r.URL)
+		}
+	}))
+	defer server.Close()
+
+	info, err := getBybitInstrumentPages(context.Background(), server.URL+"/v5/market/instruments-info?category=linear", "Bybit instruments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var symbols []string
+	for _, instrument := range info.Result.List {
+		symbols = append(symbols, instrument.Symbol)
+	}
+	if fmt.Sprint(symbols) != "[BTCUSDT ETHUSDT]" {
+		t.Errorf("symbols = %v, want both pages", symbols)
+	}
+}
+
+func TestGetBybitInstrumentPagesStopsAtTheLimit(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"result":{"list":[],"nextPageCursor":"again"}}`))
+	}))
+	defer server.Close()
+
+	if _, err := getBybitInstrumentPages(context.Background(), server.URL+"/v5/market/instruments-info?category=spot", "Bybit instruments"); err != nil {
+		t.Fatal(err)
+	}
+	if requests != maxBybitInstrumentPages {
+		t.Errorf("made %d requests, want %d", requests, maxBybitInstrumentPages)
+	}
+}
+
+func TestGetBinancePairs(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/api/v3/exchangeInfo": `{"symbols":[
+			{"symbol":"BTCUSDT","status":"TRADING","baseAsset":"BTC","quoteAsset":"USDT","filters":[
+				{"filterType":"PRICE_FILTER","minPrice":"0.01","tickSize":"0.01000000"},
+				{"filterType":"NOTIONAL","minNotional":"5.00000000"}
+			]},
+			{"symbol":"ETHBTC","status":"TRADING","baseAsset":"ETH","quoteAsset":"BTC"},
+			{"symbol":"BADUSDT","status":"TRADING","baseAsset":"BAD","quoteAsset":"USDT"},
+			{"symbol":"HALTUSDT","status":"BREAK","baseAsset":"HALT","quoteAsset":"USDT"},
+			{"symbol":"BTCUPUSDT","status":"TRADING","baseAsset":"BTCUP","quoteAsset":"USDT"},
+			{"symbol":"LEVUSDT","status":"TRADING","baseAsset":"LEV","quoteAsset":"USDT","permissionSets":[["SPOT","LEVERAGED"]]}
+		]}`,
+		"/api/v3/ticker/bookTicker": `[
+			{"symbol":"HALTUSDT","bidPrice":"1.50","askPrice":"1.51"},
+			{"symbol":"BTCUSDT","bidPrice":"60010.00","askPrice":"60010.01"},
+			{"symbol":"ETHBTC","bidPrice":"0.05","askPrice":"0.0501"},
+			{"symbol":"BADUSDT","bidPrice":"not-a-number","askPrice":"1"},
+			{"symbol":"UNLISTED","bidPrice":"1","askPrice":"1"},
+			{"symbol":"BTCUPUSDT","bidPrice":"20","askPrice":"20.1"},
+			{"symbol":"LEVUSDT","bidPrice":"3","askPrice":"3.1"}
+		]`,
+		"/api/v3/ticker/24hr": `[
+			{"symbol":"BTCUSDT","quoteVolume":"987654321"},
+			{"symbol":"ETHBTC","quoteVolume":"42"}
+		]`,
+		"/api/v3/time": `{"serverTime":1700000000456}`,
+	})
+	defer func(old string) { BinanceBaseURL = old }(BinanceBaseURL)
+	BinanceBaseURL = server.URL
+
+	pairs, serverTime, err := getBinancePairs(context.Background())
+	if err != nil {
+		t.Fatalf("getBinancePairs: %v", err)
+	}
+	if len(pairs) != 2 {
+		t.Fatalf("got %d pairs, want 2: %v", len(pairs), pairs)
+	}
+	assertPrice(t, pairs, "BTC/USDT", "BTCUSDT", "60010", "60010.01")
+	assertPrice(t, pairs, "ETH/BTC", "ETHBTC", "0.05", "0.0501")
+	if got := pairs["ETH/BTC"]; got.Base != "ETH" || got.Quote != "BTC" {
+		t.Errorf("ETH/BTC base/quote = %s/%s", got.Base, got.Quote)
+	}
+	if got := pairs["BTC/USDT"].MinNotional; !got.Equal(mustDecimal(t, "5")) {
+		t.Errorf("BTC/USDT min notional = %s, want 5", got)
+	}
+	if got := pairs["BTC/USDT"].TickSize; !got.Equal(mustDecimal(t, "0.01")) {
+		t.Errorf("BTC/USDT tick size = %s, want 0.01", got)
+	}
+	if got := pairs["ETH/BTC"].TickSize; !got.IsZero() {
+		t.Errorf("ETH/BTC tick size = %s, want unknown", got)
+	}
+	if want := time.Unix(1700000000, 456000000); !serverTime.Equal(want) {
+		t.Errorf("server time = %s, want %s", serverTime, want)
+	}
+}
+
+func TestSetBinanceRegion(t *testing.T) {
+	defer func(region, rest, stream, futures string) {
+		BinanceRegion, BinanceBaseURL, BinanceStreamURL, BinanceFuturesBaseURL = region, rest, stream, futures
+	}(BinanceRegion, BinanceBaseURL, BinanceStreamURL, BinanceFuturesBaseURL)
+	BinanceFuturesBaseURL = "http://fapi.binance.invalid"
+
+	SetBinanceRegion(BinanceRegionUS)
+	if BinanceBaseURL != "https://api.binance.us" || BinanceStreamURL != "wss://stream.binance.us:9443/ws/!bookTicker" {
+		t.Errorf("US endpoints = %s, %s", BinanceBaseURL, BinanceStreamURL)
+	}
+	// Binance.US has no futures, so nothing is fetched.
+	rates, err := (&BinanceExchange{}).FundingRates(context.Background())
+	if err != nil || len(rates) != 0 {
+		t.Errorf("US funding rates = %v, %v; want none", rates, err)
+	}
+
+	SetBinanceRegion(BinanceRegionGlobal)
+	if BinanceBaseURL != "https://api.binance.com" {
+		t.Errorf("global REST endpoint = %s", BinanceBaseURL)
+	}
+}
+
+func TestSetProxy(t *testing.T) {
+	// The proxy receives requests for the exchange's real host and answers
+	// them itself, so the fetch only succeeds if it went through the proxy.
+	proxy := newTestServer(t, map[string]string{
+		"/api/v3/exchangeInfo":      `{"symbols":[{"symbol":"BTCUSDT","status":"TRADING","baseAsset":"BTC","quoteAsset":"USDT"}]}`,
+		"/api/v3/ticker/bookTicker": `[{"symbol":"BTCUSDT","bidPrice":"60000","askPrice":"60001"}]`,
+		"/api/v3/ticker/24hr":       `[]`,
+		"/api/v3/time":              `{"serverTime":1700000000000}`,
+	})
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(old http.RoundTripper) { HTTPClient.Transport = old }(HTTPClient.Transport)
+	defer func(old func(*http.Request) (*url.URL, error)) { streamDialer.Proxy = old }(streamDialer.Proxy)
+	SetProxy(proxyURL)
+	defer func(old string) { BinanceBaseURL = old }(BinanceBaseURL)
+	BinanceBaseURL = "http://api.binance.invalid"
+
+	pairs, _, err := getBinancePairs(context.Background())
+	if err != nil {
+		t.Fatalf("getBinancePairs: %v", err)
+	}
+	assertPrice(t, pairs, "BTC/USDT", "BTCUSDT", "60000", "60001")
+	if streamDialer.Proxy == nil {
+		t.Error("streams don't use the proxy")
+	} else if got, _ := streamDialer.Proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: "stream.binance.com"}}); got == nil || got.Host != proxyURL.Host {
+		t.Errorf("stream proxy = %v, want %s", got, proxyURL)
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	defer func(old http.Header) { RequestHeaders = old }(RequestHeaders)
+	RequestHeaders = http.Header{"User-Agent": {"scanner/1.0"}, "X-Api-Key": {"secret"}}
+
+	var v struct{}
+	if err := getSignedJSON(context.Background(), "Test", server.URL, "test", http.Header{"X-Signature": {"abc"}}, &v); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"User-Agent": "scanner/1.0", "X-Api-Key": "secret", "X-Signature": "abc"} {
+		if got.Get(name) != want {
+			t.Errorf("%s = %q, want %q", name, got.Get(name), want)
+		}
+	}
+}
+
+func TestGetBinancePairsStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	defer func(old string) { BinanceBaseURL = old }(BinanceBaseURL)
+	BinanceBaseURL = server.URL
+
+	if _, _, err := getBinancePairs(context.Background()); err == nil {
+		t.Fatal("expected an error for a 429 response")
+	}
+}
+
+func TestGetWithRetryCancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := getWithRetry(ctx, ExchangeBinance, server.URL); err == nil {
+		t.Fatal("expected an error for a cancelled request")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("cancelled request took %s to return", elapsed)
+	}
+}
+
+func TestFindArbitrage(t *testing.T) {
+	price := func(bid, ask string) ExchangePrice {
+		return ExchangePrice{Base: "X", Quote: "USDT", BidPrice: mustDecimal(t, bid), AskPrice: mustDecimal(t, ask)}
+	}
+	zeroFees := map[string]ExchangeFees{}
+	minProfit := mustDecimal(t, "0.01")
+	maxProfit := mustDecimal(t, "0.5")
+
+	pairsA := map[string]ExchangePrice{
+		"WIN/USDT":  price("99", "100"),
+		"MEH/USDT":  price("99", "100"),
+		"ZERO/USDT": price("0", "100"),
+		"ONLY/USDT": price("1", "1"),
+	}
+	pairsB := map[string]ExchangePrice{
+		// 5% above A's ask: profitable buying on A.
+		"WIN/USDT": price("105", "106"),
+		// 0.5% above A's ask: below the 1% threshold.
+		"MEH/USDT": price("100.5", "101"),
+		// Would be a huge spread if zero prices weren't skipped.
+		"ZERO/USDT": price("200", "201"),
+	}
+
+	opportunities := FindArbitrage(map[string]map[string]ExchangePrice{"A": pairsA, "B": pairsB}, zeroFees, minProfit, maxProfit)
+	if len(opportunities) != 1 {
+		t.Fatalf("got %d opportunities, want 1: %+v", len(opportunities), opportunities)
+	}
+	got := opportunities[0]
+	if got.Symbol != "WIN/USDT" || got.BuyExchange != "A" || got.SellExchange != "B" {
+		t.Errorf("unexpected opportunity %+v", got)
+	}
+	if !got.ProfitPercentage.Equal(mustDecimal(t, "5")) {
+		t.Errorf("profit = %s%%, want 5%%", got.ProfitPercentage)
+	}
+}
+
+func TestFindArbitrageAppliesFees(t *testing.T) {
+	pairsA := map[string]ExchangePrice{"BTC/USDT": {BidPrice: mustDecimal(t, "99"), AskPrice: mustDecimal(t, "100")}}
+	pairsB := map[string]ExchangePrice{"BTC/USDT": {BidPrice: mustDecimal(t, "101.5"), AskPrice: mustDecimal(t, "102")}}
+	minProfit := mustDecimal(t, "0.01")
+	maxProfit := mustDecimal(t, "0.5")
+
+	// 1.5% gross, but 0.3% + 0.3% fees push it below 1%.
+	fees := map[string]ExchangeFees{
+		"A": {Taker: mustDecimal(t, "0.003")},
+		"B": {Taker: mustDecimal(t, "0.003")},
+	}
+	if got := FindArbitrage(map[string]map[string]ExchangePrice{"A": pairsA, "B": pairsB}, fees, minProfit, maxProfit); len(got) != 0 {
+		t.Errorf("expected fees to remove the opportunity, got %+v", got)
+	}
+
+	// Fees only on the selling side still leave more than 1%.
+	fees = map[string]ExchangeFees{"B": {Taker: mustDecimal(t, "0.001")}}
+	got := FindArbitrage(map[string]map[string]ExchangePrice{"A": pairsA, "B": pairsB}, fees, minProfit, maxProfit)
+	if len(got) != 1 {
+		t.Fatalf("got %d opportunities, want 1", len(got))
+	}
+	if want := mustDecimal(t, "101.3985"); !got[0].SellPrice.Equal(want) {
+		t.Errorf("sell price = %s, want %s", got[0].SellPrice, want)
+	}
+}
+
+func TestFindArbitrageDiscardsOutliers(t *testing.T) {
+	pairsA := map[string]ExchangePrice{"NEIRO/USDT": {BidPrice: mustDecimal(t, "0.0009"), AskPrice: mustDecimal(t, "0.001")}}
+	pairsB := map[string]ExchangePrice{"NEIRO/USDT": {BidPrice: mustDecimal(t, "0.043"), AskPrice: mustDecimal(t, "0.044")}}
+
+	got := FindArbitrage(map[string]map[string]ExchangePrice{"A": pairsA, "B": pairsB}, nil, mustDecimal(t, "0.01"), mustDecimal(t, "0.5"))
+	if len(got) != 0 {
+		t.Errorf("expected the outlier to be discarded, got %+v", got)
+	}
+}
+
+func TestFindArbitragePicksBestVenues(t *testing.T) {
+	price := func(bid, ask string) ExchangePrice {
+		return ExchangePrice{Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, bid), AskPrice: mustDecimal(t, ask)}
+	}
+	pairs := map[string]map[string]ExchangePrice{
+		"A": {"BTC/USDT": price("101", "102")},
+		"B": {"BTC/USDT": price("99", "100")},
+		"C": {"BTC/USDT": price("104", "105")},
+	}
+
+	got := FindArbitrage(pairs, nil, mustDecimal(t, "0.01"), mustDecimal(t, "0.5"))
+	if len(got) != 1 {
+		t.Fatalf("got %d opportunities, want one per symbol: %+v", len(got), got)
+	}
+	if got[0].BuyExchange != "B" || got[0].SellExchange != "C" {
+		t.Errorf("buy %s, sell %s; want buy B, sell C", got[0].BuyExchange, got[0].SellExchange)
+	}
+	if !got[0].ProfitPercentage.Equal(mustDecimal(t, "4")) {
+		t.Errorf("profit = %s%%, want 4%%", got[0].ProfitPercentage)
+	}
+}
+
+func TestFindArbitrageEveryDirection(t *testing.T) {
+	defer func(old bool) { BestDirectionOnly = old }(BestDirectionOnly)
+	BestDirectionOnly = false
+
+	price := func(bid, ask string) ExchangePrice {
+		return ExchangePrice{Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, bid), AskPrice: mustDecimal(t, ask)}
+	}
+	pairs := map[string]map[string]ExchangePrice{
+		"A": {"BTC/USDT": price("101", "102")},
+		"B": {"BTC/USDT": price("99", "100")},
+		"C": {"BTC/USDT": price("104", "105")},
+	}
+
+	got := FindArbitrage(pairs, nil, mustDecimal(t, "0.01"), mustDecimal(t, "0.5"))
+	var routes []string
+	for _, opportunity := range got {
+		routes = append(routes, opportunity.BuyExchange+"->"+opportunity.SellExchange)
+	}
+	if want := []string{"A->C", "B->A", "B->C"}; fmt.Sprint(routes) != fmt.Sprint(want) {
+		t.Errorf("routes = %v, want %v", routes, want)
+	}
+}
+
+func TestFindArbitrageDeterministicOrder(t *testing.T) {
+	price := func(bid, ask string) ExchangePrice {
+		return ExchangePrice{Quote: "USDT", BidPrice: mustDecimal(t, bid), AskPrice: mustDecimal(t, ask)}
+	}
+	pairsA := map[string]ExchangePrice{}
+	pairsB := map[string]ExchangePrice{}
+	var want []string
+	for _, base := range []string{"AAA", "BBB", "CCC", "DDD", "EEE", "FFF", "GGG", "HHH"} {
+		symbol := base + "/USDT"
+		// Every symbol has the same 5% spread.
+		pairsA[symbol] = price("99", "100")
+		pairsB[symbol] = price("105", "106")
+		want = append(want, symbol)
+	}
+	pairs := map[string]map[string]ExchangePrice{"A": pairsA, "B": pairsB}
+
+	for run := 0; run < 5; run++ {
+		opportunities := FindArbitrage(pairs, map[string]ExchangeFees{}, mustDecimal(t, "0.01"), mustDecimal(t, "0.5"))
+		SortOpportunities(opportunities)
+		if len(opportunities) != len(want) {
+			t.Fatalf("got %d opportunities, want %d", len(opportunities), len(want))
+		}
+		for i, symbol := range want {
+			if opportunities[i].Symbol != symbol {
+				t.Fatalf("run %d: position %d = %s, want %s", run, i, opportunities[i].Symbol, symbol)
+			}
+		}
+	}
+}
+
+func TestSortOpportunities(t *testing.T) {
+	opportunities := []ArbitrageOpportunity{
+		{Symbol: "LOW/USDT", ProfitPercentage: mustDecimal(t, "1.2")},
+		{Symbol: "TIE-SMALL/USDT", ProfitPercentage: mustDecimal(t, "3"), NetProfit: mustDecimal(t, "5")},
+		{Symbol: "HIGH/USDT", ProfitPercentage: mustDecimal(t, "4.5")},
+		{Symbol: "TIE-BIG/USDT", ProfitPercentage: mustDecimal(t, "3"), NetProfit: mustDecimal(t, "50")},
+	}
+
+	SortOpportunities(opportunities)
+	want := []string{"HIGH/USDT", "TIE-BIG/USDT", "TIE-SMALL/USDT", "LOW/USDT"}
+	for i, symbol := range want {
+		if opportunities[i].Symbol != symbol {
+			t.Errorf("position %d = %s, want %s", i, opportunities[i].Symbol, symbol)
+		}
+	}
+}
+
+func TestSortOpportunitiesRankBy(t *testing.T) {
+	defer func(old string) { RankBy = old }(RankBy)
+	opportunities := []ArbitrageOpportunity{
+		// 10% on $10 of depth is $1 achievable.
+		{Symbol: "MICRO/USDT", ProfitPercentage: mustDecimal(t, "10"), NetProfit: mustDecimal(t, "10"), AvailableDepth: mustDecimal(t, "10")},
+		// 2% on $50k of depth is $1000 achievable.
+		{Symbol: "DEEP/USDT", ProfitPercentage: mustDecimal(t, "2"), NetProfit: mustDecimal(t, "2"), AvailableDepth: mustDecimal(t, "50000")},
+		{Symbol: "BIG/USDT", ProfitPercentage: mustDecimal(t, "3"), NetProfit: mustDecimal(t, "30")},
+	}
+	for rankBy, want := range map[string][]string{
+		RankByPercent:    {"MICRO/USDT", "BIG/USDT", "DEEP/USDT"},
+		RankByAbsolute:   {"BIG/USDT", "MICRO/USDT", "DEEP/USDT"},
+		RankByAchievable: {"DEEP/USDT", "MICRO/USDT", "BIG/USDT"},
+	} {
+		RankBy = rankBy
+		SortOpportunities(opportunities)
+		for i, symbol := range want {
+			if opportunities[i].Symbol != symbol {
+				t.Errorf("%s: position %d = %s, want %s", rankBy, i, opportunities[i].Symbol, symbol)
+			}
+		}
+	}
+}
+
+func TestProfitableDepth(t *testing.T) {
+	buyBook := OrderBook{Asks: []OrderBookLevel{
+		{Price: mustDecimal(t, "100"), Quantity: mustDecimal(t, "1")},
+		{Price: mustDecimal(t, "101"), Quantity: mustDecimal(t, "2")},
+		// No longer below the best bid after fees.
+		{Price: mustDecimal(t, "103"), Quantity: mustDecimal(t, "50")},
+	}}
+	sellBook := OrderBook{Bids: []OrderBookLevel{
+		{Price: mustDecimal(t, "104"), Quantity: mustDecimal(t, "10")},
+		{Price: mustDecimal(t, "100"), Quantity: mustDecimal(t, "50")},
+	}}
+	fee := mustDecimal(t, "0.005")
+
+	// The bids above 100.5 are worth 1040, the asks below 103.48 only 302.
+	if got := profitableDepth(buyBook, sellBook, fee, fee); !got.Equal(mustDecimal(t, "302")) {
+		t.Errorf("profitableDepth = %s, want 302", got)
+	}
+	if got := profitableDepth(OrderBook{}, sellBook, fee, fee); !got.IsZero() {
+		t.Errorf("profitableDepth with an empty book = %s, want 0", got)
+	}
+}
+
+func TestFindArbitrageTinyPrices(t *testing.T) {
+	pairs := map[string]map[string]ExchangePrice{
+		"A": {"BABY/USDT": {BidPrice: mustDecimal(t, "0.0000000029"), AskPrice: mustDecimal(t, "0.000000003")}},
+		"B": {"BABY/USDT": {BidPrice: mustDecimal(t, "0.0000000031"), AskPrice: mustDecimal(t, "0.0000000032")}},
+	}
+
+	got := FindArbitrage(pairs, nil, mustDecimal(t, "0.01"), mustDecimal(t, "0.5"))
+	if len(got) != 1 {
+		t.Fatalf("got %d opportunities, want 1", len(got))
+	}
+	if want := "3.3333333333"; got[0].ProfitPercentage.StringFixed(10) != want {
+		t.Errorf("profit = %s%%, want %s%%", got[0].ProfitPercentage, want)
+	}
+	if want := "333.33"; got[0].ProfitBps.StringFixed(2) != want {
+		t.Errorf("profit = %s bps, want %s bps", got[0].ProfitBps, want)
+	}
+}
+
+func TestFilterByPrice(t *testing.T) {
+	pairs := map[string]ExchangePrice{
+		"BTC/USDT":  {BidPrice: mustDecimal(t, "60000"), AskPrice: mustDecimal(t, "60001")},
+		"DUST/USDT": {BidPrice: mustDecimal(t, "0.00000001"), AskPrice: mustDecimal(t, "0.00000002")},
+	}
+	filtered := FilterByPrice(pairs, mustDecimal(t, "0.000001"))
+	if _, ok := filtered["DUST/USDT"]; ok || len(filtered) != 1 {
+		t.Errorf("unexpected pairs left: %v", filtered)
+	}
+}
+
+func TestFilterByAssets(t *testing.T) {
+	pairs := map[string]ExchangePrice{
+		"BTC/USDT": {Base: "BTC", Quote: "USDT"},
+		"BTC/USDC": {Base: "BTC", Quote: "USDC"},
+		"ETH/BTC":  {Base: "ETH", Quote: "BTC"},
+		"SOL/USDT": {Base: "SOL", Quote: "USDT"},
+	}
+	tests := []struct {
+		bases, quotes []string
+		want          string
+	}{
+		{[]string{"btc", "ETH"}, nil, "[BTC/USDC BTC/USDT ETH/BTC]"},
+		{[]string{"BTC"}, []string{"USDT"}, "[BTC/USDT]"},
+		{nil, []string{"USDT"}, "[BTC/USDT SOL/USDT]"},
+		{nil, nil, "[BTC/USDC BTC/USDT ETH/BTC SOL/USDT]"},
+	}
+	for _, tt := range tests {
+		var got []string
+		for symbol := range FilterByAssets(pairs, tt.bases, tt.quotes) {
+			got = append(got, symbol)
+		}
+		sort.Strings(got)
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("FilterByAssets(%v, %v) = %v, want %s", tt.bases, tt.quotes, got, tt.want)
+		}
+	}
+}
+
+func TestFilterByAge(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	pairs := map[string]ExchangePrice{
+		"NEW/USDT":     {ListedAt: now.Add(-24 * time.Hour)},
+		"OLD/USDT":     {ListedAt: now.Add(-30 * 24 * time.Hour)},
+		"UNKNOWN/USDT": {},
+	}
+
+	filtered := FilterByAge(pairs, 7*24*time.Hour, now)
+	if len(filtered) != 2 {
+		t.Fatalf("got %d pairs, want 2: %v", len(filtered), filtered)
+	}
+	if _, ok := filtered["NEW/USDT"]; ok {
+		t.Error("NEW/USDT should be excluded as a recent listing")
+	}
+}
+
+func TestFilterByUpdateAge(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	pairs := map[string]ExchangePrice{
+		"FRESH/USDT":   {UpdatedAt: now.Add(-time.Second)},
+		"HALTED/USDT":  {UpdatedAt: now.Add(-time.Hour)},
+		"UNKNOWN/USDT": {},
+	}
+
+	filtered, excluded := FilterByUpdateAge(pairs, 5*time.Minute, now)
+	if len(filtered) != 2 || excluded != 1 {
+		t.Fatalf("kept %d pairs and excluded %d, want 2 and 1: %v", len(filtered), excluded, filtered)
+	}
+	if _, ok := filtered["HALTED/USDT"]; ok {
+		t.Error("HALTED/USDT should be excluded as stale")
+	}
+}
+
+func TestFilterCrossed(t *testing.T) {
+	pairs := map[string]ExchangePrice{
+		"BTC/USDT": {BidPrice: mustDecimal(t, "100"), AskPrice: mustDecimal(t, "100.1")},
+		"ETH/USDT": {BidPrice: mustDecimal(t, "10.1"), AskPrice: mustDecimal(t, "10")},
+		"SOL/USDT": {BidPrice: mustDecimal(t, "5"), AskPrice: mustDecimal(t, "5")},
+	}
+
+	filtered, crossed := FilterCrossed(pairs)
+	if len(filtered) != 1 || filtered["BTC/USDT"].AskPrice.IsZero() {
+		t.Errorf("kept %v, want only BTC/USDT", filtered)
+	}
+	if len(crossed) != 2 || crossed[0] != "ETH/USDT" || crossed[1] != "SOL/USDT" {
+		t.Errorf("crossed = %v, want ETH/USDT and SOL/USDT", crossed)
+	}
+}
+
+func TestFilterByMinNotional(t *testing.T) {
+	pairs := map[string]map[string]ExchangePrice{
+		"A": {"BTC/USDT": {MinNotional: mustDecimal(t, "5")}, "ETH/USDT": {}},
+		"B": {"BTC/USDT": {}, "ETH/USDT": {MinNotional: mustDecimal(t, "20")}},
+	}
+	opportunities := []ArbitrageOpportunity{
+		{Symbol: "BTC/USDT", BuyExchange: "A", SellExchange: "B"},
+		{Symbol: "ETH/USDT", BuyExchange: "A", SellExchange: "B"},
+	}
+
+	kept := FilterByMinNotional(opportunities, pairs, mustDecimal(t, "10"))
+	if len(kept) != 1 || kept[0].Symbol != "BTC/USDT" {
+		t.Errorf("kept %+v, want only BTC/USDT", kept)
+	}
+}
+
+func TestComputeOpportunity(t *testing.T) {
+	a := ExchangePrice{Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, "99"), AskPrice: mustDecimal(t, "100")}
+	b := ExchangePrice{Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, "103"), AskPrice: mustDecimal(t, "104")}
+	fees := map[string]ExchangeFees{"A": {Taker: mustDecimal(t, "0.001")}, "B": {Taker: mustDecimal(t, "0.001")}}
+	minProfit, maxProfit := mustDecimal(t, "0.01"), mustDecimal(t, "0.5")
+
+	got, ok, outlier := ComputeOpportunity("BTC/USDT", "A", "B", a, b, fees, minProfit, maxProfit)
+	if !ok || outlier {
+		t.Fatalf("buy A, sell B: ok = %v, outlier = %v", ok, outlier)
+	}
+	if !got.BuyPrice.Equal(mustDecimal(t, "100.1")) || !got.SellPrice.Equal(mustDecimal(t, "102.897")) {
+		t.Errorf("buy %s, sell %s; want 100.1 and 102.897", got.BuyPrice, got.SellPrice)
+	}
+	if !got.Spread.Equal(mustDecimal(t, "2.797")) {
+		t.Errorf("spread = %s, want 2.797", got.Spread)
+	}
+	// Before fees, 100 to 103 is 3%.
+	if !got.GrossProfitPercentage.Equal(mustDecimal(t, "3")) {
+		t.Errorf("gross profit = %s%%, want 3%%", got.GrossProfitPercentage)
+	}
+
+	// The reverse direction loses money.
+	if _, ok, outlier := ComputeOpportunity("BTC/USDT", "B", "A", b, a, fees, minProfit, maxProfit); ok || outlier {
+		t.Errorf("buy B, sell A: ok = %v, outlier = %v", ok, outlier)
+	}
+
+	if _, ok, outlier := ComputeOpportunity("BTC/USDT", "A", "B", a, b, fees, minProfit, mustDecimal(t, "0.02")); ok || !outlier {
+		t.Errorf("above the sanity limit: ok = %v, outlier = %v", ok, outlier)
+	}
+}
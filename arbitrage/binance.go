@@ -0,0 +1,661 @@
+package arbitrage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Binance regions selectable with BinanceRegion.
+const (
+	BinanceRegionGlobal = "global"
+	BinanceRegionUS     = "us"
+)
+
+// BinanceRegion is the Binance deployment that is scanned. Binance.US serves
+// the same API from its own hosts, for users that binance.com blocks, but
+// lists fewer markets, many of them quoted in USD, and no futures.
+var BinanceRegion = BinanceRegionGlobal
+
+// binanceRegionEndpoints are the REST and stream endpoints of each region.
+var binanceRegionEndpoints = map[string]struct{ rest, stream string }{
+	BinanceRegionGlobal: {"https://api.binance.com", "wss://stream.binance.com:9443/ws/!bookTicker"},
+	BinanceRegionUS:     {"https://api.binance.us", "wss://stream.binance.us:9443/ws/!bookTicker"},
+}
+
+// IsBinanceRegion reports whether region is a known Binance region.
+func IsBinanceRegion(region string) bool {
+	_, ok := binanceRegionEndpoints[region]
+	return ok
+}
+
+// SetBinanceRegion selects region and points the Binance fetcher and stream
+// at its endpoints. Overrides of individual endpoints are applied after it.
+func SetBinanceRegion(region string) {
+	endpoints := binanceRegionEndpoints[region]
+	BinanceRegion = region
+	BinanceBaseURL, BinanceStreamURL = endpoints.rest, endpoints.stream
+}
+
+// BinanceExchange fetches spot prices from the Binance API.
+type BinanceExchange struct {
+	// stream, if set, supplies live prices from the book ticker WebSocket.
+	// Pairs falls back to REST whenever it isn't live.
+	stream     *priceStream
+	serverTime time.Time
+}
+
+func (*BinanceExchange) Name() string {
+	return ExchangeBinance
+}
+
+// StartStream subscribes to the book ticker WebSocket, whose prices Pairs
+// then prefers over REST, until ctx is cancelled.
+func (e *BinanceExchange) StartStream(ctx context.Context) {
+	e.stream = newBinanceStream()
+	e.stream.start(ctx)
+}
+
+func (e *BinanceExchange) Pairs(ctx context.Context) (map[string]ExchangePrice, error) {
+	if e.stream != nil {
+		if pairs, ok := e.stream.snapshot(); ok {
+			e.serverTime = time.Time{}
+			return pairs, nil
+		}
+		slog.Warn("Stream is not live, fetching over REST", "exchange", ExchangeBinance)
+	}
+	pairs, serverTime, err := getBinancePairs(ctx)
+	e.serverTime = serverTime
+	return pairs, err
+}
+
+// ServerTime returns the Binance server time fetched alongside the last
+// prices.
+func (e *BinanceExchange) ServerTime() time.Time {
+	return e.serverTime
+}
+
+func (*BinanceExchange) OrderBook(ctx context.Context, symbol string) (OrderBook, error) {
+	return getBinanceOrderBook(ctx, symbol)
+}
+
+func (*BinanceExchange) TopOfBook(ctx context.Context, symbol string) (decimal.Decimal, decimal.Decimal, error) {
+	tickers, err := getBinanceTickers(ctx, symbol)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	for _, ticker := range tickers {
+		if ticker.Symbol == symbol {
+			return parseTopOfBook(ExchangeBinance, symbol, ticker.BidPrice, ticker.AskPrice)
+		}
+	}
+	return decimal.Zero, decimal.Zero, fmt.Errorf("Binance returned no ticker for %s", symbol)
+}
+
+type BinanceOrderBook struct {
+	Bids [][]string `json:"bids"`
+	Asks [][]string `json:"asks"`
+}
+
+type BinanceExchangeInfo struct {
+	Symbols Records[struct {
+		Symbol     string `json:"symbol"`
+		Status     string `json:"status"`
+		BaseAsset  string `json:"baseAsset"`
+		QuoteAsset string `json:"quoteAsset"`
+		// Permissions and PermissionSets list the account types allowed to
+		// trade the symbol; leveraged tokens carry LEVERAGED.
+		Permissions    []string   `json:"permissions"`
+		PermissionSets [][]string `json:"permissionSets"`
+		Filters        []struct {
+			FilterType  string `json:"filterType"`
+			MinNotional string `json:"minNotional"`
+			TickSize    string `json:"tickSize"`
+		} `json:"filters"`
+	}] `json:"symbols"`
+}
+
+// BinanceFuturesExchangeInfo lists the USD-M futures markets.
+type BinanceFuturesExchangeInfo struct {
+	Symbols []struct {
+		Symbol       string `json:"symbol"`
+		Status       string `json:"status"`
+		ContractType string `json:"contractType"`
+		BaseAsset    string `json:"baseAsset"`
+		QuoteAsset   string `json:"quoteAsset"`
+	} `json:"symbols"`
+}
+
+// BinancePremiumIndex carries the funding rate of the running period and
+// when it settles, in milliseconds.
+type BinancePremiumIndex struct {
+	Symbol          string `json:"symbol"`
+	LastFundingRate string `json:"lastFundingRate"`
+	NextFundingTime int64  `json:"nextFundingTime"`
+}
+
+// BinanceFundingInfo only lists the perpetuals whose funding interval
+// differs from the default.
+type BinanceFundingInfo struct {
+	Symbol               string `json:"symbol"`
+	FundingIntervalHours int    `json:"fundingIntervalHours"`
+}
+
+type BinanceTicker24h struct {
+	Symbol      string `json:"symbol"`
+	QuoteVolume string `json:"quoteVolume"`
+}
+
+// BinanceBookTicker is one update from the book ticker stream. The
+// quantities must be declared even though they're unused: encoding/json
+// matches keys case-insensitively, so "B" would otherwise overwrite the bid
+// price from "b".
+type BinanceBookTicker struct {
+	Symbol   string `json:"s"`
+	BidPrice string `json:"b"`
+	BidQty   string `json:"B"`
+	AskPrice string `json:"a"`
+	AskQty   string `json:"A"`
+}
+
+type BinanceServerTime struct {
+	ServerTime int64 `json:"serverTime"`
+}
+
+type BinanceTicker struct {
+	Symbol   string `json:"symbol"`
+	BidPrice string `json:"bidPrice"`
+	AskPrice string `json:"askPrice"`
+}
+
+// getBinancePairs also returns the Binance server time, which the ticker
+// endpoints don't include, or the zero time if it couldn't be fetched.
+func getBinancePairs(ctx context.Context) (map[string]ExchangePrice, time.Time, error) {
+	var (
+		wg                        sync.WaitGroup
+		exchangeInfo              BinanceExchangeInfo
+		tickers                   []BinanceTicker
+		stats                     []BinanceTicker24h
+		serverTime                time.Time
+		marketsErr, serverTimeErr error
+	)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if len(Watchlist) > 0 {
+			exchangeInfo, tickers, stats, marketsErr = getBinanceWatchlist(ctx)
+		} else {
+			exchangeInfo, tickers, stats, marketsErr = getBinanceMarkets(ctx)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		serverTime, serverTimeErr = getBinanceServerTime(ctx)
+	}()
+	wg.Wait()
+
+	if marketsErr != nil {
+		return nil, time.Time{}, marketsErr
+	}
+	if serverTimeErr != nil {
+		// The prices are still usable; only the skew check loses precision.
+		slog.Warn("Falling back to local time", "exchange", ExchangeBinance, "err", serverTimeErr)
+	}
+
+	type assets struct {
+		base, quote string
+		minNotional decimal.Decimal
+		tickSize    decimal.Decimal
+	}
+	bases := make(map[string]bool)
+	for _, symbol := range exchangeInfo.Symbols {
+		bases[symbol.BaseAsset] = true
+	}
+	symbols := make(map[string]assets)
+	inactive, leveraged := 0, 0
+	for _, symbol := range exchangeInfo.Symbols {
+		// The tickers still include markets in BREAK or HALT, with the
+		// last prices quoted before trading stopped.
+		if symbol.Status != "TRADING" {
+			inactive++
+			continue
+		}
+		if !IncludeLeveraged && (hasLeveragedPermission(symbol.Permissions, symbol.PermissionSets) ||
+			isLeveragedToken(ExchangeBinance, symbol.BaseAsset, bases)) {
+			leveraged++
+			continue
+		}
+		info := assets{base: symbol.BaseAsset, quote: symbol.QuoteAsset}
+		// Older symbols carry MIN_NOTIONAL, newer ones NOTIONAL; both
+		// bound the order value in quote currency.
+		for _, filter := range symbol.Filters {
+			if filter.FilterType == "MIN_NOTIONAL" || filter.FilterType == "NOTIONAL" {
+				info.minNotional, _ = decimal.NewFromString(filter.MinNotional)
+			}
+			if filter.FilterType == "PRICE_FILTER" {
+				info.tickSize, _ = decimal.NewFromString(filter.TickSize)
+			}
+		}
+		symbols[symbol.Symbol] = info
+	}
+	slog.Debug("Skipped symbols that are not trading", "exchange", ExchangeBinance, "skipped", inactive)
+	if leveraged > 0 {
+		slog.Debug("Skipped leveraged tokens", "exchange", ExchangeBinance, "skipped", leveraged)
+	}
+
+	volumes := make(map[string]decimal.Decimal)
+	for _, stat := range stats {
+		if volume, err := decimal.NewFromString(stat.QuoteVolume); err == nil {
+			volumes[stat.Symbol] = volume
+		}
+	}
+
+	parser := newTickerParser(ExchangeBinance)
+	pairs := make(map[string]ExchangePrice)
+	for _, ticker := range tickers {
+		symbol, known := symbols[ticker.Symbol]
+		if !known {
+			continue
+		}
+		bidPrice, askPrice, ok := parser.prices(ticker.Symbol, ticker.BidPrice, ticker.AskPrice)
+		if !ok {
+			continue
+		}
+		base, quote := CanonicalAsset(symbol.base), CanonicalAsset(symbol.quote)
+		pairs[CanonicalSymbol(base, quote)] = ExchangePrice{
+			Symbol:      ticker.Symbol,
+			Base:        base,
+			Quote:       quote,
+			BidPrice:    bidPrice,
+			AskPrice:    askPrice,
+			QuoteVolume: volumes[ticker.Symbol],
+			MinNotional: symbol.minNotional,
+			TickSize:    symbol.tickSize,
+		}
+	}
+	parser.report()
+
+	return pairs, serverTime, nil
+}
+
+// hasLeveragedPermission reports whether a Binance symbol's permissions mark
+// it as a leveraged token.
+func hasLeveragedPermission(permissions []string, sets [][]string) bool {
+	for _, set := range sets {
+		if hasLeveragedPermission(set, nil) {
+			return true
+		}
+	}
+	for _, permission := range permissions {
+		if permission == "LEVERAGED" {
+			return true
+		}
+	}
+	return false
+}
+
+// getBinanceMarkets fetches the exchange info, book tickers and 24h stats of
+// every symbol at once.
+func getBinanceMarkets(ctx context.Context) (BinanceExchangeInfo, []BinanceTicker, []BinanceTicker24h, error) {
+	var (
+		wg                                   sync.WaitGroup
+		exchangeInfo                         BinanceExchangeInfo
+		tickers                              []BinanceTicker
+		stats                                []BinanceTicker24h
+		exchangeInfoErr, tickerErr, statsErr error
+	)
+
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		exchangeInfo, exchangeInfoErr = getBinanceExchangeInfo(ctx, "")
+	}()
+	go func() {
+		defer wg.Done()
+		tickers, tickerErr = getBinanceTickers(ctx, "")
+	}()
+	go func() {
+		defer wg.Done()
+		stats, statsErr = getBinance24hStats(ctx, "")
+	}()
+	wg.Wait()
+
+	for _, err := range []error{exchangeInfoErr, tickerErr, statsErr} {
+		if err != nil {
+			return BinanceExchangeInfo{}, nil, nil, err
+		}
+	}
+	return exchangeInfo, tickers, stats, nil
+}
+
+// getBinanceWatchlist fetches the same as getBinanceMarkets, but only for the
+// symbols in Watchlist, one symbol at a time. Symbols Binance doesn't list
+// are skipped.
+func getBinanceWatchlist(ctx context.Context) (BinanceExchangeInfo, []BinanceTicker, []BinanceTicker24h, error) {
+	var (
+		mu           sync.Mutex
+		exchangeInfo BinanceExchangeInfo
+		tickers      []BinanceTicker
+		stats        []BinanceTicker24h
+	)
+	err := forEachWatched(func(symbol string) error {
+		info, err := getBinanceExchangeInfo(ctx, symbol)
+		if err != nil || len(info.Symbols) == 0 {
+			return err
+		}
+		symbolTickers, err := getBinanceTickers(ctx, symbol)
+		if err != nil {
+			return err
+		}
+		symbolStats, err := getBinance24hStats(ctx, symbol)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		exchangeInfo.Symbols = append(exchangeInfo.Symbols, info.Symbols...)
+		tickers = append(tickers, symbolTickers...)
+		stats = append(stats, symbolStats...)
+		return nil
+	})
+	if err != nil {
+		return BinanceExchangeInfo{}, nil, nil, err
+	}
+	return exchangeInfo, tickers, stats, nil
+}
+
+// binanceInvalidSymbol is the error code Binance answers a request for a
+// symbol it doesn't list with.
+const binanceInvalidSymbol = -1121
+
+// getBinanceExchangeInfo fetches the exchange info of every symbol, or only
+// of symbol if it is set. A symbol Binance doesn't list yields no symbols
+// rather than an error.
+func getBinanceExchangeInfo(ctx context.Context, symbol string) (BinanceExchangeInfo, error) {
+	apiURL := BinanceBaseURL + "/api/v3/exchangeInfo"
+	if symbol != "" {
+		apiURL += "?symbol=" + url.QueryEscape(symbol)
+	}
+	resp, err := getWithRetry(ctx, ExchangeBinance, apiURL)
+	if err != nil {
+		return BinanceExchangeInfo{}, fmt.Errorf("error fetching Binance exchange info: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return BinanceExchangeInfo{}, fmt.Errorf("error reading Binance response: %v", err)
+	}
+	if symbol != "" && resp.StatusCode == http.StatusBadRequest {
+		var apiErr struct {
+			Code int `json:"code"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Code == binanceInvalidSymbol {
+			slog.Debug("Watched symbol is not listed", "exchange", ExchangeBinance, "symbol", symbol)
+			return BinanceExchangeInfo{}, nil
+		}
+	}
+	if err := CheckStatus(ExchangeBinance, resp, body); err != nil {
+		return BinanceExchangeInfo{}, err
+	}
+
+	var exchangeInfo BinanceExchangeInfo
+	err = json.Unmarshal(body, &exchangeInfo)
+	if err != nil {
+		return BinanceExchangeInfo{}, fmt.Errorf("error unmarshalling Binance exchange info: %v", err)
+	}
+
+	return exchangeInfo, nil
+}
+
+// getBinanceTickers fetches the book tickers of every symbol, or only of
+// symbol if it is set.
+func getBinanceTickers(ctx context.Context, symbol string) ([]BinanceTicker, error) {
+	apiURL := BinanceBaseURL + "/api/v3/ticker/bookTicker"
+	if symbol != "" {
+		// The symbols parameter returns an array like the unfiltered call,
+		// where symbol would return a bare object.
+		apiURL += "?symbols=" + url.QueryEscape(`["`+symbol+`"]`)
+	}
+	resp, err := getWithRetry(ctx, ExchangeBinance, apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching Binance tickers: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Binance response: %v", err)
+	}
+	if err := CheckStatus(ExchangeBinance, resp, body); err != nil {
+		return nil, err
+	}
+
+	var tickers Records[BinanceTicker]
+	err = json.Unmarshal(body, &tickers)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling Binance tickers: %v", err)
+	}
+
+	return tickers, nil
+}
+
+// getBinance24hStats fetches the 24h stats of every symbol, or only of
+// symbol if it is set.
+func getBinance24hStats(ctx context.Context, symbol string) ([]BinanceTicker24h, error) {
+	apiURL := BinanceBaseURL + "/api/v3/ticker/24hr"
+	if symbol != "" {
+		apiURL += "?symbols=" + url.QueryEscape(`["`+symbol+`"]`)
+	}
+	resp, err := getWithRetry(ctx, ExchangeBinance, apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching Binance 24h stats: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Binance response: %v", err)
+	}
+	if err := CheckStatus(ExchangeBinance, resp, body); err != nil {
+		return nil, err
+	}
+
+	var stats Records[BinanceTicker24h]
+	err = json.Unmarshal(body, &stats)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling Binance 24h stats: %v", err)
+	}
+
+	return stats, nil
+}
+
+func getBinanceOrderBook(ctx context.Context, symbol string) (OrderBook, error) {
+	apiURL := BinanceBaseURL + "/api/v3/depth?limit=100&symbol=" + url.QueryEscape(symbol)
+	resp, err := getWithRetry(ctx, ExchangeBinance, apiURL)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error fetching Binance order book for %s: %v", symbol, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error reading Binance response: %v", err)
+	}
+	if err := CheckStatus(ExchangeBinance, resp, body); err != nil {
+		return OrderBook{}, err
+	}
+
+	var book BinanceOrderBook
+	err = json.Unmarshal(body, &book)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error unmarshalling Binance order book: %v", err)
+	}
+
+	return OrderBook{Bids: parseOrderBookLevels(book.Bids), Asks: parseOrderBookLevels(book.Asks)}, nil
+}
+
+func getBinanceServerTime(ctx context.Context) (time.Time, error) {
+	apiURL := BinanceBaseURL + "/api/v3/time"
+	resp, err := getWithRetry(ctx, ExchangeBinance, apiURL)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error fetching Binance server time: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error reading Binance response: %v", err)
+	}
+	if err := CheckStatus(ExchangeBinance, resp, body); err != nil {
+		return time.Time{}, err
+	}
+
+	var serverTime BinanceServerTime
+	err = json.Unmarshal(body, &serverTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error unmarshalling Binance server time: %v", err)
+	}
+
+	return time.Unix(0, serverTime.ServerTime*int64(time.Millisecond)), nil
+}
+
+// newBinanceStream returns a stream of every market's best bid and ask. The
+// stream only carries prices, so the asset metadata and 24h volumes come
+// from the REST seed.
+func newBinanceStream() *priceStream {
+	return &priceStream{
+		name: ExchangeBinance,
+		url:  BinanceStreamURL,
+		seed: func(ctx context.Context) (map[string]ExchangePrice, error) {
+			pairs, _, err := getBinancePairs(ctx)
+			return pairs, err
+		},
+		handle: handleBinanceBookTicker,
+	}
+}
+
+func handleBinanceBookTicker(message []byte, prices map[string]ExchangePrice) error {
+	var ticker BinanceBookTicker
+	if err := json.Unmarshal(message, &ticker); err != nil {
+		return fmt.Errorf("error unmarshalling Binance book ticker: %v", err)
+	}
+	price, known := prices[ticker.Symbol]
+	if !known {
+		return nil
+	}
+	bidPrice, err := decimal.NewFromString(ticker.BidPrice)
+	if err != nil || bidPrice.IsZero() {
+		return nil
+	}
+	askPrice, err := decimal.NewFromString(ticker.AskPrice)
+	if err != nil || askPrice.IsZero() {
+		return nil
+	}
+	// Spot book tickers carry no time, so the update is dated on arrival.
+	price.BidPrice, price.AskPrice = bidPrice, askPrice
+	price.UpdatedAt = time.Now()
+	prices[ticker.Symbol] = price
+	return nil
+}
+
+// FundingRates returns the funding of Binance's USD-M perpetuals. Binance.US
+// has none.
+func (*BinanceExchange) FundingRates(ctx context.Context) (map[string]FundingRate, error) {
+	if BinanceRegion != BinanceRegionGlobal {
+		return map[string]FundingRate{}, nil
+	}
+	return getBinanceFundingRates(ctx)
+}
+
+// BinanceFutures names the USD-M futures API, which is served from its own
+// host, in endpoint overrides and request logs.
+const BinanceFutures = "BinanceFutures"
+
+// getBinanceFundingRates returns the funding rates of every USD-M
+// perpetual that is trading.
+func getBinanceFundingRates(ctx context.Context) (map[string]FundingRate, error) {
+	var exchangeInfo BinanceFuturesExchangeInfo
+	if err := getJSON(ctx, BinanceFutures, BinanceFuturesBaseURL+"/fapi/v1/exchangeInfo", "Binance futures exchange info", &exchangeInfo); err != nil {
+		return nil, err
+	}
+	var premiums []BinancePremiumIndex
+	if err := getJSON(ctx, BinanceFutures, BinanceFuturesBaseURL+"/fapi/v1/premiumIndex", "Binance premium index", &premiums); err != nil {
+		return nil, err
+	}
+	var tickers []BinanceTicker
+	if err := getJSON(ctx, BinanceFutures, BinanceFuturesBaseURL+"/fapi/v1/ticker/bookTicker", "Binance futures tickers", &tickers); err != nil {
+		return nil, err
+	}
+	var fundingInfo []BinanceFundingInfo
+	if err := getJSON(ctx, BinanceFutures, BinanceFuturesBaseURL+"/fapi/v1/fundingInfo", "Binance funding info", &fundingInfo); err != nil {
+		return nil, err
+	}
+
+	intervals := make(map[string]time.Duration, len(fundingInfo))
+	for _, info := range fundingInfo {
+		if info.FundingIntervalHours > 0 {
+			intervals[info.Symbol] = time.Duration(info.FundingIntervalHours) * time.Hour
+		}
+	}
+	bids := make(map[string]decimal.Decimal, len(tickers))
+	asks := make(map[string]decimal.Decimal, len(tickers))
+	for _, ticker := range tickers {
+		bids[ticker.Symbol], _ = decimal.NewFromString(ticker.BidPrice)
+		asks[ticker.Symbol], _ = decimal.NewFromString(ticker.AskPrice)
+	}
+	type perpetual struct {
+		base, quote string
+	}
+	perpetuals := make(map[string]perpetual)
+	for _, symbol := range exchangeInfo.Symbols {
+		if symbol.Status == "TRADING" && symbol.ContractType == "PERPETUAL" {
+			perpetuals[symbol.Symbol] = perpetual{base: symbol.BaseAsset, quote: symbol.QuoteAsset}
+		}
+	}
+
+	rates := make(map[string]FundingRate)
+	for _, premium := range premiums {
+		perp, ok := perpetuals[premium.Symbol]
+		if !ok {
+			continue
+		}
+		rate, err := decimal.NewFromString(premium.LastFundingRate)
+		if err != nil {
+			continue
+		}
+		bidPrice, askPrice := bids[premium.Symbol], asks[premium.Symbol]
+		if !bidPrice.IsPositive() || !askPrice.IsPositive() {
+			continue
+		}
+		interval, ok := intervals[premium.Symbol]
+		if !ok {
+			interval = defaultFundingInterval
+		}
+		var next time.Time
+		if premium.NextFundingTime > 0 {
+			next = time.Unix(0, premium.NextFundingTime*int64(time.Millisecond))
+		}
+		base, quote := CanonicalAsset(perp.base), CanonicalAsset(perp.quote)
+		rates[CanonicalSymbol(base, quote)] = FundingRate{
+			Symbol:          premium.Symbol,
+			Base:            base,
+			Quote:           quote,
+			BidPrice:        bidPrice,
+			AskPrice:        askPrice,
+			Rate:            rate,
+			Interval:        interval,
+			NextFundingTime: next,
+		}
+	}
+	return rates, nil
+}
@@ -0,0 +1,655 @@
+package arbitrage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+)
+
+// BybitExchange fetches prices from the Bybit v5 API, in the market selected
+// by BybitCategory.
+type BybitExchange struct {
+	// stream, if set, supplies live prices from the public WebSocket.
+	// Pairs falls back to REST whenever it isn't live.
+	stream     *priceStream
+	serverTime time.Time
+}
+
+func (*BybitExchange) Name() string {
+	return ExchangeBybit
+}
+
+// StartStream subscribes to the public WebSocket, whose prices Pairs then
+// prefers over REST, until ctx is cancelled.
+func (e *BybitExchange) StartStream(ctx context.Context) {
+	e.stream = newBybitStream()
+	e.stream.start(ctx)
+}
+
+func (e *BybitExchange) Pairs(ctx context.Context) (map[string]ExchangePrice, error) {
+	if e.stream != nil {
+		if pairs, ok := e.stream.snapshot(); ok {
+			e.serverTime = time.Time{}
+			return pairs, nil
+		}
+		slog.Warn("Stream is not live, fetching over REST", "exchange", ExchangeBybit)
+	}
+	pairs, serverTime, err := getBybitPairs(ctx)
+	e.serverTime = serverTime
+	return pairs, err
+}
+
+// ServerTime returns the time Bybit reported with the last tickers.
+func (e *BybitExchange) ServerTime() time.Time {
+	return e.serverTime
+}
+
+func (*BybitExchange) OrderBook(ctx context.Context, symbol string) (OrderBook, error) {
+	return getBybitOrderBook(ctx, symbol)
+}
+
+// FundingRates returns the funding of Bybit's USDT and USDC perpetuals,
+// whichever category is scanned for prices.
+func (*BybitExchange) FundingRates(ctx context.Context) (map[string]FundingRate, error) {
+	return getBybitFundingRates(ctx)
+}
+
+func (*BybitExchange) TopOfBook(ctx context.Context, symbol string) (decimal.Decimal, decimal.Decimal, error) {
+	tickers, err := getBybitTickers(ctx, symbol)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	for _, ticker := range tickers.Result.List {
+		if ticker.Symbol == symbol {
+			return parseTopOfBook(ExchangeBybit, symbol, ticker.Bid1Price, ticker.Ask1Price)
+		}
+	}
+	return decimal.Zero, decimal.Zero, fmt.Errorf("Bybit returned no ticker for %s", symbol)
+}
+
+type BybitInstrumentsInfo struct {
+	Result struct {
+		List Records[struct {
+			Symbol       string `json:"symbol"`
+			BaseCoin     string `json:"baseCoin"`
+			QuoteCoin    string `json:"quoteCoin"`
+			Status       string `json:"status"`
+			ContractType string `json:"contractType"`
+			// LaunchTime is the listing time in milliseconds.
+			LaunchTime string `json:"launchTime"`
+			// Spot markets limit the order value with minOrderAmt,
+			// derivatives with minNotionalValue.
+			LotSizeFilter struct {
+				MinOrderAmt      string `json:"minOrderAmt"`
+				MinNotionalValue string `json:"minNotionalValue"`
+			} `json:"lotSizeFilter"`
+			PriceFilter struct {
+				TickSize string `json:"tickSize"`
+			} `json:"priceFilter"`
+			// FundingInterval is the funding period of perpetuals, in
+			// minutes.
+			FundingInterval int `json:"fundingInterval"`
+		}] `json:"list"`
+		// NextPageCursor is set when more instruments follow on another
+		// page.
+		NextPageCursor string `json:"nextPageCursor"`
+	} `json:"result"`
+}
+
+// BybitTickers time is Bybit's clock when the response was generated, in
+// milliseconds.
+type BybitTickers struct {
+	Time   int64 `json:"time"`
+	Result struct {
+		List Records[struct {
+			Symbol      string `json:"symbol"`
+			Bid1Price   string `json:"bid1Price"`
+			Ask1Price   string `json:"ask1Price"`
+			Turnover24h string `json:"turnover24h"`
+			// Perpetuals also report the funding rate of the running
+			// period and when it settles, in milliseconds.
+			FundingRate     string `json:"fundingRate"`
+			NextFundingTime string `json:"nextFundingTime"`
+		}] `json:"list"`
+	} `json:"result"`
+}
+
+type BybitOrderBook struct {
+	Result struct {
+		Bids [][]string `json:"b"`
+		Asks [][]string `json:"a"`
+	} `json:"result"`
+}
+
+// Bybit market categories.
+const (
+	BybitCategorySpot    = "spot"
+	BybitCategoryLinear  = "linear"
+	BybitCategoryInverse = "inverse"
+)
+
+// BybitCategory selects which Bybit market is scanned.
+var BybitCategory = BybitCategorySpot
+
+// getBybitPairs also returns the server time of the tickers response.
+func getBybitPairs(ctx context.Context) (map[string]ExchangePrice, time.Time, error) {
+	var (
+		wg                         sync.WaitGroup
+		instrumentsInfo            BybitInstrumentsInfo
+		tickers                    BybitTickers
+		instrumentsErr, tickersErr error
+	)
+
+	if len(Watchlist) > 0 {
+		// Only the watched symbols Bybit lists can be requested, so the
+		// instruments come first.
+		instrumentsInfo, instrumentsErr = bybitInstruments.get(ctx)
+		if instrumentsErr == nil {
+			tickers, tickersErr = getBybitWatchlistTickers(ctx, instrumentsInfo)
+		}
+	} else {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			instrumentsInfo, instrumentsErr = bybitInstruments.get(ctx)
+		}()
+		go func() {
+			defer wg.Done()
+			tickers, tickersErr = getBybitTickers(ctx, "")
+		}()
+		wg.Wait()
+	}
+
+	if instrumentsErr != nil {
+		return nil, time.Time{}, instrumentsErr
+	}
+	if tickersErr != nil {
+		// The failure may be an API or category change that affects the
+		// instruments too, so don't trust the cache on the next attempt.
+		bybitInstruments.invalidate()
+		return nil, time.Time{}, tickersErr
+	}
+
+	// Create a map of active trading pairs
+	type assets struct {
+		base, quote string
+		minNotional decimal.Decimal
+		tickSize    decimal.Decimal
+		listedAt    time.Time
+	}
+	bases := make(map[string]bool)
+	for _, instrument := range instrumentsInfo.Result.List {
+		bases[instrument.BaseCoin] = true
+	}
+	activePairs := make(map[string]assets)
+	leveraged := 0
+	for _, instrument := range instrumentsInfo.Result.List {
+		if instrument.Status != "Trading" {
+			continue
+		}
+		if !IncludeLeveraged && isLeveragedToken(ExchangeBybit, instrument.BaseCoin, bases) {
+			leveraged++
+			continue
+		}
+		// Linear and inverse categories also list dated futures such as
+		// BTCUSDT-27DEC24 whose price includes a term premium; only
+		// perpetuals track the spot price closely enough to compare.
+		if BybitCategory != BybitCategorySpot &&
+			instrument.ContractType != "LinearPerpetual" && instrument.ContractType != "InversePerpetual" {
+			continue
+		}
+		minNotional := instrument.LotSizeFilter.MinOrderAmt
+		if minNotional == "" {
+			minNotional = instrument.LotSizeFilter.MinNotionalValue
+		}
+		info := assets{base: instrument.BaseCoin, quote: instrument.QuoteCoin}
+		info.minNotional, _ = decimal.NewFromString(minNotional)
+		info.tickSize, _ = decimal.NewFromString(instrument.PriceFilter.TickSize)
+		if launched, err := strconv.ParseInt(instrument.LaunchTime, 10, 64); err == nil && launched > 0 {
+			info.listedAt = time.Unix(0, launched*int64(time.Millisecond))
+		}
+		activePairs[instrument.Symbol] = info
+	}
+	if leveraged > 0 {
+		slog.Debug("Skipped leveraged tokens", "exchange", ExchangeBybit, "skipped", leveraged)
+	}
+
+	parser := newTickerParser(ExchangeBybit)
+	pairs := make(map[string]ExchangePrice)
+	for _, ticker := range tickers.Result.List {
+		instrument, active := activePairs[ticker.Symbol]
+		if !active {
+			continue
+		}
+		bidPrice, askPrice, ok := parser.prices(ticker.Symbol, ticker.Bid1Price, ticker.Ask1Price)
+		if !ok {
+			continue
+		}
+		volume, _ := decimal.NewFromString(ticker.Turnover24h)
+		base, quote := CanonicalAsset(instrument.base), CanonicalAsset(instrument.quote)
+		pairs[CanonicalSymbol(base, quote)] = ExchangePrice{
+			Symbol:      ticker.Symbol,
+			Base:        base,
+			Quote:       quote,
+			BidPrice:    bidPrice,
+			AskPrice:    askPrice,
+			QuoteVolume: volume,
+			MinNotional: instrument.minNotional,
+			ListedAt:    instrument.listedAt,
+			TickSize:    instrument.tickSize,
+		}
+	}
+	parser.report()
+
+	var serverTime time.Time
+	if tickers.Time > 0 {
+		serverTime = time.Unix(0, tickers.Time*int64(time.Millisecond))
+	}
+	return pairs, serverTime, nil
+}
+
+// DefaultInstrumentsTTL is how long the instruments list is reused. Markets
+// are listed and delisted rarely, while the tickers change every cycle.
+const DefaultInstrumentsTTL = time.Hour
+
+// InstrumentsTTL is how long bybitInstruments serves a cached response.
+var InstrumentsTTL = DefaultInstrumentsTTL
+
+// bybitInstruments caches the Bybit instruments list between cycles.
+var bybitInstruments = &bybitInstrumentsCache{}
+
+// bybitInstrumentsCache holds the last instruments response together with
+// the URL it came from, so a different category or base URL is never served
+// from the cache.
+type bybitInstrumentsCache struct {
+	mu        sync.Mutex
+	apiURL    string
+	info      BybitInstrumentsInfo
+	fetchedAt time.Time
+}
+
+// get returns the cached instruments if they are younger than
+// InstrumentsTTL, and fetches them otherwise.
+func (c *bybitInstrumentsCache) get(ctx context.Context) (BybitInstrumentsInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	apiURL := bybitInstrumentsURL()
+	if c.apiURL == apiURL && time.Since(c.fetchedAt) < InstrumentsTTL {
+		return c.info, nil
+	}
+
+	info, err := getBybitInstrumentsInfo(ctx)
+	if err != nil {
+		c.apiURL = ""
+		return BybitInstrumentsInfo{}, err
+	}
+	c.apiURL, c.info, c.fetchedAt = apiURL, info, time.Now()
+	return info, nil
+}
+
+func (c *bybitInstrumentsCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.apiURL = ""
+}
+
+func bybitInstrumentsURL() string {
+	return BybitBaseURL + "/v5/market/instruments-info?category=" + url.QueryEscape(BybitCategory)
+}
+
+func getBybitInstrumentsInfo(ctx context.Context) (BybitInstrumentsInfo, error) {
+	return getBybitInstrumentPages(ctx, bybitInstrumentsURL(), "Bybit instruments info")
+}
+
+// maxBybitInstrumentPages caps how many pages getBybitInstrumentPages
+// follows, in case the cursor never runs out.
+const maxBybitInstrumentPages = 20
+
+// getBybitInstrumentPages fetches apiURL and every further page its
+// nextPageCursor points to, and returns their instruments as one list. what
+// names the resource in errors.
+func getBybitInstrumentPages(ctx context.Context, apiURL, what string) (BybitInstrumentsInfo, error) {
+	var instrumentsInfo BybitInstrumentsInfo
+	pageURL := apiURL
+	for page := 1; ; page++ {
+		var info BybitInstrumentsInfo
+		if err := getJSON(ctx, ExchangeBybit, pageURL, what, &info); err != nil {
+			return BybitInstrumentsInfo{}, err
+		}
+		instrumentsInfo.Result.List = append(instrumentsInfo.Result.List, info.Result.List...)
+
+		cursor := info.Result.NextPageCursor
+		if cursor == "" {
+			break
+		}
+		if page == maxBybitInstrumentPages {
+			slog.Warn("Bybit instruments have more pages than the limit, ignoring the rest", "pages", page, "instruments", len(instrumentsInfo.Result.List))
+			break
+		}
+		pageURL = apiURL + "&cursor=" + url.QueryEscape(cursor)
+	}
+	return instrumentsInfo, nil
+}
+
+// getBybitWatchlistTickers fetches the tickers of the symbols in Watchlist
+// that are listed in instruments, one symbol at a time. The response time is
+// that of the latest ticker.
+func getBybitWatchlistTickers(ctx context.Context, instruments BybitInstrumentsInfo) (BybitTickers, error) {
+	listed := make(map[string]bool, len(instruments.Result.List))
+	for _, instrument := range instruments.Result.List {
+		listed[instrument.Symbol] = true
+	}
+
+	var mu sync.Mutex
+	var tickers BybitTickers
+	err := forEachWatched(func(symbol string) error {
+		if !listed[symbol] {
+			slog.Debug("Watched symbol is not listed", "exchange", ExchangeBybit, "symbol", symbol)
+			return nil
+		}
+		symbolTickers, err := getBybitTickers(ctx, symbol)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		tickers.Result.List = append(tickers.Result.List, symbolTickers.Result.List...)
+		if symbolTickers.Time > tickers.Time {
+			tickers.Time = symbolTickers.Time
+		}
+		return nil
+	})
+	if err != nil {
+		return BybitTickers{}, err
+	}
+	return tickers, nil
+}
+
+// getBybitTickers fetches the tickers of every market in BybitCategory, or
+// only of symbol if it is set.
+func getBybitTickers(ctx context.Context, symbol string) (BybitTickers, error) {
+	apiURL := BybitBaseURL + "/v5/market/tickers?category=" + url.QueryEscape(BybitCategory)
+	if symbol != "" {
+		apiURL += "&symbol=" + url.QueryEscape(symbol)
+	}
+	resp, err := getWithRetry(ctx, ExchangeBybit, apiURL)
+	if err != nil {
+		return BybitTickers{}, fmt.Errorf("error fetching Bybit tickers: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return BybitTickers{}, fmt.Errorf("error reading Bybit response: %v", err)
+	}
+	if err := CheckStatus(ExchangeBybit, resp, body); err != nil {
+		return BybitTickers{}, err
+	}
+
+	var tickers BybitTickers
+	err = json.Unmarshal(body, &tickers)
+	if err != nil {
+		return BybitTickers{}, fmt.Errorf("error unmarshalling Bybit tickers: %v", err)
+	}
+
+	return tickers, nil
+}
+
+func getBybitOrderBook(ctx context.Context, symbol string) (OrderBook, error) {
+	apiURL := BybitBaseURL + "/v5/market/orderbook?limit=200&category=" + url.QueryEscape(BybitCategory) + "&symbol=" + url.QueryEscape(symbol)
+	resp, err := getWithRetry(ctx, ExchangeBybit, apiURL)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error fetching Bybit order book for %s: %v", symbol, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error reading Bybit response: %v", err)
+	}
+	if err := CheckStatus(ExchangeBybit, resp, body); err != nil {
+		return OrderBook{}, err
+	}
+
+	var book BybitOrderBook
+	err = json.Unmarshal(body, &book)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error unmarshalling Bybit order book: %v", err)
+	}
+
+	return OrderBook{Bids: parseOrderBookLevels(book.Result.Bids), Asks: parseOrderBookLevels(book.Result.Asks)}, nil
+}
+
+// Bybit accepts at most bybitStreamTopicsPerRequest topics per spot
+// subscribe request and drops connections that send no ping for 30 seconds.
+const (
+	bybitStreamTopicsPerRequest = 10
+	bybitStreamPingInterval     = 20 * time.Second
+)
+
+// BybitStreamMessage is a topic update from the public WebSocket. Type is
+// "snapshot" or "delta"; a delta only carries the fields that changed.
+// Ts is when Bybit generated the update, in milliseconds.
+type BybitStreamMessage struct {
+	Topic string          `json:"topic"`
+	Type  string          `json:"type"`
+	Ts    int64           `json:"ts"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// BybitStreamTicker is the data of a tickers topic on the linear and inverse
+// streams.
+type BybitStreamTicker struct {
+	Symbol      string `json:"symbol"`
+	Bid1Price   string `json:"bid1Price"`
+	Ask1Price   string `json:"ask1Price"`
+	Turnover24h string `json:"turnover24h"`
+}
+
+// BybitStreamOrderBook is the data of an orderbook.1 topic. A level with a
+// size of 0 has been removed.
+type BybitStreamOrderBook struct {
+	Symbol string     `json:"s"`
+	Bids   [][]string `json:"b"`
+	Asks   [][]string `json:"a"`
+}
+
+// newBybitStream returns a stream of the best bid and ask of every market in
+// BybitCategory. Bybit's spot tickers topic carries no bid or ask, so spot
+// markets follow the top of the order book instead; linear and inverse
+// markets use the tickers topic.
+func newBybitStream() *priceStream {
+	topic := "tickers."
+	if BybitCategory == BybitCategorySpot {
+		topic = "orderbook.1."
+	}
+	return &priceStream{
+		name: ExchangeBybit,
+		url:  BybitStreamBaseURL + "/v5/public/" + BybitCategory,
+		seed: func(ctx context.Context) (map[string]ExchangePrice, error) {
+			pairs, _, err := getBybitPairs(ctx)
+			return pairs, err
+		},
+		subscribe: func(conn *websocket.Conn, prices map[string]ExchangePrice) error {
+			return subscribeBybitTopics(conn, topic, prices)
+		},
+		handle:       handleBybitStreamMessage,
+		ping:         []byte(`{"op":"ping"}`),
+		pingInterval: bybitStreamPingInterval,
+	}
+}
+
+func subscribeBybitTopics(conn *websocket.Conn, topic string, prices map[string]ExchangePrice) error {
+	var args []string
+	for symbol := range prices {
+		args = append(args, topic+symbol)
+	}
+	for len(args) > 0 {
+		batch := args
+		if len(batch) > bybitStreamTopicsPerRequest {
+			batch = batch[:bybitStreamTopicsPerRequest]
+		}
+		args = args[len(batch):]
+		request := map[string]interface{}{"op": "subscribe", "args": batch}
+		if err := conn.WriteJSON(request); err != nil {
+			return fmt.Errorf("error subscribing to Bybit topics: %v", err)
+		}
+	}
+	return nil
+}
+
+func handleBybitStreamMessage(message []byte, prices map[string]ExchangePrice) error {
+	var update BybitStreamMessage
+	if err := json.Unmarshal(message, &update); err != nil {
+		return fmt.Errorf("error unmarshalling Bybit stream message: %v", err)
+	}
+	updatedAt := time.Now()
+	if update.Ts > 0 {
+		updatedAt = time.UnixMilli(update.Ts)
+	}
+
+	switch {
+	case strings.HasPrefix(update.Topic, "tickers."):
+		var ticker BybitStreamTicker
+		if err := json.Unmarshal(update.Data, &ticker); err != nil {
+			return fmt.Errorf("error unmarshalling Bybit ticker: %v", err)
+		}
+		price, known := prices[ticker.Symbol]
+		if !known {
+			return nil
+		}
+		// Fields missing from a delta keep their previous value.
+		if bidPrice, err := decimal.NewFromString(ticker.Bid1Price); err == nil {
+			price.BidPrice = bidPrice
+		}
+		if askPrice, err := decimal.NewFromString(ticker.Ask1Price); err == nil {
+			price.AskPrice = askPrice
+		}
+		if volume, err := decimal.NewFromString(ticker.Turnover24h); err == nil {
+			price.QuoteVolume = volume
+		}
+		price.UpdatedAt = updatedAt
+		prices[ticker.Symbol] = price
+
+	case strings.HasPrefix(update.Topic, "orderbook.1."):
+		var book BybitStreamOrderBook
+		if err := json.Unmarshal(update.Data, &book); err != nil {
+			return fmt.Errorf("error unmarshalling Bybit order book: %v", err)
+		}
+		price, known := prices[book.Symbol]
+		if !known {
+			return nil
+		}
+		// A snapshot replaces the top of the book, so an empty side means
+		// there is no bid or ask. A delta only names the sides that changed.
+		if update.Type == "snapshot" {
+			price.BidPrice, price.AskPrice = decimal.Zero, decimal.Zero
+		}
+		price.BidPrice = bybitTopOfBook(book.Bids, price.BidPrice)
+		price.AskPrice = bybitTopOfBook(book.Asks, price.AskPrice)
+		price.UpdatedAt = updatedAt
+		prices[book.Symbol] = price
+	}
+	// Anything else is a subscription or pong response.
+	return nil
+}
+
+// bybitTopOfBook applies the levels of one side of an orderbook.1 update to
+// the current best price. A zero size removes the level, leaving a zero
+// price that the comparison skips until a new level arrives.
+func bybitTopOfBook(levels [][]string, current decimal.Decimal) decimal.Decimal {
+	for _, level := range levels {
+		if len(level) < 2 {
+			continue
+		}
+		size, err := decimal.NewFromString(level[1])
+		if err != nil {
+			continue
+		}
+		if size.IsZero() {
+			current = decimal.Zero
+			continue
+		}
+		if price, err := decimal.NewFromString(level[0]); err == nil {
+			current = price
+		}
+	}
+	return current
+}
+
+// getBybitFundingRates returns the funding rates of every linear perpetual
+// that is trading.
+func getBybitFundingRates(ctx context.Context) (map[string]FundingRate, error) {
+	apiURL := BybitBaseURL + "/v5/market/instruments-info?limit=1000&category=" + BybitCategoryLinear
+	instrumentsInfo, err := getBybitInstrumentPages(ctx, apiURL, "Bybit perpetual instruments")
+	if err != nil {
+		return nil, err
+	}
+	var tickers BybitTickers
+	apiURL = BybitBaseURL + "/v5/market/tickers?category=" + BybitCategoryLinear
+	if err := getJSON(ctx, ExchangeBybit, apiURL, "Bybit perpetual tickers", &tickers); err != nil {
+		return nil, err
+	}
+
+	type perpetual struct {
+		base, quote string
+		interval    time.Duration
+	}
+	perpetuals := make(map[string]perpetual)
+	for _, instrument := range instrumentsInfo.Result.List {
+		if instrument.Status != "Trading" || instrument.ContractType != "LinearPerpetual" {
+			continue
+		}
+		interval := defaultFundingInterval
+		if instrument.FundingInterval > 0 {
+			interval = time.Duration(instrument.FundingInterval) * time.Minute
+		}
+		perpetuals[instrument.Symbol] = perpetual{base: instrument.BaseCoin, quote: instrument.QuoteCoin, interval: interval}
+	}
+
+	rates := make(map[string]FundingRate)
+	for _, ticker := range tickers.Result.List {
+		perp, ok := perpetuals[ticker.Symbol]
+		if !ok {
+			continue
+		}
+		rate, err := decimal.NewFromString(ticker.FundingRate)
+		if err != nil {
+			continue
+		}
+		bidPrice, err := decimal.NewFromString(ticker.Bid1Price)
+		if err != nil || bidPrice.IsZero() {
+			continue
+		}
+		askPrice, err := decimal.NewFromString(ticker.Ask1Price)
+		if err != nil || askPrice.IsZero() {
+			continue
+		}
+		var next time.Time
+		if settles, err := strconv.ParseInt(ticker.NextFundingTime, 10, 64); err == nil && settles > 0 {
+			next = time.Unix(0, settles*int64(time.Millisecond))
+		}
+		base, quote := CanonicalAsset(perp.base), CanonicalAsset(perp.quote)
+		rates[CanonicalSymbol(base, quote)] = FundingRate{
+			Symbol:          ticker.Symbol,
+			Base:            base,
+			Quote:           quote,
+			BidPrice:        bidPrice,
+			AskPrice:        askPrice,
+			Rate:            rate,
+			Interval:        perp.interval,
+			NextFundingTime: next,
+		}
+	}
+	return rates, nil
+}
@@ -0,0 +1,242 @@
+package arbitrage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Coinbase has no bulk ticker endpoint, so every product's best bid and ask
+// is a separate request. They are spread over coinbaseTickerWorkers
+// concurrent requests, which the per-host request limiter keeps under the
+// public limit of 10 requests per second.
+const coinbaseTickerWorkers = 10
+
+// CoinbaseExchange fetches spot prices from the Coinbase Exchange API.
+type CoinbaseExchange struct{}
+
+func (CoinbaseExchange) Name() string {
+	return ExchangeCoinbase
+}
+
+func (CoinbaseExchange) Pairs(ctx context.Context) (map[string]ExchangePrice, error) {
+	return getCoinbasePairs(ctx)
+}
+
+func (CoinbaseExchange) OrderBook(ctx context.Context, productID string) (OrderBook, error) {
+	return getCoinbaseOrderBook(ctx, productID)
+}
+
+func (CoinbaseExchange) TopOfBook(ctx context.Context, productID string) (decimal.Decimal, decimal.Decimal, error) {
+	ticker, err := getCoinbaseTicker(ctx, productID)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	return parseTopOfBook(ExchangeCoinbase, productID, ticker.Bid, ticker.Ask)
+}
+
+type CoinbaseProduct struct {
+	ID              string `json:"id"`
+	BaseCurrency    string `json:"base_currency"`
+	QuoteCurrency   string `json:"quote_currency"`
+	Status          string `json:"status"`
+	TradingDisabled bool   `json:"trading_disabled"`
+	CancelOnly      bool   `json:"cancel_only"`
+	QuoteIncrement  string `json:"quote_increment"`
+}
+
+// CoinbaseTicker volume is the 24h volume in the base currency.
+type CoinbaseTicker struct {
+	Bid    string `json:"bid"`
+	Ask    string `json:"ask"`
+	Price  string `json:"price"`
+	Volume string `json:"volume"`
+	// Time is when the ticker was last updated, in RFC 3339.
+	Time string `json:"time"`
+}
+
+// CoinbaseOrderBook levels are [price, size, order count], with the count
+// encoded as a number.
+type CoinbaseOrderBook struct {
+	Bids [][]interface{} `json:"bids"`
+	Asks [][]interface{} `json:"asks"`
+}
+
+func getCoinbasePairs(ctx context.Context) (map[string]ExchangePrice, error) {
+	products, err := getCoinbaseProducts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var tradable []CoinbaseProduct
+	for _, product := range products {
+		if product.Status == "online" && !product.TradingDisabled && !product.CancelOnly {
+			tradable = append(tradable, product)
+		}
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		parser  = newTickerParser(ExchangeCoinbase)
+		pairs   = make(map[string]ExchangePrice)
+		failed  int
+		jobs    = make(chan CoinbaseProduct)
+		workers = coinbaseTickerWorkers
+	)
+	if workers > len(tradable) {
+		workers = len(tradable)
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for product := range jobs {
+				price, ok, err := getCoinbasePrice(ctx, product, parser)
+				mu.Lock()
+				if err != nil {
+					failed++
+				} else if ok {
+					pairs[CanonicalSymbol(price.Base, price.Quote)] = price
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, product := range tradable {
+		jobs <- product
+	}
+	close(jobs)
+	wg.Wait()
+
+	// A single product failing shouldn't hide the rest of the exchange, but
+	// losing all of them means Coinbase itself is unavailable.
+	if failed > 0 {
+		if failed == len(tradable) {
+			return nil, fmt.Errorf("error fetching Coinbase tickers: all %d requests failed", failed)
+		}
+		slog.Warn("Skipped products whose ticker could not be fetched", "exchange", ExchangeCoinbase, "skipped", failed, "products", len(tradable))
+	}
+
+	parser.report()
+	return pairs, nil
+}
+
+// getCoinbasePrice fetches the ticker of one product and parses it with
+// parser. ok is false when the product has no usable bid or ask.
+func getCoinbasePrice(ctx context.Context, product CoinbaseProduct, parser *tickerParser) (ExchangePrice, bool, error) {
+	ticker, err := getCoinbaseTicker(ctx, product.ID)
+	if err != nil {
+		return ExchangePrice{}, false, err
+	}
+
+	bidPrice, askPrice, ok := parser.prices(product.ID, ticker.Bid, ticker.Ask)
+	if !ok {
+		return ExchangePrice{}, false, nil
+	}
+	var quoteVolume decimal.Decimal
+	volume, volumeErr := decimal.NewFromString(ticker.Volume)
+	last, lastErr := decimal.NewFromString(ticker.Price)
+	if volumeErr == nil && lastErr == nil {
+		quoteVolume = volume.Mul(last)
+	}
+
+	tickSize, _ := decimal.NewFromString(product.QuoteIncrement)
+	updatedAt, _ := time.Parse(time.RFC3339Nano, ticker.Time)
+
+	// Base and quote come straight from the product metadata, so BTC-USD and
+	// BTC-USDT stay separate symbols: USD and USDT are different assets.
+	return ExchangePrice{
+		Symbol:      product.ID,
+		Base:        CanonicalAsset(product.BaseCurrency),
+		Quote:       CanonicalAsset(product.QuoteCurrency),
+		BidPrice:    bidPrice,
+		AskPrice:    askPrice,
+		QuoteVolume: quoteVolume,
+		TickSize:    tickSize,
+		UpdatedAt:   updatedAt,
+	}, true, nil
+}
+
+func getCoinbaseProducts(ctx context.Context) ([]CoinbaseProduct, error) {
+	apiURL := CoinbaseBaseURL + "/products"
+	resp, err := getWithRetry(ctx, ExchangeCoinbase, apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching Coinbase products: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Coinbase response: %v", err)
+	}
+	if err := CheckStatus(ExchangeCoinbase, resp, body); err != nil {
+		return nil, err
+	}
+
+	var products Records[CoinbaseProduct]
+	err = json.Unmarshal(body, &products)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling Coinbase products: %v", err)
+	}
+
+	return products, nil
+}
+
+func getCoinbaseTicker(ctx context.Context, productID string) (CoinbaseTicker, error) {
+	apiURL := CoinbaseBaseURL + "/products/" + url.PathEscape(productID) + "/ticker"
+	resp, err := getWithRetry(ctx, ExchangeCoinbase, apiURL)
+	if err != nil {
+		return CoinbaseTicker{}, fmt.Errorf("error fetching Coinbase ticker for %s: %v", productID, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return CoinbaseTicker{}, fmt.Errorf("error reading Coinbase response: %v", err)
+	}
+	if err := CheckStatus(ExchangeCoinbase, resp, body); err != nil {
+		return CoinbaseTicker{}, err
+	}
+
+	var ticker CoinbaseTicker
+	err = json.Unmarshal(body, &ticker)
+	if err != nil {
+		return CoinbaseTicker{}, fmt.Errorf("error unmarshalling Coinbase ticker: %v", err)
+	}
+
+	return ticker, nil
+}
+
+func getCoinbaseOrderBook(ctx context.Context, productID string) (OrderBook, error) {
+	apiURL := CoinbaseBaseURL + "/products/" + url.PathEscape(productID) + "/book?level=2"
+	resp, err := getWithRetry(ctx, ExchangeCoinbase, apiURL)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error fetching Coinbase order book for %s: %v", productID, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error reading Coinbase response: %v", err)
+	}
+	if err := CheckStatus(ExchangeCoinbase, resp, body); err != nil {
+		return OrderBook{}, err
+	}
+
+	var book CoinbaseOrderBook
+	err = json.Unmarshal(body, &book)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error unmarshalling Coinbase order book: %v", err)
+	}
+
+	return OrderBook{Bids: parseMixedOrderBookLevels(book.Bids), Asks: parseMixedOrderBookLevels(book.Asks)}, nil
+}
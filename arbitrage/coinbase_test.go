@@ -0,0 +1,35 @@
+package arbitrage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetCoinbasePairs(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/products": `[
+			{"id":"BTC-USD","base_currency":"BTC","quote_currency":"USD","status":"online"},
+			{"id":"BTC-USDT","base_currency":"BTC","quote_currency":"USDT","status":"online"},
+			{"id":"OLD-USD","base_currency":"OLD","quote_currency":"USD","status":"delisted"},
+			{"id":"HALT-USD","base_currency":"HALT","quote_currency":"USD","status":"online","trading_disabled":true}
+		]`,
+		"/products/BTC-USD/ticker":  `{"bid":"60000.00","ask":"60000.01","price":"60000.00","volume":"100"}`,
+		"/products/BTC-USDT/ticker": `{"bid":"60010.00","ask":"60010.50","price":"60010.00","volume":"1"}`,
+	})
+	defer func(old string) { CoinbaseBaseURL = old }(CoinbaseBaseURL)
+	CoinbaseBaseURL = server.URL
+
+	pairs, err := getCoinbasePairs(context.Background())
+	if err != nil {
+		t.Fatalf("getCoinbasePairs: %v", err)
+	}
+	if len(pairs) != 2 {
+		t.Fatalf("got %d pairs, want 2: %v", len(pairs), pairs)
+	}
+	// USD and USDT markets must stay separate.
+	assertPrice(t, pairs, "BTC/USD", "BTC-USD", "60000.00", "60000.01")
+	assertPrice(t, pairs, "BTC/USDT", "BTC-USDT", "60010.00", "60010.50")
+	if got := pairs["BTC/USD"].QuoteVolume; !got.Equal(mustDecimal(t, "6000000")) {
+		t.Errorf("BTC/USD quote volume = %s, want 6000000", got)
+	}
+}
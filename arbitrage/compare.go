@@ -0,0 +1,217 @@
+package arbitrage
+
+import (
+	"log/slog"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// Ranking modes selectable with RankBy.
+const (
+	RankByPercent    = "percent"
+	RankByAbsolute   = "absolute"
+	RankByAchievable = "achievable"
+)
+
+// RankBy is what SortOpportunities ranks on: the profit percentage, the
+// absolute NetProfit on the configured amount, or the achievable profit,
+// AvailableDepth times the profit percentage, which puts a small edge on a
+// deep book ahead of a large one on a few dollars.
+var RankBy = RankByPercent
+
+// achievableProfit is the profit, in quote currency, of trading the whole
+// AvailableDepth of an opportunity at its profit percentage.
+func achievableProfit(opportunity ArbitrageOpportunity) decimal.Decimal {
+	return opportunity.AvailableDepth.Mul(opportunity.ProfitPercentage).DivRound(hundred, DivisionPrecision)
+}
+
+// SortOpportunities ranks opportunities from most to least profitable by
+// RankBy. Ties on the absolute and achievable profit are broken on the
+// profit percentage, and ties on the percentage on the absolute net profit.
+func SortOpportunities(opportunities []ArbitrageOpportunity) {
+	sort.SliceStable(opportunities, func(i, j int) bool {
+		a, b := opportunities[i], opportunities[j]
+		switch RankBy {
+		case RankByAbsolute:
+			if !a.NetProfit.Equal(b.NetProfit) {
+				return a.NetProfit.GreaterThan(b.NetProfit)
+			}
+		case RankByAchievable:
+			if achievableA, achievableB := achievableProfit(a), achievableProfit(b); !achievableA.Equal(achievableB) {
+				return achievableA.GreaterThan(achievableB)
+			}
+		}
+		if !a.ProfitPercentage.Equal(b.ProfitPercentage) {
+			return a.ProfitPercentage.GreaterThan(b.ProfitPercentage)
+		}
+		return a.NetProfit.GreaterThan(b.NetProfit)
+	})
+}
+
+// BestSpread returns the most profitable direction for symbol among the
+// listed exchanges after fees, however small or negative the profit is.
+func BestSpread(symbol string, listed []string, pairs map[string]map[string]ExchangePrice, fees map[string]ExchangeFees) (buyExchange, sellExchange string, profit decimal.Decimal, ok bool) {
+	one := decimal.NewFromInt(1)
+	for _, buyer := range listed {
+		for _, seller := range listed {
+			if buyer == seller {
+				continue
+			}
+			buyFee, sellFee := legFees(fees[buyer], fees[seller])
+			ask, bid := tickRoundedPrices(pairs[buyer][symbol], pairs[seller][symbol])
+			buyPrice := ask.Mul(one.Add(buyFee))
+			sellPrice := bid.Mul(one.Sub(sellFee))
+			buyPrice, sellPrice = applySlippage(buyPrice, sellPrice)
+			spread, valid := profitFraction(buyPrice, sellPrice)
+			if !valid {
+				continue
+			}
+			if !ok || spread.GreaterThan(profit) {
+				buyExchange, sellExchange, profit, ok = buyer, seller, spread, true
+			}
+		}
+	}
+	return buyExchange, sellExchange, profit, ok
+}
+
+// ComputeOpportunity evaluates buying symbol on buyExchange and selling it on
+// sellExchange after the fees of both legs (maker on one leg with
+// -maker-leg). ok is false when the profit is below minProfit or above
+// maxProfit; outlier reports the latter, which is logged since it usually
+// means bad data. Both limits are fractions (0.01 is 1%).
+func ComputeOpportunity(symbol, buyExchange, sellExchange string, buy, sell ExchangePrice, fees map[string]ExchangeFees, minProfit, maxProfit decimal.Decimal) (opportunity ArbitrageOpportunity, ok, outlier bool) {
+	one := decimal.NewFromInt(1)
+	buyFee, sellFee := legFees(fees[buyExchange], fees[sellExchange])
+	ask, bid := tickRoundedPrices(buy, sell)
+	buyPrice := ask.Mul(one.Add(buyFee))
+	sellPrice := bid.Mul(one.Sub(sellFee))
+	buyPrice, sellPrice = applySlippage(buyPrice, sellPrice)
+	profit, valid := profitFraction(buyPrice, sellPrice)
+	if !valid {
+		return ArbitrageOpportunity{}, false, false
+	}
+
+	if profit.GreaterThan(maxProfit) {
+		// Spreads this wide almost always mean the two listings are
+		// different assets sharing a ticker, not a real opportunity.
+		slog.Debug("Discarding outlier above the sanity limit", "symbol", symbol, "buy_exchange", buyExchange, "sell_exchange", sellExchange,
+			"profit_pct", profit.Mul(hundred).StringFixed(2), "max_profit_pct", maxProfit.Mul(hundred))
+		return ArbitrageOpportunity{}, false, true
+	}
+	if profit.LessThan(minProfit) {
+		return ArbitrageOpportunity{}, false, false
+	}
+
+	opportunity = ArbitrageOpportunity{
+		Symbol:       symbol,
+		Base:         buy.Base,
+		Quote:        buy.Quote,
+		BuyExchange:  buyExchange,
+		SellExchange: sellExchange,
+		BuyPrice:     buyPrice,
+		SellPrice:    sellPrice,
+		BuyTickSize:  buy.TickSize,
+		SellTickSize: sell.TickSize,
+		Spread:       sellPrice.Sub(buyPrice),
+		MakerLeg:     reportedMakerLeg(),
+	}
+	opportunity.GrossProfitPercentage, _ = profitPercent(ask, bid)
+	opportunity = withProfit(opportunity, profit)
+	if sell.Quote != buy.Quote {
+		opportunity.SellQuote = sell.Quote
+	}
+	if buy.RawAskPrice.IsPositive() && sell.RawBidPrice.IsPositive() {
+		rawAsk, rawBid := buy.RawAskPrice, sell.RawBidPrice
+		opportunity.RawAsk, opportunity.RawBid = &rawAsk, &rawBid
+	}
+	return withRoundTrip(opportunity), true, false
+}
+
+// BestDirectionOnly makes FindArbitrage report only the most profitable
+// pair of exchanges and direction for each symbol. When it is false every
+// pair of exchanges whose spread qualifies is reported.
+var BestDirectionOnly = true
+
+// FindArbitrage compares every pair of exchanges on every symbol listed on at
+// least two of them and returns, for each symbol, the most profitable
+// fee-adjusted spread if it is at least minProfit, or with BestDirectionOnly
+// unset every such spread. pairs maps exchange names to their prices.
+// Spreads above maxProfit are discarded as bad data. Both limits are
+// fractions (0.01 is 1%).
+func FindArbitrage(pairs map[string]map[string]ExchangePrice, fees map[string]ExchangeFees, minProfit, maxProfit decimal.Decimal) []ArbitrageOpportunity {
+	// Visit the exchanges in a fixed order so ties between venues always
+	// resolve the same way.
+	names := make([]string, 0, len(pairs))
+	for name := range pairs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, name := range names {
+		slog.Debug("Comparing pairs", "exchange", name, "pairs", len(pairs[name]))
+		for symbol := range pairs[name] {
+			if !seen[symbol] {
+				seen[symbol] = true
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+	// Compare the symbols in a fixed order too, so identical prices always
+	// produce opportunities in the same order.
+	sort.Strings(symbols)
+
+	var opportunities []ArbitrageOpportunity
+	outliersDiscarded := 0
+	symbolsCompared := 0
+
+	for _, symbol := range symbols {
+		var listed []string
+		for _, name := range names {
+			price, exists := pairs[name][symbol]
+			// Check for zero prices
+			if !exists || price.AskPrice.IsZero() || price.BidPrice.IsZero() {
+				continue
+			}
+			listed = append(listed, name)
+		}
+		if len(listed) < 2 {
+			continue
+		}
+
+		symbolsCompared++
+
+		var best *ArbitrageOpportunity
+		for _, buyName := range listed {
+			for _, sellName := range listed {
+				if buyName == sellName {
+					continue
+				}
+				opportunity, ok, outlier := ComputeOpportunity(symbol, buyName, sellName, pairs[buyName][symbol], pairs[sellName][symbol], fees, minProfit, maxProfit)
+				if outlier {
+					outliersDiscarded++
+				}
+				if ok && !BestDirectionOnly {
+					opportunities = append(opportunities, opportunity)
+					continue
+				}
+				if !ok || (best != nil && !opportunity.ProfitPercentage.GreaterThan(best.ProfitPercentage)) {
+					continue
+				}
+				best = &opportunity
+			}
+		}
+		if best != nil {
+			opportunities = append(opportunities, *best)
+		}
+	}
+
+	slog.Info("Compared symbols", "symbols", symbolsCompared, "exchanges", len(names), "opportunities", len(opportunities))
+	if outliersDiscarded > 0 {
+		slog.Warn("Discarded outliers above the sanity limit", "outliers", outliersDiscarded, "max_profit_pct", maxProfit.Mul(hundred))
+	}
+
+	return opportunities
+}
@@ -0,0 +1,36 @@
+package arbitrage
+
+import "sync"
+
+// DefaultConcurrency is how many opportunities are depth-checked or
+// confirmed at the same time by default.
+const DefaultConcurrency = 4
+
+// Concurrency bounds how many opportunities FilterByDepth and
+// ConfirmOpportunities fetch at the same time. Their requests are still
+// paced by the per-host rate limits.
+var Concurrency = DefaultConcurrency
+
+// forEachConcurrently calls fn for every index below n, running at most
+// Concurrency calls at a time, and returns once all of them have finished.
+// fn must only write results to its own index.
+func forEachConcurrently(n int, fn func(i int)) {
+	limit := Concurrency
+	if limit < 1 {
+		limit = 1
+	}
+	semaphore := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,42 @@
+package arbitrage
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestForEachConcurrently(t *testing.T) {
+	defer func(previous int) { Concurrency = previous }(Concurrency)
+	Concurrency = 3
+
+	var (
+		mu            sync.Mutex
+		running, peak int
+		done          = make([]bool, 10)
+	)
+	forEachConcurrently(len(done), func(i int) {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+		done[i] = true
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+
+	if peak > Concurrency {
+		t.Errorf("%d calls ran at once, want at most %d", peak, Concurrency)
+	}
+	for i, ok := range done {
+		if !ok {
+			t.Errorf("index %d was never called", i)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package arbitrage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// parseTopOfBook parses a best bid and ask returned for one market. Either
+// side being empty or zero is an error, since the market can't be traded.
+func parseTopOfBook(exchange, symbol, bid, ask string) (decimal.Decimal, decimal.Decimal, error) {
+	bidPrice, err := decimal.NewFromString(bid)
+	if err != nil || !bidPrice.IsPositive() {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("%s returned no usable bid for %s", exchange, symbol)
+	}
+	askPrice, err := decimal.NewFromString(ask)
+	if err != nil || !askPrice.IsPositive() {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("%s returned no usable ask for %s", exchange, symbol)
+	}
+	return bidPrice, askPrice, nil
+}
+
+// fetchTopOfBook re-fetches the best bid and ask of price's market, converted
+// at stableRates like the prices they replace. The fresh prices aren't
+// smoothed, so the raw prices kept from the fetch are dropped.
+func fetchTopOfBook(ctx context.Context, exchange Exchange, price ExchangePrice, stableRates map[string]decimal.Decimal) (ExchangePrice, error) {
+	provider, ok := exchange.(TopOfBookProvider)
+	if !ok {
+		return price, fmt.Errorf("%s does not support single-market tickers", exchange.Name())
+	}
+	bid, ask, err := provider.TopOfBook(ctx, price.Symbol)
+	if err != nil {
+		return price, err
+	}
+	if rate, ok := stableQuoteRate(price, stableRates); ok {
+		bid, ask = bid.Mul(rate), ask.Mul(rate)
+	}
+	price.BidPrice, price.AskPrice = bid, ask
+	price.RawBidPrice, price.RawAskPrice = decimal.Zero, decimal.Zero
+	return price, nil
+}
+
+// ConfirmOpportunities re-fetches both legs of every opportunity and keeps
+// only those whose spread still clears minProfit at the fresh prices, which
+// weeds out momentary bad ticks. The kept opportunities carry the fresh
+// prices. stableRates are the rates the pairs were re-priced at by
+// ConvertStableQuotes, or nil if they weren't. Up to Concurrency
+// opportunities are confirmed at the same time; the kept ones stay in order.
+func ConfirmOpportunities(ctx context.Context, opportunities []ArbitrageOpportunity, exchanges map[string]Exchange, pairs map[string]map[string]ExchangePrice, fees map[string]ExchangeFees, stableRates map[string]decimal.Decimal, minProfit, maxProfit decimal.Decimal) []ArbitrageOpportunity {
+	confirmed := make([]ArbitrageOpportunity, len(opportunities))
+	persisted := make([]bool, len(opportunities))
+	forEachConcurrently(len(opportunities), func(i int) {
+		opportunity := opportunities[i]
+		var (
+			wg                sync.WaitGroup
+			buy, sell         ExchangePrice
+			buyErr, sellErr   error
+			buyName, sellName = opportunity.BuyExchange, opportunity.SellExchange
+		)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			buy, buyErr = fetchTopOfBook(ctx, exchanges[buyName], pairs[buyName][opportunity.Symbol], stableRates)
+		}()
+		go func() {
+			defer wg.Done()
+			sell, sellErr = fetchTopOfBook(ctx, exchanges[sellName], pairs[sellName][opportunity.Symbol], stableRates)
+		}()
+		wg.Wait()
+		if buyErr != nil || sellErr != nil {
+			err := buyErr
+			if err == nil {
+				err = sellErr
+			}
+			slog.Warn("Dropping opportunity that could not be confirmed", "symbol", opportunity.Symbol,
+				"buy_exchange", buyName, "sell_exchange", sellName, "err", err)
+			return
+		}
+
+		confirmed[i], persisted[i], _ = ComputeOpportunity(opportunity.Symbol, buyName, sellName, buy, sell, fees, minProfit, maxProfit)
+		if !persisted[i] {
+			slog.Info("Dropping opportunity that did not persist", "symbol", opportunity.Symbol,
+				"buy_exchange", buyName, "sell_exchange", sellName, "profit_pct", opportunity.ProfitPercentage.StringFixed(2))
+		}
+	})
+
+	kept := []ArbitrageOpportunity{}
+	for i := range confirmed {
+		if persisted[i] {
+			kept = append(kept, confirmed[i])
+		}
+	}
+	slog.Info("Confirmed opportunities", "kept", len(kept), "checked", len(opportunities))
+	return kept
+}
@@ -0,0 +1,126 @@
+package arbitrage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// topOfBookExchange serves fixed bid/ask pairs for the confirmation step.
+type topOfBookExchange struct {
+	name   string
+	quotes map[string][2]string
+}
+
+func (e topOfBookExchange) Name() string { return e.name }
+
+func (e topOfBookExchange) Pairs(ctx context.Context) (map[string]ExchangePrice, error) {
+	return nil, nil
+}
+
+func (e topOfBookExchange) TopOfBook(ctx context.Context, symbol string) (decimal.Decimal, decimal.Decimal, error) {
+	quote, ok := e.quotes[symbol]
+	if !ok {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("unknown symbol %s", symbol)
+	}
+	return parseTopOfBook(e.name, symbol, quote[0], quote[1])
+}
+
+func TestConfirmOpportunities(t *testing.T) {
+	exchanges := map[string]Exchange{
+		"A": topOfBookExchange{name: "A", quotes: map[string][2]string{"BTCUSDT": {"99", "100"}, "ETHUSDT": {"99", "100"}}},
+		"B": topOfBookExchange{name: "B", quotes: map[string][2]string{"BTCUSDT": {"103", "104"}, "ETHUSDT": {"100", "101"}}},
+	}
+	pairs := map[string]map[string]ExchangePrice{
+		"A": {"BTC/USDT": {Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT"}, "ETH/USDT": {Symbol: "ETHUSDT", Base: "ETH", Quote: "USDT"}, "XRP/USDT": {Symbol: "XRPUSDT"}},
+		"B": {"BTC/USDT": {Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT"}, "ETH/USDT": {Symbol: "ETHUSDT", Base: "ETH", Quote: "USDT"}, "XRP/USDT": {Symbol: "XRPUSDT"}},
+	}
+	opportunities := []ArbitrageOpportunity{
+		{Symbol: "BTC/USDT", BuyExchange: "A", SellExchange: "B"},
+		// The ETH spread has since closed to 0%.
+		{Symbol: "ETH/USDT", BuyExchange: "A", SellExchange: "B"},
+		// XRP can't be re-fetched at all.
+		{Symbol: "XRP/USDT", BuyExchange: "A", SellExchange: "B"},
+	}
+	fees := map[string]ExchangeFees{"A": {}, "B": {}}
+
+	kept := ConfirmOpportunities(context.Background(), opportunities, exchanges, pairs, fees, nil, mustDecimal(t, "0.01"), mustDecimal(t, "0.5"))
+	if len(kept) != 1 || kept[0].Symbol != "BTC/USDT" {
+		t.Fatalf("kept %+v, want only BTC/USDT", kept)
+	}
+	if !kept[0].BuyPrice.Equal(mustDecimal(t, "100")) || !kept[0].SellPrice.Equal(mustDecimal(t, "103")) {
+		t.Errorf("confirmed prices buy %s, sell %s; want the fresh 100 and 103", kept[0].BuyPrice, kept[0].SellPrice)
+	}
+}
+
+func TestConfirmSmoothedOpportunity(t *testing.T) {
+	exchanges := map[string]Exchange{
+		"A": topOfBookExchange{name: "A", quotes: map[string][2]string{"BTCUSDT": {"99", "100"}}},
+		"B": topOfBookExchange{name: "B", quotes: map[string][2]string{"BTCUSDT": {"103", "104"}}},
+	}
+	smoother := NewPriceSmoother(3)
+	pairs := map[string]map[string]ExchangePrice{}
+	for name, quote := range map[string][2]string{"A": {"97", "98"}, "B": {"105", "106"}} {
+		pairs[name] = smoother.Smooth(name, map[string]ExchangePrice{"BTC/USDT": {Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT",
+			BidPrice: mustDecimal(t, quote[0]), AskPrice: mustDecimal(t, quote[1])}})
+	}
+	opportunities := []ArbitrageOpportunity{{Symbol: "BTC/USDT", BuyExchange: "A", SellExchange: "B"}}
+	fees := map[string]ExchangeFees{"A": {}, "B": {}}
+
+	kept := ConfirmOpportunities(context.Background(), opportunities, exchanges, pairs, fees, nil, mustDecimal(t, "0.01"), mustDecimal(t, "0.5"))
+	if len(kept) != 1 {
+		t.Fatalf("kept %+v, want the opportunity", kept)
+	}
+	if kept[0].RawAsk != nil || kept[0].RawBid != nil {
+		t.Errorf("confirmed opportunity reports raw ask %v and bid %v from before the re-fetch", kept[0].RawAsk, kept[0].RawBid)
+	}
+	if !kept[0].BuyPrice.Equal(mustDecimal(t, "100")) || !kept[0].SellPrice.Equal(mustDecimal(t, "103")) {
+		t.Errorf("confirmed prices buy %s, sell %s; want the fresh 100 and 103", kept[0].BuyPrice, kept[0].SellPrice)
+	}
+}
+
+func TestConfirmCrossQuoteOpportunity(t *testing.T) {
+	// A quotes BTC in USDC, worth 0.9 USDT, so its ask of 100 USDC is 90
+	// USDT: below B's bid of 92, though not at parity.
+	exchanges := map[string]Exchange{
+		"A": topOfBookExchange{name: "A", quotes: map[string][2]string{"BTCUSDC": {"99", "100"}}},
+		"B": topOfBookExchange{name: "B", quotes: map[string][2]string{"BTCUSDT": {"92", "93"}}},
+	}
+	symbol := CanonicalSymbol("BTC", stableQuoteKey)
+	pairs := map[string]map[string]ExchangePrice{
+		"A": {symbol: {Symbol: "BTCUSDC", Base: "BTC", Quote: "USDC"}},
+		"B": {symbol: {Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT"}},
+	}
+	opportunities := []ArbitrageOpportunity{{Symbol: symbol, BuyExchange: "A", SellExchange: "B"}}
+	fees := map[string]ExchangeFees{"A": {}, "B": {}}
+	rates := map[string]decimal.Decimal{"USDT": decimal.NewFromInt(1), "USDC": mustDecimal(t, "0.9")}
+
+	kept := ConfirmOpportunities(context.Background(), opportunities, exchanges, pairs, fees, rates, mustDecimal(t, "0.01"), mustDecimal(t, "0.5"))
+	if len(kept) != 1 {
+		t.Fatalf("kept %+v, want the opportunity at converted prices", kept)
+	}
+	if !kept[0].BuyPrice.Equal(mustDecimal(t, "90")) || kept[0].SellQuote != "USDT" {
+		t.Errorf("confirmed buy price %s, sell quote %q; want 90 and USDT", kept[0].BuyPrice, kept[0].SellQuote)
+	}
+}
+
+func TestBinanceTopOfBook(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/api/v3/ticker/bookTicker": `[{"symbol":"BTCUSDT","bidPrice":"60000","askPrice":"60000.5"}]`,
+	})
+	defer func(old string) { BinanceBaseURL = old }(BinanceBaseURL)
+	BinanceBaseURL = server.URL
+
+	bid, ask, err := (&BinanceExchange{}).TopOfBook(context.Background(), "BTCUSDT")
+	if err != nil {
+		t.Fatalf("TopOfBook: %v", err)
+	}
+	if !bid.Equal(mustDecimal(t, "60000")) || !ask.Equal(mustDecimal(t, "60000.5")) {
+		t.Errorf("bid %s, ask %s", bid, ask)
+	}
+	if _, _, err := (&BinanceExchange{}).TopOfBook(context.Background(), "ETHUSDT"); err == nil {
+		t.Error("expected an error for a symbol missing from the response")
+	}
+}
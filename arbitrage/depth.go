@@ -0,0 +1,196 @@
+package arbitrage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/shopspring/decimal"
+)
+
+// OrderBookLevel is a single price level of an order book.
+type OrderBookLevel struct {
+	Price    decimal.Decimal
+	Quantity decimal.Decimal
+}
+
+// OrderBook holds the visible depth of one market, best prices first.
+type OrderBook struct {
+	Bids []OrderBookLevel
+	Asks []OrderBookLevel
+}
+
+// parseOrderBookLevels converts [price, quantity] string pairs as returned by
+// the exchanges into levels, skipping anything that doesn't parse.
+func parseOrderBookLevels(raw [][]string) []OrderBookLevel {
+	levels := make([]OrderBookLevel, 0, len(raw))
+	for _, entry := range raw {
+		if len(entry) < 2 {
+			continue
+		}
+		price, err := decimal.NewFromString(entry[0])
+		if err != nil || !price.IsPositive() {
+			continue
+		}
+		quantity, err := decimal.NewFromString(entry[1])
+		if err != nil || !quantity.IsPositive() {
+			continue
+		}
+		levels = append(levels, OrderBookLevel{Price: price, Quantity: quantity})
+	}
+	return levels
+}
+
+// parseMixedOrderBookLevels parses levels that start with price and quantity
+// strings but carry extra fields of other types, such as Kraken's numeric
+// timestamps or Coinbase's order counts.
+func parseMixedOrderBookLevels(raw [][]interface{}) []OrderBookLevel {
+	levels := make([][]string, 0, len(raw))
+	for _, entry := range raw {
+		if len(entry) < 2 {
+			continue
+		}
+		price, _ := entry[0].(string)
+		volume, _ := entry[1].(string)
+		levels = append(levels, []string{price, volume})
+	}
+	return parseOrderBookLevels(levels)
+}
+
+// buyWithQuote walks the asks spending quoteAmount and returns the base
+// quantity received. ok is false when the book is too thin to absorb the
+// whole amount.
+func buyWithQuote(asks []OrderBookLevel, quoteAmount decimal.Decimal) (base decimal.Decimal, ok bool) {
+	remaining := quoteAmount
+	for _, level := range asks {
+		cost := level.Price.Mul(level.Quantity)
+		if cost.GreaterThanOrEqual(remaining) {
+			return base.Add(remaining.DivRound(level.Price, DivisionPrecision)), true
+		}
+		base = base.Add(level.Quantity)
+		remaining = remaining.Sub(cost)
+	}
+	return base, false
+}
+
+// sellBase walks the bids selling baseAmount and returns the quote proceeds.
+// ok is false when the book is too thin to absorb the whole amount.
+func sellBase(bids []OrderBookLevel, baseAmount decimal.Decimal) (quote decimal.Decimal, ok bool) {
+	remaining := baseAmount
+	for _, level := range bids {
+		if level.Quantity.GreaterThanOrEqual(remaining) {
+			return quote.Add(remaining.Mul(level.Price)), true
+		}
+		quote = quote.Add(level.Quantity.Mul(level.Price))
+		remaining = remaining.Sub(level.Quantity)
+	}
+	return quote, false
+}
+
+// checkDepth re-evaluates an opportunity against the order books of both
+// exchanges for a trade of tradeSize in quote currency. It returns the
+// opportunity with prices replaced by the fee-adjusted average fill prices
+// and the profit recomputed, or false if the profit doesn't survive at that
+// size or either book is too thin. A flat slippage is applied on top of the
+// average fill prices.
+func checkDepth(opportunity ArbitrageOpportunity, buyBook, sellBook OrderBook, buyFees, sellFees ExchangeFees, tradeSize, minProfit decimal.Decimal) (ArbitrageOpportunity, bool) {
+	one := decimal.NewFromInt(1)
+
+	// The fee is paid on top of the amount that reaches the book.
+	buyFee, sellFee := legFees(buyFees, sellFees)
+	spendable := tradeSize.DivRound(one.Add(buyFee), DivisionPrecision)
+	base, ok := buyWithQuote(buyBook.Asks, spendable)
+	if !ok || !base.IsPositive() {
+		return opportunity, false
+	}
+	gross, ok := sellBase(sellBook.Bids, base)
+	if !ok {
+		return opportunity, false
+	}
+	proceeds := gross.Mul(one.Sub(sellFee))
+
+	buyPrice, sellPrice := applySlippage(tradeSize.DivRound(base, DivisionPrecision), proceeds.DivRound(base, DivisionPrecision))
+	profit, ok := profitFraction(buyPrice, sellPrice)
+	if !ok || profit.LessThan(minProfit) {
+		return opportunity, false
+	}
+
+	opportunity.BuyPrice = buyPrice
+	opportunity.SellPrice = sellPrice
+	opportunity.Spread = sellPrice.Sub(buyPrice)
+	opportunity.AvailableDepth = profitableDepth(buyBook, sellBook, buyFee, sellFee)
+	opportunity.GrossProfitPercentage, _ = profitPercent(spendable.DivRound(base, DivisionPrecision), gross.DivRound(base, DivisionPrecision))
+	return withRoundTrip(withProfit(opportunity, profit)), true
+}
+
+// profitableDepth is how much can be traded across the two books before the
+// trade stops paying: the quote value of the asks that cost less after fees
+// than the best bid pays, or of the bids that pay more than the best ask
+// costs, whichever is smaller.
+func profitableDepth(buyBook, sellBook OrderBook, buyFee, sellFee decimal.Decimal) decimal.Decimal {
+	if len(buyBook.Asks) == 0 || len(sellBook.Bids) == 0 {
+		return decimal.Zero
+	}
+	one := decimal.NewFromInt(1)
+	bestAsk := buyBook.Asks[0].Price.Mul(one.Add(buyFee))
+	bestBid := sellBook.Bids[0].Price.Mul(one.Sub(sellFee))
+
+	var askDepth, bidDepth decimal.Decimal
+	for _, level := range buyBook.Asks {
+		if !level.Price.Mul(one.Add(buyFee)).LessThan(bestBid) {
+			break
+		}
+		askDepth = askDepth.Add(level.Price.Mul(level.Quantity))
+	}
+	for _, level := range sellBook.Bids {
+		if !level.Price.Mul(one.Sub(sellFee)).GreaterThan(bestAsk) {
+			break
+		}
+		bidDepth = bidDepth.Add(level.Price.Mul(level.Quantity))
+	}
+	return decimal.Min(askDepth, bidDepth)
+}
+
+// FilterByDepth fetches the order books for every opportunity and keeps only
+// those whose profit survives a trade of tradeSize. Up to Concurrency
+// opportunities are checked at the same time; the kept ones stay in order.
+func FilterByDepth(ctx context.Context, opportunities []ArbitrageOpportunity, exchanges map[string]Exchange, pairs map[string]map[string]ExchangePrice, fees map[string]ExchangeFees, tradeSize, minProfit decimal.Decimal) []ArbitrageOpportunity {
+	checked := make([]ArbitrageOpportunity, len(opportunities))
+	survived := make([]bool, len(opportunities))
+	forEachConcurrently(len(opportunities), func(i int) {
+		opportunity := opportunities[i]
+		buyBook, err := fetchOrderBook(ctx, exchanges[opportunity.BuyExchange], pairs[opportunity.BuyExchange][opportunity.Symbol])
+		if err != nil {
+			slog.Warn("Skipping depth check", "symbol", opportunity.Symbol, "exchange", opportunity.BuyExchange, "err", err)
+			return
+		}
+		sellBook, err := fetchOrderBook(ctx, exchanges[opportunity.SellExchange], pairs[opportunity.SellExchange][opportunity.Symbol])
+		if err != nil {
+			slog.Warn("Skipping depth check", "symbol", opportunity.Symbol, "exchange", opportunity.SellExchange, "err", err)
+			return
+		}
+
+		checked[i], survived[i] = checkDepth(opportunity, buyBook, sellBook, fees[opportunity.BuyExchange], fees[opportunity.SellExchange], tradeSize, minProfit)
+		if !survived[i] {
+			slog.Info("Dropping opportunity that does not survive book depth", "symbol", opportunity.Symbol,
+				"buy_exchange", opportunity.BuyExchange, "sell_exchange", opportunity.SellExchange, "trade_size", tradeSize)
+		}
+	})
+
+	var kept []ArbitrageOpportunity
+	for i := range checked {
+		if survived[i] {
+			kept = append(kept, checked[i])
+		}
+	}
+	slog.Info("Checked opportunities against book depth", "kept", len(kept), "checked", len(opportunities), "trade_size", tradeSize)
+	return kept
+}
+
+func fetchOrderBook(ctx context.Context, exchange Exchange, price ExchangePrice) (OrderBook, error) {
+	provider, ok := exchange.(OrderBookProvider)
+	if !ok {
+		return OrderBook{}, fmt.Errorf("%s does not support order book depth", exchange.Name())
+	}
+	return provider.OrderBook(ctx, price.Symbol)
+}
@@ -0,0 +1,75 @@
+package arbitrage
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DumpDir, if set, is a directory every REST response from an exchange is
+// written to before it is parsed, so a surprising result can be reproduced
+// from exactly what the exchange returned. Signed requests are never
+// dumped, since their responses describe the account.
+var DumpDir string
+
+// dumpNameUnsafe matches the runs of characters kept out of dump file names.
+var dumpNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// maxDumpNameLength keeps the request part of dump file names short enough
+// for any file system.
+const maxDumpNameLength = 100
+
+// dumpUnsigned dumps resp with dumpResponse when DumpDir is set and the
+// request was not signed, that is sent without extra headers, and passes
+// resp and err through otherwise.
+func dumpUnsigned(exchange, apiURL string, header http.Header, resp *http.Response, err error) (*http.Response, error) {
+	if err != nil || resp == nil || DumpDir == "" || header != nil {
+		return resp, err
+	}
+	return resp, dumpResponse(exchange, apiURL, resp)
+}
+
+// dumpResponse writes the body of resp to DumpDir, named after the time,
+// exchange and request, and leaves resp readable as before. Failing to
+// write the dump is logged and doesn't fail the request.
+func dumpResponse(exchange, apiURL string, resp *http.Response) error {
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error reading %s response: %v", exchange, err)
+	}
+
+	path := filepath.Join(DumpDir, dumpFileName(exchange, apiURL, time.Now()))
+	if err := ioutil.WriteFile(path, body, 0644); err != nil {
+		slog.Warn("Failed to dump response", "exchange", exchange, "err", err)
+		return nil
+	}
+	slog.Debug("Dumped response", "exchange", exchange, "file", path, "status", resp.StatusCode)
+	return nil
+}
+
+// dumpFileName names the dump of a response to apiURL received at, such as
+// 20240101T000000.123456789Z-Binance-api_v3_ticker_bookTicker.json. The
+// query is included, so requests for different symbols don't share a name.
+func dumpFileName(exchange, apiURL string, at time.Time) string {
+	request := apiURL
+	if parsed, err := url.Parse(apiURL); err == nil {
+		request = parsed.Path
+		if parsed.RawQuery != "" {
+			request += "_" + parsed.RawQuery
+		}
+	}
+	request = strings.Trim(dumpNameUnsafe.ReplaceAllString(request, "_"), "_")
+	if len(request) > maxDumpNameLength {
+		request = request[:maxDumpNameLength]
+	}
+	return at.UTC().Format("20060102T150405.000000000Z") + "-" + exchange + "-" + request + ".json"
+}
@@ -0,0 +1,51 @@
+package arbitrage
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDumpResponses(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/api/v3/ticker/bookTicker": `[{"symbol":"BTCUSDT"}]`,
+		"/api/v3/account":           `{"balances":[]}`,
+	})
+	defer func(old string) { DumpDir = old }(DumpDir)
+	DumpDir = t.TempDir()
+
+	var v interface{}
+	if err := getJSON(context.Background(), ExchangeBinance, server.URL+"/api/v3/ticker/bookTicker", "tickers", &v); err != nil {
+		t.Fatal(err)
+	}
+	// The response is still parsed after being dumped.
+	if tickers, ok := v.([]interface{}); !ok || len(tickers) != 1 {
+		t.Errorf("parsed %v", v)
+	}
+	// Signed responses describe the account and are never dumped.
+	if err := getSignedJSON(context.Background(), ExchangeBinance, server.URL+"/api/v3/account?signature=abc", "account", http.Header{"X-Mbx-Apikey": {"key"}}, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(DumpDir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 {
+		t.Fatalf("dumped %v, want only the tickers", paths)
+	}
+	if data, err := ioutil.ReadFile(paths[0]); err != nil || string(data) != `[{"symbol":"BTCUSDT"}]` {
+		t.Errorf("dump = %q, %v", data, err)
+	}
+}
+
+func TestDumpFileName(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 123456789, time.UTC)
+	got := dumpFileName(ExchangeBinance, "https://api.binance.com/api/v3/exchangeInfo?symbol=BTCUSDT", at)
+	if want := "20240101T000000.123456789Z-Binance-api_v3_exchangeInfo_symbol_BTCUSDT.json"; got != want {
+		t.Errorf("dumpFileName = %q, want %q", got, want)
+	}
+}
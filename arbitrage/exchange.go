@@ -0,0 +1,42 @@
+package arbitrage
+
+import (
+	"context"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Exchange is a source of current prices, keyed by canonical symbol. Adding a
+// venue means implementing it and adding it to the list in main.
+type Exchange interface {
+	Name() string
+	Pairs(ctx context.Context) (map[string]ExchangePrice, error)
+}
+
+// OrderBookProvider is implemented by exchanges that can fetch the order
+// book of a single market, identified by the exchange's own symbol.
+type OrderBookProvider interface {
+	OrderBook(ctx context.Context, symbol string) (OrderBook, error)
+}
+
+// TopOfBookProvider is implemented by exchanges that can fetch the best bid
+// and ask of a single market, identified by the exchange's own symbol, more
+// cheaply than all of their prices.
+type TopOfBookProvider interface {
+	TopOfBook(ctx context.Context, symbol string) (bid, ask decimal.Decimal, err error)
+}
+
+// FundingRateProvider is implemented by exchanges with perpetual markets. It
+// returns their funding rates keyed by canonical symbol, so they line up
+// with spot prices.
+type FundingRateProvider interface {
+	FundingRates(ctx context.Context) (map[string]FundingRate, error)
+}
+
+// ServerTimeReporter is implemented by exchanges whose API reports its own
+// clock alongside prices. ServerTime returns the time reported with the last
+// Pairs call, or the zero time if none was.
+type ServerTimeReporter interface {
+	ServerTime() time.Time
+}
@@ -0,0 +1,225 @@
+package arbitrage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// StringList is a list setting that is written as a comma-separated string on
+// the command line and as either an array or a comma-separated string in
+// the config file.
+type StringList []string
+
+func (l StringList) String() string {
+	return strings.Join(l, ",")
+}
+
+// Set implements flag.Value.
+func (l *StringList) Set(value string) error {
+	*l = splitList(value)
+	return nil
+}
+
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*l = list
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("expected a list or a comma-separated string: %v", err)
+	}
+	*l = splitList(value)
+	return nil
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, entry := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry != "" && !strings.HasPrefix(entry, "#") {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// symbolMatchKey reduces a symbol in any of the usual notations (BTCUSDT,
+// BTC-USDT, BTC/USDT, btc_usdt) to a form that can be compared.
+func symbolMatchKey(symbol string) string {
+	return strings.NewReplacer("/", "", "-", "", "_", "").Replace(CanonicalAsset(symbol))
+}
+
+// SymbolFilter restricts the pairs that are compared. A non-empty whitelist
+// takes precedence over the blacklist.
+type SymbolFilter struct {
+	whitelist map[string]bool
+	blacklist map[string]bool
+}
+
+// NewSymbolFilter builds a filter from the configured lists. Each list may
+// instead name a file containing symbols, separated by commas or newlines.
+func NewSymbolFilter(whitelist, blacklist []string) (SymbolFilter, error) {
+	var filter SymbolFilter
+	var err error
+	if filter.whitelist, err = loadSymbolSet(whitelist); err != nil {
+		return SymbolFilter{}, err
+	}
+	if filter.blacklist, err = loadSymbolSet(blacklist); err != nil {
+		return SymbolFilter{}, err
+	}
+	return filter, nil
+}
+
+func loadSymbolSet(entries []string) (map[string]bool, error) {
+	if len(entries) == 1 {
+		if info, err := os.Stat(entries[0]); err == nil && !info.IsDir() {
+			data, err := ioutil.ReadFile(entries[0])
+			if err != nil {
+				return nil, fmt.Errorf("error reading symbol list: %v", err)
+			}
+			entries = splitList(string(data))
+		}
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	set := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		set[symbolMatchKey(entry)] = true
+	}
+	return set, nil
+}
+
+func (f SymbolFilter) Active() bool {
+	return len(f.whitelist) > 0 || len(f.blacklist) > 0
+}
+
+func (f SymbolFilter) Allows(price ExchangePrice) bool {
+	key := symbolMatchKey(price.Base + price.Quote)
+	if len(f.whitelist) > 0 {
+		return f.whitelist[key]
+	}
+	return !f.blacklist[key]
+}
+
+// apply returns the pairs the filter allows.
+func (f SymbolFilter) Apply(pairs map[string]ExchangePrice) map[string]ExchangePrice {
+	if !f.Active() {
+		return pairs
+	}
+	filtered := make(map[string]ExchangePrice, len(pairs))
+	for symbol, price := range pairs {
+		if f.Allows(price) {
+			filtered[symbol] = price
+		}
+	}
+	return filtered
+}
+
+// FilterByAssets returns the pairs whose base asset is one of bases and whose
+// quote asset is one of quotes, matched on the canonical assets so BTCUSDT
+// and BTC-USDC both count as base BTC. An empty list allows every asset.
+func FilterByAssets(pairs map[string]ExchangePrice, bases, quotes []string) map[string]ExchangePrice {
+	baseSet, quoteSet := assetSet(bases), assetSet(quotes)
+	filtered := make(map[string]ExchangePrice, len(pairs))
+	for symbol, price := range pairs {
+		if (baseSet == nil || baseSet[price.Base]) && (quoteSet == nil || quoteSet[price.Quote]) {
+			filtered[symbol] = price
+		}
+	}
+	return filtered
+}
+
+func assetSet(assets []string) map[string]bool {
+	if len(assets) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(assets))
+	for _, asset := range assets {
+		set[CanonicalAsset(asset)] = true
+	}
+	return set
+}
+
+// FilterByVolume returns the pairs that traded at least minVolume in quote
+// currency over the last 24 hours. Thinly traded pairs produce most of the
+// absurd spreads.
+func FilterByVolume(pairs map[string]ExchangePrice, minVolume decimal.Decimal) map[string]ExchangePrice {
+	filtered := make(map[string]ExchangePrice, len(pairs))
+	for symbol, price := range pairs {
+		if price.QuoteVolume.GreaterThanOrEqual(minVolume) {
+			filtered[symbol] = price
+		}
+	}
+	return filtered
+}
+
+// FilterByPrice returns the pairs whose bid and ask are both at least
+// minPrice. For assets priced a few ticks above zero, a single tick is a
+// large fraction of the price, so their spreads are mostly rounding noise.
+func FilterByPrice(pairs map[string]ExchangePrice, minPrice decimal.Decimal) map[string]ExchangePrice {
+	filtered := make(map[string]ExchangePrice, len(pairs))
+	for symbol, price := range pairs {
+		if price.BidPrice.GreaterThanOrEqual(minPrice) && price.AskPrice.GreaterThanOrEqual(minPrice) {
+			filtered[symbol] = price
+		}
+	}
+	return filtered
+}
+
+// FilterByUpdateAge drops the pairs whose prices were last updated more
+// than maxAge before now, since a market that stopped quoting is usually
+// halted or illiquid and its spread can't be traded. Pairs without a known
+// update time are kept. It also returns how many pairs were dropped.
+func FilterByUpdateAge(pairs map[string]ExchangePrice, maxAge time.Duration, now time.Time) (map[string]ExchangePrice, int) {
+	cutoff := now.Add(-maxAge)
+	filtered := make(map[string]ExchangePrice, len(pairs))
+	for symbol, price := range pairs {
+		if price.UpdatedAt.IsZero() || !price.UpdatedAt.Before(cutoff) {
+			filtered[symbol] = price
+		}
+	}
+	return filtered, len(pairs) - len(filtered)
+}
+
+// FilterCrossed drops the pairs whose bid is at or above their ask: a crossed
+// or locked book on a single exchange is bad data or a momentary glitch, and
+// comparing it produces exactly the large false profits outliers come from.
+// It also returns the symbols dropped, sorted.
+func FilterCrossed(pairs map[string]ExchangePrice) (map[string]ExchangePrice, []string) {
+	filtered := make(map[string]ExchangePrice, len(pairs))
+	var crossed []string
+	for symbol, price := range pairs {
+		if price.BidPrice.GreaterThanOrEqual(price.AskPrice) {
+			crossed = append(crossed, symbol)
+			continue
+		}
+		filtered[symbol] = price
+	}
+	sort.Strings(crossed)
+	return filtered, crossed
+}
+
+// FilterByAge drops the pairs listed less than minAge before now. New
+// listings are where different tokens sharing a ticker and wild opening
+// spreads show up. Pairs without a known listing time are kept.
+func FilterByAge(pairs map[string]ExchangePrice, minAge time.Duration, now time.Time) map[string]ExchangePrice {
+	cutoff := now.Add(-minAge)
+	filtered := make(map[string]ExchangePrice, len(pairs))
+	for symbol, price := range pairs {
+		if price.ListedAt.IsZero() || !price.ListedAt.After(cutoff) {
+			filtered[symbol] = price
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,173 @@
+package arbitrage
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// defaultFundingInterval is how often perpetuals settle funding unless the
+// exchange says otherwise.
+const defaultFundingInterval = 8 * time.Hour
+
+// FundingRate is the funding of a perpetual market. Rate is the fraction of
+// the position value longs pay shorts at NextFundingTime, as estimated for
+// the running funding period; a negative rate is paid by shorts. BidPrice
+// and AskPrice are the perpetual's own top of book.
+type FundingRate struct {
+	Symbol          string
+	Base            string
+	Quote           string
+	BidPrice        decimal.Decimal
+	AskPrice        decimal.Decimal
+	Rate            decimal.Decimal
+	Interval        time.Duration
+	NextFundingTime time.Time
+}
+
+// BasisOpportunity is a cash-and-carry trade: buying Symbol spot on
+// SpotExchange at SpotPrice and shorting the perpetual PerpSymbol on
+// PerpExchange at PerpPrice, then collecting funding while the two legs
+// hedge each other. Prices are fee-adjusted like those of an
+// ArbitrageOpportunity. BasisPercentage is the perpetual's premium over
+// spot at entry, which the trade also earns if it converges.
+// FundingRatePercentage is paid every FundingInterval; AnnualizedFunding is
+// that rate over a year, in percent, assuming it stays constant.
+type BasisOpportunity struct {
+	Symbol                string          `json:"symbol"`
+	Base                  string          `json:"base"`
+	Quote                 string          `json:"quote"`
+	SpotExchange          string          `json:"spot_exchange"`
+	PerpExchange          string          `json:"perp_exchange"`
+	PerpSymbol            string          `json:"perp_symbol"`
+	SpotPrice             decimal.Decimal `json:"spot_price"`
+	PerpPrice             decimal.Decimal `json:"perp_price"`
+	BasisPercentage       decimal.Decimal `json:"basis_percentage"`
+	FundingRatePercentage decimal.Decimal `json:"funding_rate_percentage"`
+	FundingInterval       Duration        `json:"funding_interval"`
+	NextFundingTime       time.Time       `json:"next_funding_time"`
+	AnnualizedFunding     decimal.Decimal `json:"annualized_funding"`
+}
+
+// FetchFundingRates fetches the funding rates of every exchange that has
+// perpetuals. Exchanges that fail are logged and left out.
+func FetchFundingRates(ctx context.Context, exchanges map[string]Exchange) map[string]map[string]FundingRate {
+	names := make([]string, 0, len(exchanges))
+	for name := range exchanges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	funding := make(map[string]map[string]FundingRate)
+	for _, name := range names {
+		provider, ok := exchanges[name].(FundingRateProvider)
+		if !ok {
+			continue
+		}
+		rates, err := provider.FundingRates(ctx)
+		if err != nil {
+			slog.Warn("Failed to fetch funding rates", "exchange", name, "err", err)
+			continue
+		}
+		slog.Info("Retrieved funding rates", "exchange", name, "perpetuals", len(rates))
+		funding[name] = rates
+	}
+	return funding
+}
+
+// SpotPairs returns the prices of the exchanges whose prices are spot, which
+// excludes Bybit when it is scanned for perpetuals.
+func SpotPairs(pairs map[string]map[string]ExchangePrice) map[string]map[string]ExchangePrice {
+	if BybitCategory == BybitCategorySpot {
+		return pairs
+	}
+	spot := make(map[string]map[string]ExchangePrice, len(pairs))
+	for name, prices := range pairs {
+		if name != ExchangeBybit {
+			spot[name] = prices
+		}
+	}
+	return spot
+}
+
+// FindBasisTrades pairs every perpetual whose longs pay funding with the
+// cheapest fee-adjusted spot market of the same symbol, and returns those
+// whose annualized funding is at least minAPR (a fraction, 0.1 is 10%), best
+// first. spot maps exchange names to their spot prices.
+func FindBasisTrades(spot map[string]map[string]ExchangePrice, funding map[string]map[string]FundingRate, fees map[string]ExchangeFees, minAPR decimal.Decimal) []BasisOpportunity {
+	one := decimal.NewFromInt(1)
+	year := decimal.NewFromInt(int64(365 * 24 * time.Hour))
+
+	spotNames := make([]string, 0, len(spot))
+	for name := range spot {
+		spotNames = append(spotNames, name)
+	}
+	sort.Strings(spotNames)
+
+	var trades []BasisOpportunity
+	for perpName, rates := range funding {
+		for key, rate := range rates {
+			// Shorting spot to collect negative funding is rarely possible,
+			// so only positive funding is a trade.
+			if !rate.Rate.IsPositive() || rate.Interval <= 0 || !rate.BidPrice.IsPositive() {
+				continue
+			}
+			apr := rate.Rate.Mul(year).DivRound(decimal.NewFromInt(int64(rate.Interval)), DivisionPrecision)
+			if apr.LessThan(minAPR) {
+				continue
+			}
+
+			var best BasisOpportunity
+			found := false
+			for _, spotName := range spotNames {
+				price, ok := spot[spotName][key]
+				if !ok {
+					// Spot markets may be merged under a shared stablecoin key.
+					price, ok = spot[spotName][CanonicalSymbol(rate.Base, stableQuoteKey)]
+				}
+				if !ok || !price.AskPrice.IsPositive() {
+					continue
+				}
+				buyFee, sellFee := legFees(fees[spotName], fees[perpName])
+				spotPrice, perpPrice := applySlippage(price.AskPrice.Mul(one.Add(buyFee)), rate.BidPrice.Mul(one.Sub(sellFee)))
+				if found && !spotPrice.LessThan(best.SpotPrice) {
+					continue
+				}
+				best = BasisOpportunity{
+					Symbol:                key,
+					Base:                  rate.Base,
+					Quote:                 rate.Quote,
+					SpotExchange:          spotName,
+					PerpExchange:          perpName,
+					PerpSymbol:            rate.Symbol,
+					SpotPrice:             spotPrice,
+					PerpPrice:             perpPrice,
+					BasisPercentage:       perpPrice.Sub(spotPrice).DivRound(spotPrice, DivisionPrecision).Mul(hundred),
+					FundingRatePercentage: rate.Rate.Mul(hundred),
+					FundingInterval:       Duration(rate.Interval),
+					NextFundingTime:       rate.NextFundingTime,
+					AnnualizedFunding:     apr.Mul(hundred),
+				}
+				found = true
+			}
+			if found {
+				trades = append(trades, best)
+			}
+		}
+	}
+
+	sort.Slice(trades, func(i, j int) bool {
+		a, b := trades[i], trades[j]
+		if !a.AnnualizedFunding.Equal(b.AnnualizedFunding) {
+			return a.AnnualizedFunding.GreaterThan(b.AnnualizedFunding)
+		}
+		if a.Symbol != b.Symbol {
+			return a.Symbol < b.Symbol
+		}
+		return a.PerpExchange < b.PerpExchange
+	})
+	return trades
+}
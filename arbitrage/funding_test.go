@@ -0,0 +1,115 @@
+package arbitrage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetBybitFundingRates(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/v5/market/instruments-info": `{"result":{"list":[
+			{"symbol":"BTCUSDT","baseCoin":"BTC","quoteCoin":"USDT","status":"Trading","contractType":"LinearPerpetual","fundingInterval":480},
+			{"symbol":"ETHUSDT","baseCoin":"ETH","quoteCoin":"USDT","status":"Trading","contractType":"LinearPerpetual","fundingInterval":240},
+			{"symbol":"BTC-27DEC24","baseCoin":"BTC","quoteCoin":"USDT","status":"Trading","contractType":"LinearFutures"}
+		]}}`,
+		"/v5/market/tickers": `{"result":{"list":[
+			{"symbol":"BTCUSDT","bid1Price":"60010","ask1Price":"60011","fundingRate":"0.0001","nextFundingTime":"1700006400000"},
+			{"symbol":"ETHUSDT","bid1Price":"3000","ask1Price":"3000.1","fundingRate":"-0.00005","nextFundingTime":"1700006400000"},
+			{"symbol":"BTC-27DEC24","bid1Price":"61000","ask1Price":"61001","fundingRate":"","nextFundingTime":"0"}
+		]}}`,
+	})
+	defer func(old string) { BybitBaseURL = old }(BybitBaseURL)
+	BybitBaseURL = server.URL
+
+	rates, err := getBybitFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("getBybitFundingRates: %v", err)
+	}
+	if len(rates) != 2 {
+		t.Fatalf("got %d rates, want 2: %v", len(rates), rates)
+	}
+	btc := rates["BTC/USDT"]
+	if btc.Symbol != "BTCUSDT" || !btc.Rate.Equal(mustDecimal(t, "0.0001")) || btc.Interval != 8*time.Hour {
+		t.Errorf("BTC/USDT = %+v", btc)
+	}
+	if !btc.BidPrice.Equal(mustDecimal(t, "60010")) || !btc.NextFundingTime.Equal(time.Unix(1700006400, 0)) {
+		t.Errorf("BTC/USDT bid %s, next funding %s", btc.BidPrice, btc.NextFundingTime)
+	}
+	if got := rates["ETH/USDT"].Interval; got != 4*time.Hour {
+		t.Errorf("ETH/USDT interval = %s, want 4h", got)
+	}
+}
+
+func TestGetBinanceFundingRates(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/fapi/v1/exchangeInfo": `{"symbols":[
+			{"symbol":"BTCUSDT","status":"TRADING","contractType":"PERPETUAL","baseAsset":"BTC","quoteAsset":"USDT"},
+			{"symbol":"SOLUSDT","status":"TRADING","contractType":"PERPETUAL","baseAsset":"SOL","quoteAsset":"USDT"},
+			{"symbol":"BTCUSDT_241227","status":"TRADING","contractType":"CURRENT_QUARTER","baseAsset":"BTC","quoteAsset":"USDT"}
+		]}`,
+		"/fapi/v1/premiumIndex": `[
+			{"symbol":"BTCUSDT","lastFundingRate":"0.00010000","nextFundingTime":1700006400000},
+			{"symbol":"SOLUSDT","lastFundingRate":"0.00030000","nextFundingTime":1700006400000},
+			{"symbol":"BTCUSDT_241227","lastFundingRate":"","nextFundingTime":0}
+		]`,
+		"/fapi/v1/ticker/bookTicker": `[
+			{"symbol":"BTCUSDT","bidPrice":"60020.0","askPrice":"60020.1"},
+			{"symbol":"SOLUSDT","bidPrice":"150.10","askPrice":"150.11"}
+		]`,
+		"/fapi/v1/fundingInfo": `[{"symbol":"SOLUSDT","fundingIntervalHours":4}]`,
+	})
+	defer func(old string) { BinanceFuturesBaseURL = old }(BinanceFuturesBaseURL)
+	BinanceFuturesBaseURL = server.URL
+
+	rates, err := getBinanceFundingRates(context.Background())
+	if err != nil {
+		t.Fatalf("getBinanceFundingRates: %v", err)
+	}
+	if len(rates) != 2 {
+		t.Fatalf("got %d rates, want 2: %v", len(rates), rates)
+	}
+	if got := rates["BTC/USDT"]; got.Interval != defaultFundingInterval || !got.AskPrice.Equal(mustDecimal(t, "60020.1")) {
+		t.Errorf("BTC/USDT = %+v", got)
+	}
+	if got := rates["SOL/USDT"]; got.Interval != 4*time.Hour || !got.Rate.Equal(mustDecimal(t, "0.0003")) {
+		t.Errorf("SOL/USDT = %+v", got)
+	}
+}
+
+func TestFindBasisTrades(t *testing.T) {
+	spot := map[string]map[string]ExchangePrice{
+		"A": {"BTC/USDT": {Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, "99"), AskPrice: mustDecimal(t, "100")}},
+		"B": {"BTC/USDT": {Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, "100"), AskPrice: mustDecimal(t, "101")}},
+	}
+	funding := map[string]map[string]FundingRate{
+		"P": {
+			"BTC/USDT": {Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, "100.5"), AskPrice: mustDecimal(t, "100.6"),
+				Rate: mustDecimal(t, "0.0001"), Interval: 8 * time.Hour},
+			// Negative funding is paid by shorts, so there is no trade.
+			"ETH/USDT": {Symbol: "ETHUSDT", Base: "ETH", Quote: "USDT", BidPrice: mustDecimal(t, "3000"), AskPrice: mustDecimal(t, "3001"),
+				Rate: mustDecimal(t, "-0.0001"), Interval: 8 * time.Hour},
+		},
+	}
+	fees := map[string]ExchangeFees{"A": {}, "B": {}, "P": {}}
+
+	trades := FindBasisTrades(spot, funding, fees, mustDecimal(t, "0.1"))
+	if len(trades) != 1 {
+		t.Fatalf("got %d trades, want 1: %+v", len(trades), trades)
+	}
+	trade := trades[0]
+	if trade.SpotExchange != "A" || trade.PerpExchange != "P" || trade.PerpSymbol != "BTCUSDT" {
+		t.Errorf("trade = %+v, want spot on A and the perpetual on P", trade)
+	}
+	// 0.01% three times a day for a year.
+	if !trade.AnnualizedFunding.Equal(mustDecimal(t, "10.95")) {
+		t.Errorf("annualized funding = %s%%, want 10.95%%", trade.AnnualizedFunding)
+	}
+	if !trade.BasisPercentage.Equal(mustDecimal(t, "0.5")) {
+		t.Errorf("basis = %s%%, want 0.5%%", trade.BasisPercentage)
+	}
+
+	if trades := FindBasisTrades(spot, funding, fees, mustDecimal(t, "0.2")); len(trades) != 0 {
+		t.Errorf("got %d trades below a 20%% minimum", len(trades))
+	}
+}
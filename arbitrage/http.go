@@ -0,0 +1,174 @@
+package arbitrage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const DefaultHTTPTimeout = 10 * time.Second
+
+// maxErrorBodySnippet limits how much of an unexpected response body is
+// included in error messages.
+const maxErrorBodySnippet = 300
+
+const (
+	DefaultMaxRetries = 3
+	retryBaseDelay    = 500 * time.Millisecond
+)
+
+// HTTPClient is shared by every exchange fetcher. Unlike http.DefaultClient
+// it has a timeout, so an unresponsive exchange fails the fetch instead of
+// hanging the program. Its default transport honours HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY; SetProxy overrides them.
+var HTTPClient = &http.Client{Timeout: DefaultHTTPTimeout}
+
+// SetProxy sends every REST request and stream connection through proxyURL,
+// an http, https or socks5 URL, regardless of the proxy environment
+// variables.
+func SetProxy(proxyURL *url.URL) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	HTTPClient.Transport = transport
+	streamDialer.Proxy = http.ProxyURL(proxyURL)
+}
+
+// DefaultUserAgent identifies the scanner to the exchanges. Some endpoints
+// throttle Go's default User-Agent harder than a descriptive one.
+const DefaultUserAgent = "crypto-arbitrage-golang (+https://github.com/mirimadahmed/crypto-arbitrage-golang)"
+
+// RequestHeaders are sent with every REST request and stream connection to
+// an exchange. The headers of signed requests are added on top.
+var RequestHeaders = http.Header{"User-Agent": {DefaultUserAgent}}
+
+// MaxRetries is how many times getWithRetry repeats a request after a
+// transient failure.
+var MaxRetries = DefaultMaxRetries
+
+// getWithRetry issues a GET request to one of exchange's endpoints, retrying
+// network errors and 5xx responses up to MaxRetries times with exponential
+// backoff. Any other response, including 4xx, is returned immediately. When
+// the retries are exhausted on a 5xx the last response is returned to the
+// caller as-is. Every request first waits out any rate-limit pause the
+// exchange's previous responses asked for, then for a token from its host's
+// limiter. Cancelling ctx aborts the request and any wait before it.
+func getWithRetry(ctx context.Context, exchange, apiURL string) (*http.Response, error) {
+	return getWithHeaders(ctx, exchange, apiURL, nil)
+}
+
+// getWithHeaders is getWithRetry with extra request headers, for signed
+// requests. Their query strings carry signatures, so the URL is logged and
+// reported in errors without its query whenever headers are given.
+func getWithHeaders(ctx context.Context, exchange, apiURL string, header http.Header) (*http.Response, error) {
+	logURL := apiURL
+	if header != nil {
+		logURL = strings.SplitN(apiURL, "?", 2)[0]
+	}
+	delay := retryBaseDelay
+	for attempt := 0; ; attempt++ {
+		if err := rateLimits.wait(ctx, exchange); err != nil {
+			return nil, err
+		}
+		if err := requestLimits.wait(ctx, apiURL); err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range RequestHeaders {
+			req.Header[key] = values
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		resp, err := HTTPClient.Do(req)
+		if urlErr, ok := err.(*url.Error); ok {
+			urlErr.URL = logURL
+		}
+		if err == nil {
+			rateLimits.observe(exchange, resp)
+			requestLimits.observe(logURL, resp)
+		}
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			return dumpUnsigned(exchange, apiURL, header, resp, nil)
+		}
+		// A cancelled request is not worth retrying.
+		if attempt >= MaxRetries || ctx.Err() != nil {
+			return dumpUnsigned(exchange, apiURL, header, resp, err)
+		}
+
+		if err != nil {
+			slog.Warn("Request failed, retrying", "exchange", exchange, "url", logURL, "attempt", attempt+1, "attempts", MaxRetries+1, "delay", delay, "err", err)
+		} else {
+			resp.Body.Close()
+			slog.Warn("Request failed, retrying", "exchange", exchange, "url", logURL, "attempt", attempt+1, "attempts", MaxRetries+1, "delay", delay, "status", resp.Status)
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+		delay *= 2
+	}
+}
+
+// sleepContext sleeps for d, returning early with the context's error if it
+// is cancelled first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// CheckStatus returns a descriptive error when resp is not a 200, including
+// the start of the body, so rate-limit and HTML error pages don't surface as
+// cryptic JSON errors.
+func CheckStatus(exchange string, resp *http.Response, body []byte) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	snippet := body
+	if len(snippet) > maxErrorBodySnippet {
+		snippet = snippet[:maxErrorBodySnippet]
+	}
+	return fmt.Errorf("%s returned status %d: %s", exchange, resp.StatusCode, snippet)
+}
+
+// getJSON fetches apiURL with getWithRetry and unmarshals the response into
+// v. what names the resource in errors, e.g. "Bybit perpetual tickers".
+func getJSON(ctx context.Context, exchange, apiURL, what string, v interface{}) error {
+	return getSignedJSON(ctx, exchange, apiURL, what, nil, v)
+}
+
+// getSignedJSON is getJSON with the extra request headers of getWithHeaders.
+func getSignedJSON(ctx context.Context, exchange, apiURL, what string, header http.Header, v interface{}) error {
+	resp, err := getWithHeaders(ctx, exchange, apiURL, header)
+	if err != nil {
+		return fmt.Errorf("error fetching %s: %v", what, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading %s response: %v", exchange, err)
+	}
+	if err := CheckStatus(exchange, resp, body); err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return fmt.Errorf("error unmarshalling %s: %v", what, err)
+	}
+	return nil
+}
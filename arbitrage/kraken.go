@@ -0,0 +1,267 @@
+package arbitrage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// KrakenExchange fetches spot prices from the Kraken public API.
+type KrakenExchange struct{}
+
+func (KrakenExchange) Name() string {
+	return ExchangeKraken
+}
+
+func (KrakenExchange) Pairs(ctx context.Context) (map[string]ExchangePrice, error) {
+	return getKrakenPairs(ctx)
+}
+
+func (KrakenExchange) OrderBook(ctx context.Context, pair string) (OrderBook, error) {
+	return getKrakenOrderBook(ctx, pair)
+}
+
+func (KrakenExchange) TopOfBook(ctx context.Context, pair string) (decimal.Decimal, decimal.Decimal, error) {
+	tickers, err := getKrakenTickers(ctx, pair)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	// Kraken keys the result by its own pair name, which is what pair is.
+	ticker, ok := tickers.Result[pair]
+	if !ok || len(ticker.Bid) == 0 || len(ticker.Ask) == 0 {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("Kraken returned no ticker for %s", pair)
+	}
+	return parseTopOfBook(ExchangeKraken, pair, ticker.Bid[0], ticker.Ask[0])
+}
+
+type KrakenAssetPairs struct {
+	Error  []string `json:"error"`
+	Result RecordMap[struct {
+		Altname string `json:"altname"`
+		WSName  string `json:"wsname"`
+		Base    string `json:"base"`
+		Quote   string `json:"quote"`
+		Status  string `json:"status"`
+		// TickSize is the price increment, e.g. "0.1".
+		TickSize string `json:"tick_size"`
+	}] `json:"result"`
+}
+
+type KrakenTickers struct {
+	Error  []string `json:"error"`
+	Result RecordMap[struct {
+		Ask []string `json:"a"`
+		Bid []string `json:"b"`
+		// Volume and VWAP hold [today, last 24 hours]; volume is in the
+		// base asset.
+		Volume []string `json:"v"`
+		VWAP   []string `json:"p"`
+	}] `json:"result"`
+}
+
+// KrakenOrderBook levels are [price, volume, timestamp] with a numeric
+// timestamp, so they can't be decoded as plain strings.
+type KrakenOrderBook struct {
+	Error  []string `json:"error"`
+	Result map[string]struct {
+		Bids [][]interface{} `json:"bids"`
+		Asks [][]interface{} `json:"asks"`
+	} `json:"result"`
+}
+
+// krakenLegacyAssets are the asset codes Kraken still reports with the
+// historical X (crypto) or Z (fiat) prefix.
+var krakenLegacyAssets = map[string]bool{
+	"XXBT": true, "XETH": true, "XLTC": true, "XXRP": true, "XXLM": true,
+	"XXDG": true, "XETC": true, "XMLN": true, "XREP": true, "XXMR": true,
+	"XZEC": true, "ZUSD": true, "ZEUR": true, "ZGBP": true, "ZCAD": true,
+	"ZJPY": true, "ZAUD": true, "ZCHF": true,
+}
+
+// krakenAssetAliases maps Kraken's own asset names to the names every other
+// exchange uses.
+var krakenAssetAliases = map[string]string{
+	"XBT": "BTC",
+	"XDG": "DOGE",
+}
+
+// normalizeKrakenAsset converts a Kraken asset code such as XXBT, ZUSD or
+// XDG into the common code (BTC, USD, DOGE).
+func normalizeKrakenAsset(code string) string {
+	code = CanonicalAsset(code)
+	if krakenLegacyAssets[code] {
+		code = code[1:]
+	}
+	if alias, ok := krakenAssetAliases[code]; ok {
+		return alias
+	}
+	return code
+}
+
+func getKrakenPairs(ctx context.Context) (map[string]ExchangePrice, error) {
+	var (
+		wg                       sync.WaitGroup
+		assetPairs               KrakenAssetPairs
+		tickers                  KrakenTickers
+		assetPairsErr, tickerErr error
+	)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		assetPairs, assetPairsErr = getKrakenAssetPairs(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		tickers, tickerErr = getKrakenTickers(ctx, "")
+	}()
+	wg.Wait()
+
+	if assetPairsErr != nil {
+		return nil, assetPairsErr
+	}
+	if tickerErr != nil {
+		return nil, tickerErr
+	}
+
+	parser := newTickerParser(ExchangeKraken)
+	pairs := make(map[string]ExchangePrice)
+	for name, ticker := range tickers.Result {
+		info, exists := assetPairs.Result[name]
+		if !exists || info.Status != "online" || len(ticker.Bid) == 0 || len(ticker.Ask) == 0 {
+			continue
+		}
+
+		// wsname ("XBT/USD") already uses the short asset names; fall back to
+		// the prefixed base/quote codes when it is missing.
+		base, quote := info.Base, info.Quote
+		if parts := strings.Split(info.WSName, "/"); len(parts) == 2 {
+			base, quote = parts[0], parts[1]
+		}
+		base, quote = normalizeKrakenAsset(base), normalizeKrakenAsset(quote)
+
+		bidPrice, askPrice, ok := parser.prices(name, ticker.Bid[0], ticker.Ask[0])
+		if !ok {
+			continue
+		}
+		var quoteVolume decimal.Decimal
+		if len(ticker.Volume) > 1 && len(ticker.VWAP) > 1 {
+			volume, volumeErr := decimal.NewFromString(ticker.Volume[1])
+			vwap, vwapErr := decimal.NewFromString(ticker.VWAP[1])
+			if volumeErr == nil && vwapErr == nil {
+				quoteVolume = volume.Mul(vwap)
+			}
+		}
+		tickSize, _ := decimal.NewFromString(info.TickSize)
+		pairs[CanonicalSymbol(base, quote)] = ExchangePrice{
+			Symbol:      name,
+			Base:        base,
+			Quote:       quote,
+			BidPrice:    bidPrice,
+			AskPrice:    askPrice,
+			QuoteVolume: quoteVolume,
+			TickSize:    tickSize,
+		}
+	}
+
+	parser.report()
+	return pairs, nil
+}
+
+func getKrakenAssetPairs(ctx context.Context) (KrakenAssetPairs, error) {
+	apiURL := KrakenBaseURL + "/0/public/AssetPairs"
+	resp, err := getWithRetry(ctx, ExchangeKraken, apiURL)
+	if err != nil {
+		return KrakenAssetPairs{}, fmt.Errorf("error fetching Kraken asset pairs: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return KrakenAssetPairs{}, fmt.Errorf("error reading Kraken response: %v", err)
+	}
+	if err := CheckStatus(ExchangeKraken, resp, body); err != nil {
+		return KrakenAssetPairs{}, err
+	}
+
+	var assetPairs KrakenAssetPairs
+	err = json.Unmarshal(body, &assetPairs)
+	if err != nil {
+		return KrakenAssetPairs{}, fmt.Errorf("error unmarshalling Kraken asset pairs: %v", err)
+	}
+	if len(assetPairs.Error) > 0 {
+		return KrakenAssetPairs{}, fmt.Errorf("Kraken asset pairs error: %s", strings.Join(assetPairs.Error, ", "))
+	}
+
+	return assetPairs, nil
+}
+
+// getKrakenTickers fetches the tickers of every pair, or only of pair if it
+// is set.
+func getKrakenTickers(ctx context.Context, pair string) (KrakenTickers, error) {
+	apiURL := KrakenBaseURL + "/0/public/Ticker"
+	if pair != "" {
+		apiURL += "?pair=" + url.QueryEscape(pair)
+	}
+	resp, err := getWithRetry(ctx, ExchangeKraken, apiURL)
+	if err != nil {
+		return KrakenTickers{}, fmt.Errorf("error fetching Kraken tickers: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return KrakenTickers{}, fmt.Errorf("error reading Kraken response: %v", err)
+	}
+	if err := CheckStatus(ExchangeKraken, resp, body); err != nil {
+		return KrakenTickers{}, err
+	}
+
+	var tickers KrakenTickers
+	err = json.Unmarshal(body, &tickers)
+	if err != nil {
+		return KrakenTickers{}, fmt.Errorf("error unmarshalling Kraken tickers: %v", err)
+	}
+	if len(tickers.Error) > 0 {
+		return KrakenTickers{}, fmt.Errorf("Kraken tickers error: %s", strings.Join(tickers.Error, ", "))
+	}
+
+	return tickers, nil
+}
+
+func getKrakenOrderBook(ctx context.Context, pair string) (OrderBook, error) {
+	apiURL := KrakenBaseURL + "/0/public/Depth?count=100&pair=" + url.QueryEscape(pair)
+	resp, err := getWithRetry(ctx, ExchangeKraken, apiURL)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error fetching Kraken order book for %s: %v", pair, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error reading Kraken response: %v", err)
+	}
+	if err := CheckStatus(ExchangeKraken, resp, body); err != nil {
+		return OrderBook{}, err
+	}
+
+	var book KrakenOrderBook
+	err = json.Unmarshal(body, &book)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error unmarshalling Kraken order book: %v", err)
+	}
+	if len(book.Error) > 0 {
+		return OrderBook{}, fmt.Errorf("Kraken order book error: %s", strings.Join(book.Error, ", "))
+	}
+
+	for _, levels := range book.Result {
+		return OrderBook{Bids: parseMixedOrderBookLevels(levels.Bids), Asks: parseMixedOrderBookLevels(levels.Asks)}, nil
+	}
+	return OrderBook{}, fmt.Errorf("Kraken returned no order book for %s", pair)
+}
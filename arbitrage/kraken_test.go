@@ -0,0 +1,62 @@
+package arbitrage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNormalizeKrakenAsset(t *testing.T) {
+	tests := map[string]string{
+		"XXBT": "BTC",
+		"XBT":  "BTC",
+		"XXDG": "DOGE",
+		"XDG":  "DOGE",
+		"ZUSD": "USD",
+		"ZEUR": "EUR",
+		"XETH": "ETH",
+		"USDT": "USDT",
+		"SOL":  "SOL",
+		// Not a legacy code, so the leading X must stay.
+		"XTZ": "XTZ",
+	}
+	for code, want := range tests {
+		if got := normalizeKrakenAsset(code); got != want {
+			t.Errorf("normalizeKrakenAsset(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestGetKrakenPairs(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/0/public/AssetPairs": `{"error":[],"result":{
+			"XXBTZUSD":{"altname":"XBTUSD","wsname":"XBT/USD","base":"XXBT","quote":"ZUSD","status":"online"},
+			"XBTUSDT":{"altname":"XBTUSDT","wsname":"XBT/USDT","base":"XXBT","quote":"USDT","status":"online"},
+			"XETHZEUR":{"altname":"ETHEUR","base":"XETH","quote":"ZEUR","status":"online"},
+			"HALTUSD":{"altname":"HALTUSD","wsname":"HALT/USD","base":"HALT","quote":"ZUSD","status":"cancel_only"}
+		}}`,
+		"/0/public/Ticker": `{"error":[],"result":{
+			"XXBTZUSD":{"a":["60000.1","1","1.000"],"b":["60000.0","2","2.000"],"v":["10","100"],"p":["59000","60000"]},
+			"XBTUSDT":{"a":["60005.0","1","1.000"],"b":["60004.9","1","1.000"],"v":["1","2"],"p":["1","1"]},
+			"XETHZEUR":{"a":["2800.5","1","1.000"],"b":["2800.4","1","1.000"],"v":["1","2"],"p":["1","1"]},
+			"HALTUSD":{"a":["1","1","1.000"],"b":["1","1","1.000"],"v":["1","2"],"p":["1","1"]}
+		}}`,
+	})
+	defer func(old string) { KrakenBaseURL = old }(KrakenBaseURL)
+	KrakenBaseURL = server.URL
+
+	pairs, err := getKrakenPairs(context.Background())
+	if err != nil {
+		t.Fatalf("getKrakenPairs: %v", err)
+	}
+	if len(pairs) != 3 {
+		t.Fatalf("got %d pairs, want 3: %v", len(pairs), pairs)
+	}
+	// USD and USDT markets must stay separate.
+	assertPrice(t, pairs, "BTC/USD", "XXBTZUSD", "60000.0", "60000.1")
+	assertPrice(t, pairs, "BTC/USDT", "XBTUSDT", "60004.9", "60005.0")
+	// Without a wsname the prefixed base/quote codes are normalized.
+	assertPrice(t, pairs, "ETH/EUR", "XETHZEUR", "2800.4", "2800.5")
+	if got := pairs["BTC/USD"].QuoteVolume; !got.Equal(mustDecimal(t, "6000000")) {
+		t.Errorf("BTC/USD quote volume = %s, want 6000000", got)
+	}
+}
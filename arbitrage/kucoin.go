@@ -0,0 +1,249 @@
+package arbitrage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/url"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// KuCoinExchange fetches spot prices from the KuCoin public API.
+type KuCoinExchange struct{}
+
+func (KuCoinExchange) Name() string {
+	return ExchangeKuCoin
+}
+
+func (KuCoinExchange) Pairs(ctx context.Context) (map[string]ExchangePrice, error) {
+	return getKuCoinPairs(ctx)
+}
+
+func (KuCoinExchange) OrderBook(ctx context.Context, symbol string) (OrderBook, error) {
+	return getKuCoinOrderBook(ctx, symbol)
+}
+
+func (KuCoinExchange) TopOfBook(ctx context.Context, symbol string) (decimal.Decimal, decimal.Decimal, error) {
+	level1, err := getKuCoinLevel1(ctx, symbol)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	if level1.Data == nil {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("KuCoin returned no ticker for %s", symbol)
+	}
+	return parseTopOfBook(ExchangeKuCoin, symbol, level1.Data.BestBid, level1.Data.BestAsk)
+}
+
+// KuCoin wraps every response in a code/msg envelope; code "200000" means
+// success.
+type KuCoinTickers struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+	Data struct {
+		Ticker Records[struct {
+			Symbol string `json:"symbol"`
+			// Buy and Sell are the best bid and ask. Either is null when
+			// that side of the book is empty.
+			Buy  *string `json:"buy"`
+			Sell *string `json:"sell"`
+			// VolValue is the 24h volume in quote currency.
+			VolValue string `json:"volValue"`
+		}] `json:"ticker"`
+	} `json:"data"`
+}
+
+// KuCoinSymbols lists the spot markets with their base and quote currencies
+// and price increments.
+type KuCoinSymbols struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+	Data Records[struct {
+		Symbol         string `json:"symbol"`
+		BaseCurrency   string `json:"baseCurrency"`
+		QuoteCurrency  string `json:"quoteCurrency"`
+		PriceIncrement string `json:"priceIncrement"`
+	}] `json:"data"`
+}
+
+// KuCoinLevel1 is the best bid and ask of one market. Data is null for an
+// unknown symbol.
+type KuCoinLevel1 struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+	Data *struct {
+		BestBid string `json:"bestBid"`
+		BestAsk string `json:"bestAsk"`
+	} `json:"data"`
+}
+
+type KuCoinOrderBook struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+	Data struct {
+		Bids [][]string `json:"bids"`
+		Asks [][]string `json:"asks"`
+	} `json:"data"`
+}
+
+func getKuCoinPairs(ctx context.Context) (map[string]ExchangePrice, error) {
+	var (
+		wg                     sync.WaitGroup
+		tickers                KuCoinTickers
+		symbols                map[string]marketInfo
+		tickersErr, symbolsErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		tickers, tickersErr = getKuCoinTickers(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		symbols, symbolsErr = getKuCoinSymbols(ctx)
+	}()
+	wg.Wait()
+	if tickersErr != nil {
+		return nil, tickersErr
+	}
+	if symbolsErr != nil {
+		// The prices are still usable, only not rounded to the tick.
+		slog.Warn("Comparing without instrument metadata: no tick sizes, and base and quote taken from the symbols",
+			"exchange", ExchangeKuCoin, "err", symbolsErr)
+	}
+
+	parser := newTickerParser(ExchangeKuCoin)
+	pairs := make(map[string]ExchangePrice)
+	for _, ticker := range tickers.Data.Ticker {
+		market, known := lookupMarket(symbols, symbolsErr == nil, ticker.Symbol, "-")
+		if !known || ticker.Buy == nil || ticker.Sell == nil {
+			continue
+		}
+		bidPrice, askPrice, ok := parser.prices(ticker.Symbol, *ticker.Buy, *ticker.Sell)
+		if !ok {
+			continue
+		}
+		volume, _ := decimal.NewFromString(ticker.VolValue)
+		base, quote := CanonicalAsset(market.base), CanonicalAsset(market.quote)
+		pairs[CanonicalSymbol(base, quote)] = ExchangePrice{
+			Symbol:      ticker.Symbol,
+			Base:        base,
+			Quote:       quote,
+			BidPrice:    bidPrice,
+			AskPrice:    askPrice,
+			QuoteVolume: volume,
+			TickSize:    market.tickSize,
+		}
+	}
+
+	parser.report()
+	return pairs, nil
+}
+
+// getKuCoinSymbols returns the base and quote currencies and price increment
+// of every spot market, keyed by symbol.
+func getKuCoinSymbols(ctx context.Context) (map[string]marketInfo, error) {
+	var symbols KuCoinSymbols
+	if err := getJSON(ctx, ExchangeKuCoin, KuCoinBaseURL+"/api/v2/symbols", "KuCoin symbols", &symbols); err != nil {
+		return nil, err
+	}
+	if symbols.Code != "200000" {
+		return nil, fmt.Errorf("KuCoin symbols error %s: %s", symbols.Code, symbols.Msg)
+	}
+	markets := make(map[string]marketInfo, len(symbols.Data))
+	for _, symbol := range symbols.Data {
+		if symbol.BaseCurrency == "" || symbol.QuoteCurrency == "" {
+			continue
+		}
+		tickSize, _ := decimal.NewFromString(symbol.PriceIncrement)
+		markets[symbol.Symbol] = marketInfo{base: symbol.BaseCurrency, quote: symbol.QuoteCurrency, tickSize: tickSize}
+	}
+	return markets, nil
+}
+
+func getKuCoinTickers(ctx context.Context) (KuCoinTickers, error) {
+	apiURL := KuCoinBaseURL + "/api/v1/market/allTickers"
+	resp, err := getWithRetry(ctx, ExchangeKuCoin, apiURL)
+	if err != nil {
+		return KuCoinTickers{}, fmt.Errorf("error fetching KuCoin tickers: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return KuCoinTickers{}, fmt.Errorf("error reading KuCoin response: %v", err)
+	}
+	if err := CheckStatus(ExchangeKuCoin, resp, body); err != nil {
+		return KuCoinTickers{}, err
+	}
+
+	var tickers KuCoinTickers
+	err = json.Unmarshal(body, &tickers)
+	if err != nil {
+		return KuCoinTickers{}, fmt.Errorf("error unmarshalling KuCoin tickers: %v", err)
+	}
+	if tickers.Code != "200000" {
+		return KuCoinTickers{}, fmt.Errorf("KuCoin tickers error %s: %s", tickers.Code, tickers.Msg)
+	}
+
+	return tickers, nil
+}
+
+func getKuCoinLevel1(ctx context.Context, symbol string) (KuCoinLevel1, error) {
+	apiURL := KuCoinBaseURL + "/api/v1/market/orderbook/level1?symbol=" + url.QueryEscape(symbol)
+	resp, err := getWithRetry(ctx, ExchangeKuCoin, apiURL)
+	if err != nil {
+		return KuCoinLevel1{}, fmt.Errorf("error fetching KuCoin ticker: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return KuCoinLevel1{}, fmt.Errorf("error reading KuCoin response: %v", err)
+	}
+	if err := CheckStatus(ExchangeKuCoin, resp, body); err != nil {
+		return KuCoinLevel1{}, err
+	}
+
+	var level1 KuCoinLevel1
+	err = json.Unmarshal(body, &level1)
+	if err != nil {
+		return KuCoinLevel1{}, fmt.Errorf("error unmarshalling KuCoin ticker: %v", err)
+	}
+	if level1.Code != "200000" {
+		return KuCoinLevel1{}, fmt.Errorf("KuCoin ticker error %s: %s", level1.Code, level1.Msg)
+	}
+
+	return level1, nil
+}
+
+func getKuCoinOrderBook(ctx context.Context, symbol string) (OrderBook, error) {
+	apiURL := KuCoinBaseURL + "/api/v1/market/orderbook/level2_100?symbol=" + url.QueryEscape(symbol)
+	resp, err := getWithRetry(ctx, ExchangeKuCoin, apiURL)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error fetching KuCoin order book for %s: %v", symbol, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error reading KuCoin response: %v", err)
+	}
+	if err := CheckStatus(ExchangeKuCoin, resp, body); err != nil {
+		return OrderBook{}, err
+	}
+
+	var book KuCoinOrderBook
+	err = json.Unmarshal(body, &book)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error unmarshalling KuCoin order book: %v", err)
+	}
+	if book.Code != "200000" {
+		return OrderBook{}, fmt.Errorf("KuCoin order book error %s: %s", book.Code, book.Msg)
+	}
+
+	return OrderBook{Bids: parseOrderBookLevels(book.Data.Bids), Asks: parseOrderBookLevels(book.Data.Asks)}, nil
+}
@@ -0,0 +1,57 @@
+package arbitrage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetKuCoinPairs(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/api/v1/market/allTickers": `{"code":"200000","data":{"time":1700000000000,"ticker":[
+			{"symbol":"BTC-USDT","buy":"60000.1","sell":"60000.2","volValue":"1000000"},
+			{"symbol":"ETH-BTC","buy":"0.05","sell":"0.0501","volValue":"12"},
+			{"symbol":"DEAD-USDT","buy":null,"sell":null,"volValue":"0"},
+			{"symbol":"HALF-USDT","buy":"1.5","sell":"","volValue":"0"}
+		]}}`,
+		"/api/v2/symbols": `{"code":"200000","data":[
+			{"symbol":"BTC-USDT","baseCurrency":"BTC","quoteCurrency":"USDT","priceIncrement":"0.1"},
+			{"symbol":"ETH-BTC","baseCurrency":"ETH","quoteCurrency":"BTC","priceIncrement":"0.000001"},
+			{"symbol":"DEAD-USDT","baseCurrency":"DEAD","quoteCurrency":"USDT","priceIncrement":"0.1"},
+			{"symbol":"HALF-USDT","baseCurrency":"HALF","quoteCurrency":"USDT","priceIncrement":"0.1"}
+		]}`,
+	})
+	defer func(old string) { KuCoinBaseURL = old }(KuCoinBaseURL)
+	KuCoinBaseURL = server.URL
+
+	pairs, err := getKuCoinPairs(context.Background())
+	if err != nil {
+		t.Fatalf("getKuCoinPairs: %v", err)
+	}
+	if len(pairs) != 2 {
+		t.Fatalf("got %d pairs, want 2: %v", len(pairs), pairs)
+	}
+	assertPrice(t, pairs, "BTC/USDT", "BTC-USDT", "60000.1", "60000.2")
+	assertPrice(t, pairs, "ETH/BTC", "ETH-BTC", "0.05", "0.0501")
+	if got := pairs["BTC/USDT"].TickSize; !got.Equal(mustDecimal(t, "0.1")) {
+		t.Errorf("BTC/USDT tick size = %s, want 0.1", got)
+	}
+}
+
+func TestGetKuCoinPairsInvertedSymbol(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/api/v1/market/allTickers": `{"code":"200000","data":{"time":1700000000000,"ticker":[
+			{"symbol":"USDT-BTC","buy":"60000.1","sell":"60000.2","volValue":"1000000"}
+		]}}`,
+		"/api/v2/symbols": `{"code":"200000","data":[
+			{"symbol":"USDT-BTC","baseCurrency":"BTC","quoteCurrency":"USDT","priceIncrement":"0.1"}
+		]}`,
+	})
+	defer func(old string) { KuCoinBaseURL = old }(KuCoinBaseURL)
+	KuCoinBaseURL = server.URL
+
+	pairs, err := getKuCoinPairs(context.Background())
+	if err != nil {
+		t.Fatalf("getKuCoinPairs: %v", err)
+	}
+	assertPrice(t, pairs, "BTC/USDT", "USDT-BTC", "60000.1", "60000.2")
+}
@@ -0,0 +1,29 @@
+package arbitrage
+
+import "regexp"
+
+// IncludeLeveraged keeps leveraged tokens, such as Binance's BTCUP and
+// Bybit's BTC3L, in the pairs. They track a multiple of the underlying's
+// daily move, so their prices are never comparable with the underlying's
+// and comparing them only produces nonsensical spreads.
+var IncludeLeveraged = false
+
+// leveragedSuffixes match the base asset of each exchange's leveraged
+// tokens, capturing the underlying asset.
+var leveragedSuffixes = map[string]*regexp.Regexp{
+	ExchangeBinance: regexp.MustCompile(`^(.+)(UP|DOWN|BULL|BEAR)$`),
+	ExchangeBybit:   regexp.MustCompile(`^(.+)[2-5][LS]$`),
+}
+
+// isLeveragedToken reports whether base is one of exchange's leveraged
+// tokens: the name of an asset the exchange also lists, one of bases,
+// followed by the exchange's leveraged suffix. Requiring the underlying
+// keeps assets like JUP, whose names merely end the same way.
+func isLeveragedToken(exchange, base string, bases map[string]bool) bool {
+	pattern, ok := leveragedSuffixes[exchange]
+	if !ok {
+		return false
+	}
+	match := pattern.FindStringSubmatch(base)
+	return match != nil && bases[match[1]]
+}
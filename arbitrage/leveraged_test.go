@@ -0,0 +1,26 @@
+package arbitrage
+
+import "testing"
+
+func TestIsLeveragedToken(t *testing.T) {
+	bases := map[string]bool{"BTC": true, "ETH": true, "J": false}
+	tests := []struct {
+		exchange, base string
+		want           bool
+	}{
+		{ExchangeBinance, "BTCUP", true},
+		{ExchangeBinance, "ETHDOWN", true},
+		{ExchangeBinance, "BTC", false},
+		// JUP ends in UP, but J is not listed.
+		{ExchangeBinance, "JUP", false},
+		{ExchangeBybit, "BTC3L", true},
+		{ExchangeBybit, "ETH2S", true},
+		{ExchangeBybit, "BTCUP", false},
+		{ExchangeKraken, "BTC3L", false},
+	}
+	for _, tt := range tests {
+		if got := isLeveragedToken(tt.exchange, tt.base, bases); got != tt.want {
+			t.Errorf("isLeveragedToken(%s, %s) = %v, want %v", tt.exchange, tt.base, got, tt.want)
+		}
+	}
+}
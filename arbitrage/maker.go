@@ -0,0 +1,35 @@
+package arbitrage
+
+import "github.com/shopspring/decimal"
+
+// Legs executed as maker (limit) orders, selected with -maker-leg.
+const (
+	MakerLegNone = "none"
+	MakerLegBuy  = "buy"
+	MakerLegSell = "sell"
+	MakerLegBoth = "both"
+)
+
+var MakerLeg = MakerLegNone
+
+// legFees returns the fee rates charged on the buy and sell legs: the maker
+// fee for each leg -maker-leg rests on the book, the taker fee otherwise.
+func legFees(buyFees, sellFees ExchangeFees) (buyFee, sellFee decimal.Decimal) {
+	buyFee, sellFee = buyFees.Taker, sellFees.Taker
+	if MakerLeg == MakerLegBuy || MakerLeg == MakerLegBoth {
+		buyFee = buyFees.Maker
+	}
+	if MakerLeg == MakerLegSell || MakerLeg == MakerLegBoth {
+		sellFee = sellFees.Maker
+	}
+	return buyFee, sellFee
+}
+
+// reportedMakerLeg is the MakerLeg recorded on opportunities: empty when
+// both legs are taker orders, so the field is left out of JSON output.
+func reportedMakerLeg() string {
+	if MakerLeg == MakerLegNone {
+		return ""
+	}
+	return MakerLeg
+}
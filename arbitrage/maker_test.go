@@ -0,0 +1,48 @@
+package arbitrage
+
+import (
+	"testing"
+)
+
+func withMakerLeg(t *testing.T, leg string) {
+	t.Helper()
+	old := MakerLeg
+	t.Cleanup(func() { MakerLeg = old })
+	MakerLeg = leg
+}
+
+func TestComputeOpportunityMakerLeg(t *testing.T) {
+	a := ExchangePrice{Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, "99"), AskPrice: mustDecimal(t, "100")}
+	b := ExchangePrice{Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, "101"), AskPrice: mustDecimal(t, "102")}
+	fees := map[string]ExchangeFees{
+		"A": {Taker: mustDecimal(t, "0.01"), Maker: mustDecimal(t, "0.001")},
+		"B": {Taker: mustDecimal(t, "0.01"), Maker: mustDecimal(t, "0.002")},
+	}
+	// Accept losses so the prices of every mode can be checked.
+	minProfit, maxProfit := mustDecimal(t, "-0.5"), mustDecimal(t, "0.5")
+
+	tests := []struct {
+		leg        string
+		buy, sell  string
+		reportedAs string
+	}{
+		{MakerLegNone, "101", "99.99", ""},
+		{MakerLegBuy, "100.1", "99.99", "buy"},
+		{MakerLegSell, "101", "100.798", "sell"},
+		{MakerLegBoth, "100.1", "100.798", "both"},
+	}
+	for _, tt := range tests {
+		withMakerLeg(t, tt.leg)
+		got, ok, _ := ComputeOpportunity("BTC/USDT", "A", "B", a, b, fees, minProfit, maxProfit)
+		if !ok {
+			t.Errorf("%s: no opportunity", tt.leg)
+			continue
+		}
+		if !got.BuyPrice.Equal(mustDecimal(t, tt.buy)) || !got.SellPrice.Equal(mustDecimal(t, tt.sell)) {
+			t.Errorf("%s: buy %s, sell %s; want %s and %s", tt.leg, got.BuyPrice, got.SellPrice, tt.buy, tt.sell)
+		}
+		if got.MakerLeg != tt.reportedAs {
+			t.Errorf("%s: MakerLeg = %q, want %q", tt.leg, got.MakerLeg, tt.reportedAs)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package arbitrage
+
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// NearMiss is a symbol whose best spread fell just short of the minimum
+// profit, with the fees that would have made it an opportunity. Percentages
+// are like ArbitrageOpportunity's: 1 is 1%.
+type NearMiss struct {
+	Symbol           string
+	BuyExchange      string
+	SellExchange     string
+	ProfitPercentage decimal.Decimal
+	// RoundTripFee is the share of the capital the fees of both legs take,
+	// and BreakEvenFee the share they could take for the spread to just
+	// reach the minimum profit.
+	RoundTripFee decimal.Decimal
+	BreakEvenFee decimal.Decimal
+}
+
+// FindNearMisses returns, for every symbol listed on at least two exchanges,
+// its best spread if that is below minProfit by no more than band, best
+// first. minProfit and band are fractions.
+//
+// The profit of a trade is r*(1-f)-1, where r is the bid over the ask and
+// 1-f = (1-sellFee)/(1+buyFee) what the fees leave of the capital, so the
+// spread reaches minProfit once the round-trip fee f is at most
+// 1-(1+minProfit)/r.
+func FindNearMisses(pairs map[string]map[string]ExchangePrice, fees map[string]ExchangeFees, minProfit, band decimal.Decimal) []NearMiss {
+	names := make([]string, 0, len(pairs))
+	for name := range pairs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, name := range names {
+		for symbol := range pairs[name] {
+			if !seen[symbol] {
+				seen[symbol] = true
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+	sort.Strings(symbols)
+
+	one := decimal.NewFromInt(1)
+	floor := minProfit.Sub(band)
+	var misses []NearMiss
+	for _, symbol := range symbols {
+		var listed []string
+		for _, name := range names {
+			price, exists := pairs[name][symbol]
+			if exists && price.AskPrice.IsPositive() && price.BidPrice.IsPositive() {
+				listed = append(listed, name)
+			}
+		}
+		if len(listed) < 2 {
+			continue
+		}
+		buyExchange, sellExchange, profit, ok := BestSpread(symbol, listed, pairs, fees)
+		if !ok || !profit.LessThan(minProfit) || profit.LessThan(floor) {
+			continue
+		}
+
+		buyFee, sellFee := legFees(fees[buyExchange], fees[sellExchange])
+		kept := one.Sub(sellFee).DivRound(one.Add(buyFee), DivisionPrecision)
+		// The prices before fees, rounded to the tick as BestSpread does
+		// and with any flat slippage.
+		buyPrice, sellPrice := applySlippage(tickRoundedPrices(pairs[buyExchange][symbol], pairs[sellExchange][symbol]))
+		ratio := sellPrice.DivRound(buyPrice, DivisionPrecision)
+		misses = append(misses, NearMiss{
+			Symbol:           symbol,
+			BuyExchange:      buyExchange,
+			SellExchange:     sellExchange,
+			ProfitPercentage: profit.Mul(hundred),
+			RoundTripFee:     one.Sub(kept).Mul(hundred),
+			BreakEvenFee:     one.Sub(one.Add(minProfit).DivRound(ratio, DivisionPrecision)).Mul(hundred),
+		})
+	}
+
+	sort.SliceStable(misses, func(i, j int) bool {
+		return misses[i].ProfitPercentage.GreaterThan(misses[j].ProfitPercentage)
+	})
+	return misses
+}
@@ -0,0 +1,46 @@
+package arbitrage
+
+import "testing"
+
+func TestFindNearMisses(t *testing.T) {
+	pairs := map[string]map[string]ExchangePrice{
+		"A": {
+			"BTC/USDT": {AskPrice: mustDecimal(t, "100"), BidPrice: mustDecimal(t, "99.9")},
+			"ETH/USDT": {AskPrice: mustDecimal(t, "100"), BidPrice: mustDecimal(t, "99.9")},
+			"SOL/USDT": {AskPrice: mustDecimal(t, "100"), BidPrice: mustDecimal(t, "99.9")},
+			"XRP/USDT": {AskPrice: mustDecimal(t, "100"), BidPrice: mustDecimal(t, "99.9")},
+		},
+		"B": {
+			// 0.9% below the 1% threshold after fees: a near miss.
+			"BTC/USDT": {AskPrice: mustDecimal(t, "101.2"), BidPrice: mustDecimal(t, "101.1")},
+			// Already an opportunity.
+			"ETH/USDT": {AskPrice: mustDecimal(t, "103"), BidPrice: mustDecimal(t, "102.9")},
+			// Too far below the threshold.
+			"SOL/USDT": {AskPrice: mustDecimal(t, "100"), BidPrice: mustDecimal(t, "99.9")},
+		},
+	}
+	fees := map[string]ExchangeFees{
+		"A": {Taker: mustDecimal(t, "0.001"), Maker: mustDecimal(t, "0.001")},
+		"B": {Taker: mustDecimal(t, "0.001"), Maker: mustDecimal(t, "0.001")},
+	}
+
+	misses := FindNearMisses(pairs, fees, mustDecimal(t, "0.01"), mustDecimal(t, "0.005"))
+	if len(misses) != 1 {
+		t.Fatalf("got %d near misses, want only BTC/USDT: %+v", len(misses), misses)
+	}
+	miss := misses[0]
+	if miss.Symbol != "BTC/USDT" || miss.BuyExchange != "A" || miss.SellExchange != "B" {
+		t.Errorf("near miss %s buy %s sell %s, want BTC/USDT buy A sell B", miss.Symbol, miss.BuyExchange, miss.SellExchange)
+	}
+	for _, check := range []struct{ name, got, want string }{
+		{"profit", miss.ProfitPercentage.StringFixed(4), "0.8980"},
+		// (1-0.001)/(1+0.001) of the capital is kept.
+		{"round-trip fee", miss.RoundTripFee.StringFixed(4), "0.1998"},
+		// 101.1/100 * (1-f) = 1.01.
+		{"break-even fee", miss.BreakEvenFee.StringFixed(4), "0.0989"},
+	} {
+		if check.got != check.want {
+			t.Errorf("%s = %s%%, want %s%%", check.name, check.got, check.want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package arbitrage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// OKXExchange fetches spot prices from the OKX v5 API.
+type OKXExchange struct{}
+
+func (OKXExchange) Name() string {
+	return ExchangeOKX
+}
+
+func (OKXExchange) Pairs(ctx context.Context) (map[string]ExchangePrice, error) {
+	return getOKXPairs(ctx)
+}
+
+func (OKXExchange) OrderBook(ctx context.Context, instID string) (OrderBook, error) {
+	return getOKXOrderBook(ctx, instID)
+}
+
+func (OKXExchange) TopOfBook(ctx context.Context, instID string) (decimal.Decimal, decimal.Decimal, error) {
+	tickers, err := getOKXTickers(ctx, instID)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	for _, ticker := range tickers.Data {
+		if ticker.InstID == instID {
+			return parseTopOfBook(ExchangeOKX, instID, ticker.BidPx, ticker.AskPx)
+		}
+	}
+	return decimal.Zero, decimal.Zero, fmt.Errorf("OKX returned no ticker for %s", instID)
+}
+
+// OKX wraps every response in a code/msg envelope; code "0" means success.
+type OKXTickers struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+	Data Records[struct {
+		InstID string `json:"instId"`
+		BidPx  string `json:"bidPx"`
+		AskPx  string `json:"askPx"`
+		// VolCcy24h is the 24h volume in quote currency for spot markets.
+		VolCcy24h string `json:"volCcy24h"`
+		// Ts is when the ticker was last updated, in milliseconds.
+		Ts string `json:"ts"`
+	}] `json:"data"`
+}
+
+// OKXInstruments lists the spot markets with their base and quote
+// currencies and price increments.
+type OKXInstruments struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+	Data Records[struct {
+		InstID   string `json:"instId"`
+		BaseCcy  string `json:"baseCcy"`
+		QuoteCcy string `json:"quoteCcy"`
+		TickSz   string `json:"tickSz"`
+	}] `json:"data"`
+}
+
+// OKXOrderBook levels are [price, size, deprecated, order count].
+type OKXOrderBook struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+	Data []struct {
+		Bids [][]string `json:"bids"`
+		Asks [][]string `json:"asks"`
+	} `json:"data"`
+}
+
+func getOKXPairs(ctx context.Context) (map[string]ExchangePrice, error) {
+	var (
+		wg                         sync.WaitGroup
+		tickers                    OKXTickers
+		instruments                map[string]marketInfo
+		tickersErr, instrumentsErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		tickers, tickersErr = getOKXTickers(ctx, "")
+	}()
+	go func() {
+		defer wg.Done()
+		instruments, instrumentsErr = getOKXInstruments(ctx)
+	}()
+	wg.Wait()
+	if tickersErr != nil {
+		return nil, tickersErr
+	}
+	if instrumentsErr != nil {
+		// The prices are still usable, only not rounded to the tick.
+		slog.Warn("Comparing without instrument metadata: no tick sizes, and base and quote taken from the instrument IDs",
+			"exchange", ExchangeOKX, "err", instrumentsErr)
+	}
+
+	parser := newTickerParser(ExchangeOKX)
+	pairs := make(map[string]ExchangePrice)
+	for _, ticker := range tickers.Data {
+		market, known := lookupMarket(instruments, instrumentsErr == nil, ticker.InstID, "-")
+		if !known {
+			continue
+		}
+		bidPrice, askPrice, ok := parser.prices(ticker.InstID, ticker.BidPx, ticker.AskPx)
+		if !ok {
+			continue
+		}
+		volume, _ := decimal.NewFromString(ticker.VolCcy24h)
+		var updatedAt time.Time
+		if ts, err := strconv.ParseInt(ticker.Ts, 10, 64); err == nil && ts > 0 {
+			updatedAt = time.UnixMilli(ts)
+		}
+		base, quote := CanonicalAsset(market.base), CanonicalAsset(market.quote)
+		pairs[CanonicalSymbol(base, quote)] = ExchangePrice{
+			Symbol:      ticker.InstID,
+			Base:        base,
+			Quote:       quote,
+			BidPrice:    bidPrice,
+			AskPrice:    askPrice,
+			QuoteVolume: volume,
+			TickSize:    market.tickSize,
+			UpdatedAt:   updatedAt,
+		}
+	}
+
+	parser.report()
+	return pairs, nil
+}
+
+// getOKXInstruments returns the base and quote currencies and price
+// increment of every spot market, keyed by instrument ID.
+func getOKXInstruments(ctx context.Context) (map[string]marketInfo, error) {
+	var instruments OKXInstruments
+	if err := getJSON(ctx, ExchangeOKX, OKXBaseURL+"/api/v5/public/instruments?instType=SPOT", "OKX instruments", &instruments); err != nil {
+		return nil, err
+	}
+	if instruments.Code != "0" {
+		return nil, fmt.Errorf("OKX instruments error %s: %s", instruments.Code, instruments.Msg)
+	}
+	markets := make(map[string]marketInfo, len(instruments.Data))
+	for _, instrument := range instruments.Data {
+		if instrument.BaseCcy == "" || instrument.QuoteCcy == "" {
+			continue
+		}
+		tickSize, _ := decimal.NewFromString(instrument.TickSz)
+		markets[instrument.InstID] = marketInfo{base: instrument.BaseCcy, quote: instrument.QuoteCcy, tickSize: tickSize}
+	}
+	return markets, nil
+}
+
+// getOKXTickers fetches the tickers of every spot market, or only of instID
+// if it is set.
+func getOKXTickers(ctx context.Context, instID string) (OKXTickers, error) {
+	apiURL := OKXBaseURL + "/api/v5/market/tickers?instType=SPOT"
+	if instID != "" {
+		apiURL = OKXBaseURL + "/api/v5/market/ticker?instId=" + url.QueryEscape(instID)
+	}
+	resp, err := getWithRetry(ctx, ExchangeOKX, apiURL)
+	if err != nil {
+		return OKXTickers{}, fmt.Errorf("error fetching OKX tickers: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return OKXTickers{}, fmt.Errorf("error reading OKX response: %v", err)
+	}
+	if err := CheckStatus(ExchangeOKX, resp, body); err != nil {
+		return OKXTickers{}, err
+	}
+
+	var tickers OKXTickers
+	err = json.Unmarshal(body, &tickers)
+	if err != nil {
+		return OKXTickers{}, fmt.Errorf("error unmarshalling OKX tickers: %v", err)
+	}
+	if tickers.Code != "0" {
+		return OKXTickers{}, fmt.Errorf("OKX tickers error %s: %s", tickers.Code, tickers.Msg)
+	}
+
+	return tickers, nil
+}
+
+func getOKXOrderBook(ctx context.Context, instID string) (OrderBook, error) {
+	apiURL := OKXBaseURL + "/api/v5/market/books?sz=100&instId=" + url.QueryEscape(instID)
+	resp, err := getWithRetry(ctx, ExchangeOKX, apiURL)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error fetching OKX order book for %s: %v", instID, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error reading OKX response: %v", err)
+	}
+	if err := CheckStatus(ExchangeOKX, resp, body); err != nil {
+		return OrderBook{}, err
+	}
+
+	var book OKXOrderBook
+	err = json.Unmarshal(body, &book)
+	if err != nil {
+		return OrderBook{}, fmt.Errorf("error unmarshalling OKX order book: %v", err)
+	}
+	if book.Code != "0" {
+		return OrderBook{}, fmt.Errorf("OKX order book error %s: %s", book.Code, book.Msg)
+	}
+	if len(book.Data) == 0 {
+		return OrderBook{}, fmt.Errorf("OKX returned no order book for %s", instID)
+	}
+
+	return OrderBook{Bids: parseOrderBookLevels(book.Data[0].Bids), Asks: parseOrderBookLevels(book.Data[0].Asks)}, nil
+}
@@ -0,0 +1,102 @@
+package arbitrage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetOKXPairs(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/api/v5/market/tickers": `{"code":"0","msg":"","data":[
+			{"instId":"BTC-USDT","bidPx":"60000.1","askPx":"60000.2","volCcy24h":"1000000","ts":"1700000000123"},
+			{"instId":"ETH-BTC","bidPx":"0.05","askPx":"0.0501","volCcy24h":"12"},
+			{"instId":"DEAD-USDT","bidPx":"","askPx":"","volCcy24h":"0"}
+		]}`,
+		"/api/v5/public/instruments": `{"code":"0","msg":"","data":[
+			{"instId":"BTC-USDT","baseCcy":"BTC","quoteCcy":"USDT","tickSz":"0.1"},
+			{"instId":"ETH-BTC","baseCcy":"ETH","quoteCcy":"BTC","tickSz":"0.00001"},
+			{"instId":"DEAD-USDT","baseCcy":"DEAD","quoteCcy":"USDT","tickSz":"0.1"}
+		]}`,
+	})
+	defer func(old string) { OKXBaseURL = old }(OKXBaseURL)
+	OKXBaseURL = server.URL
+
+	pairs, err := getOKXPairs(context.Background())
+	if err != nil {
+		t.Fatalf("getOKXPairs: %v", err)
+	}
+	if len(pairs) != 2 {
+		t.Fatalf("got %d pairs, want 2: %v", len(pairs), pairs)
+	}
+	assertPrice(t, pairs, "BTC/USDT", "BTC-USDT", "60000.1", "60000.2")
+	assertPrice(t, pairs, "ETH/BTC", "ETH-BTC", "0.05", "0.0501")
+	if got := pairs["BTC/USDT"].QuoteVolume; !got.Equal(mustDecimal(t, "1000000")) {
+		t.Errorf("BTC/USDT quote volume = %s, want 1000000", got)
+	}
+	if got := pairs["BTC/USDT"].TickSize; !got.Equal(mustDecimal(t, "0.1")) {
+		t.Errorf("BTC/USDT tick size = %s, want 0.1", got)
+	}
+	if got := pairs["BTC/USDT"].UpdatedAt; !got.Equal(time.UnixMilli(1700000000123)) {
+		t.Errorf("BTC/USDT updated at %s", got)
+	}
+	if got := pairs["ETH/BTC"].UpdatedAt; !got.IsZero() {
+		t.Errorf("ETH/BTC updated at %s, want unknown", got)
+	}
+}
+
+// TestGetOKXPairsInvertedInstrumentID stubs an exchange that writes its
+// markets QUOTE-BASE: the instrument metadata, not the order of the ID's
+// parts, decides which asset is the base.
+func TestGetOKXPairsInvertedInstrumentID(t *testing.T) {
+	instruments := `{"code":"0","msg":"","data":[{"instId":"USDT-BTC","baseCcy":"BTC","quoteCcy":"USDT","tickSz":"0.1"}]}`
+	server := newTestServer(t, map[string]string{
+		"/api/v5/market/tickers": `{"code":"0","msg":"","data":[
+			{"instId":"USDT-BTC","bidPx":"60000.1","askPx":"60000.2","volCcy24h":"1000000"},
+			{"instId":"ETH-USDT","bidPx":"3000","askPx":"3000.1","volCcy24h":"1000000"}
+		]}`,
+		"/api/v5/public/instruments": instruments,
+	})
+	defer func(old string) { OKXBaseURL = old }(OKXBaseURL)
+	OKXBaseURL = server.URL
+
+	pairs, err := getOKXPairs(context.Background())
+	if err != nil {
+		t.Fatalf("getOKXPairs: %v", err)
+	}
+	// ETH-USDT is missing from the instrument list, so its roles are
+	// unknown rather than guessed from the ID.
+	if len(pairs) != 1 {
+		t.Fatalf("got %d pairs, want only BTC/USDT: %v", len(pairs), pairs)
+	}
+	assertPrice(t, pairs, "BTC/USDT", "USDT-BTC", "60000.1", "60000.2")
+	if price := pairs["BTC/USDT"]; price.Base != "BTC" || price.Quote != "USDT" {
+		t.Errorf("base and quote = %s, %s, want BTC, USDT", price.Base, price.Quote)
+	}
+
+	// Without the instrument list the IDs can only be split by position.
+	server.Close()
+	server = newTestServer(t, map[string]string{
+		"/api/v5/market/tickers":     `{"code":"0","msg":"","data":[{"instId":"ETH-USDT","bidPx":"3000","askPx":"3000.1","volCcy24h":"1"}]}`,
+		"/api/v5/public/instruments": `{"code":"50001","msg":"Service temporarily unavailable","data":[]}`,
+	})
+	defer server.Close()
+	OKXBaseURL = server.URL
+	if pairs, err = getOKXPairs(context.Background()); err != nil {
+		t.Fatalf("getOKXPairs: %v", err)
+	}
+	assertPrice(t, pairs, "ETH/USDT", "ETH-USDT", "3000", "3000.1")
+}
+
+func TestGetOKXPairsAPIError(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/api/v5/market/tickers":     `{"code":"50011","msg":"Too Many Requests","data":[]}`,
+		"/api/v5/public/instruments": `{"code":"0","msg":"","data":[]}`,
+	})
+	defer func(old string) { OKXBaseURL = old }(OKXBaseURL)
+	OKXBaseURL = server.URL
+
+	if _, err := getOKXPairs(context.Background()); err == nil {
+		t.Fatal("expected an error for a non-zero OKX code")
+	}
+}
@@ -0,0 +1,68 @@
+package arbitrage
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// maxUnparseableRatio is the share of an exchange's tickers that may have
+// unparseable prices before a warning is logged. A few odd markets are
+// normal; many of them usually mean the API's number format changed.
+const maxUnparseableRatio = 0.05
+
+// tickerParser parses the prices of one fetch's tickers and keeps count of
+// those that didn't parse, so skipped markets are visible in the logs. It is
+// safe for concurrent use.
+type tickerParser struct {
+	exchange string
+
+	mu          sync.Mutex
+	tickers     int
+	unparseable int
+}
+
+func newTickerParser(exchange string) *tickerParser {
+	return &tickerParser{exchange: exchange}
+}
+
+// prices parses a ticker's bid and ask. ok is false when either doesn't
+// parse, which is counted and logged, or is zero or empty, which only means
+// that side of the book is empty.
+func (p *tickerParser) prices(symbol, bid, ask string) (bidPrice, askPrice decimal.Decimal, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tickers++
+	if bid == "" || ask == "" {
+		return decimal.Zero, decimal.Zero, false
+	}
+
+	bidPrice, bidErr := decimal.NewFromString(bid)
+	askPrice, askErr := decimal.NewFromString(ask)
+	if bidErr != nil || askErr != nil {
+		p.unparseable++
+		slog.Debug("Skipping ticker with unparseable prices", "exchange", p.exchange, "symbol", symbol, "bid", bid, "ask", ask)
+		return decimal.Zero, decimal.Zero, false
+	}
+	if bidPrice.IsZero() || askPrice.IsZero() {
+		return decimal.Zero, decimal.Zero, false
+	}
+	return bidPrice, askPrice, true
+}
+
+// report logs how many tickers were skipped for unparseable prices, as a
+// warning once they exceed maxUnparseableRatio of the tickers parsed.
+func (p *tickerParser) report() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.unparseable == 0 {
+		return
+	}
+	if float64(p.unparseable) > maxUnparseableRatio*float64(p.tickers) {
+		slog.Warn("Many tickers had unparseable prices; the API format may have changed", "exchange", p.exchange,
+			"skipped", p.unparseable, "tickers", p.tickers)
+		return
+	}
+	slog.Debug("Skipped tickers with unparseable prices", "exchange", p.exchange, "skipped", p.unparseable, "tickers", p.tickers)
+}
@@ -0,0 +1,53 @@
+package arbitrage
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestTickerParser(t *testing.T) {
+	var logs bytes.Buffer
+	defer func(old *slog.Logger) { slog.SetDefault(old) }(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	parser := newTickerParser("Test")
+	tests := []struct {
+		bid, ask string
+		ok       bool
+	}{
+		{"1.5", "1.6", true},
+		{"0", "1.6", false},
+		{"not-a-number", "1.6", false},
+		{"1.5", "", false},
+	}
+	for _, tt := range tests {
+		bid, ask, ok := parser.prices("X", tt.bid, tt.ask)
+		if ok != tt.ok {
+			t.Errorf("prices(%q, %q) ok = %v, want %v", tt.bid, tt.ask, ok, tt.ok)
+		}
+		if ok && (bid.String() != tt.bid || ask.String() != tt.ask) {
+			t.Errorf("prices(%q, %q) = %s, %s", tt.bid, tt.ask, bid, ask)
+		}
+	}
+	// An empty book side, quoted as zero or an empty string, is normal;
+	// only the malformed ticker counts.
+	if parser.tickers != 4 || parser.unparseable != 1 {
+		t.Errorf("counted %d unparseable of %d tickers, want 1 of 4", parser.unparseable, parser.tickers)
+	}
+
+	parser.report()
+	if !strings.Contains(logs.String(), "level=WARN") || !strings.Contains(logs.String(), "skipped=1") {
+		t.Errorf("a quarter of the tickers unparseable didn't log a warning:\n%s", logs.String())
+	}
+
+	logs.Reset()
+	for i := 0; i < 100; i++ {
+		parser.prices("X", "1", "2")
+	}
+	parser.report()
+	if strings.Contains(logs.String(), "level=WARN") || !strings.Contains(logs.String(), "Skipped tickers with unparseable prices") {
+		t.Errorf("1 unparseable of 104 tickers should only be logged at debug level:\n%s", logs.String())
+	}
+}
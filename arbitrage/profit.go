@@ -0,0 +1,34 @@
+package arbitrage
+
+import "github.com/shopspring/decimal"
+
+var (
+	hundred     = decimal.NewFromInt(100)
+	tenThousand = decimal.NewFromInt(10000)
+)
+
+// profitFraction returns the profit of buying at buy and selling at sell as
+// a fraction of buy (0.01 is 1%), rounded half away from zero to
+// DivisionPrecision decimal places. It is negative when sell is below buy.
+// ok is false when buy is zero or negative, since no profit can be stated
+// relative to it.
+func profitFraction(buy, sell decimal.Decimal) (profit decimal.Decimal, ok bool) {
+	if !buy.IsPositive() {
+		return decimal.Zero, false
+	}
+	return sell.Sub(buy).DivRound(buy, DivisionPrecision), true
+}
+
+// profitPercent is profitFraction expressed as a percentage (1 is 1%).
+func profitPercent(buy, sell decimal.Decimal) (decimal.Decimal, bool) {
+	profit, ok := profitFraction(buy, sell)
+	return profit.Mul(hundred), ok
+}
+
+// withProfit sets the percentage and basis-point profit of opportunity from
+// a profit fraction.
+func withProfit(opportunity ArbitrageOpportunity, profit decimal.Decimal) ArbitrageOpportunity {
+	opportunity.ProfitPercentage = profit.Mul(hundred)
+	opportunity.ProfitBps = profit.Mul(tenThousand)
+	return opportunity
+}
@@ -0,0 +1,39 @@
+package arbitrage
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestProfitPercent(t *testing.T) {
+	tests := []struct {
+		name      string
+		buy, sell string
+		want      string
+		ok        bool
+	}{
+		{"profit", "100", "101", "1", true},
+		{"loss", "100", "99", "-1", true},
+		{"even", "100", "100", "0", true},
+		{"rounded", "3", "4", "33.333333333333333333333333333333", true},
+		{"zero buy", "0", "1", "0", false},
+		{"negative buy", "-1", "1", "0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := profitPercent(mustDecimal(t, tt.buy), mustDecimal(t, tt.sell))
+			if ok != tt.ok || !got.Equal(mustDecimal(t, tt.want)) {
+				t.Errorf("profitPercent(%s, %s) = %s, %v; want %s, %v", tt.buy, tt.sell, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDivisionPrecisionLeftAlone(t *testing.T) {
+	// Importing the package must not change the precision of divisions
+	// elsewhere in the program.
+	if decimal.DivisionPrecision != 16 {
+		t.Errorf("decimal.DivisionPrecision = %d, want the default 16", decimal.DivisionPrecision)
+	}
+}
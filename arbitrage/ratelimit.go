@@ -0,0 +1,188 @@
+package arbitrage
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// binanceWeightLimit is Binance's request weight allowance per minute
+	// for a single IP.
+	binanceWeightLimit = 6000
+
+	// rateLimitHeadroom is the fraction of an allowance that may be used
+	// before requests to that exchange are paused.
+	rateLimitHeadroom = 0.9
+)
+
+// rateLimitTracker remembers, per exchange, until when requests should be
+// held back because the exchange reported that its limit is nearly used up.
+// Exchanges are tracked independently since their limits are unrelated.
+type rateLimitTracker struct {
+	mu       sync.Mutex
+	resumeAt map[string]time.Time
+}
+
+var rateLimits = &rateLimitTracker{resumeAt: make(map[string]time.Time)}
+
+// wait blocks until requests to exchange may be sent again, or ctx is
+// cancelled.
+func (t *rateLimitTracker) wait(ctx context.Context, exchange string) error {
+	t.mu.Lock()
+	resumeAt := t.resumeAt[exchange]
+	t.mu.Unlock()
+
+	if pause := time.Until(resumeAt); pause > 0 {
+		slog.Warn("Rate limit nearly reached, pausing requests", "exchange", exchange, "pause", pause.Round(time.Millisecond))
+		return sleepContext(ctx, pause)
+	}
+	return nil
+}
+
+// observe reads the rate-limit headers of a response from exchange, logs the
+// current usage and schedules a pause if the allowance is nearly spent.
+func (t *rateLimitTracker) observe(exchange string, resp *http.Response) {
+	var resumeAt time.Time
+
+	switch exchange {
+	case ExchangeBinance:
+		used, err := strconv.Atoi(resp.Header.Get("X-MBX-USED-WEIGHT-1M"))
+		if err != nil {
+			return
+		}
+		slog.Debug("Rate limit usage", "exchange", exchange, "used_weight", used, "limit", binanceWeightLimit)
+		if float64(used) >= binanceWeightLimit*rateLimitHeadroom {
+			// The weight counter resets at the start of every minute.
+			resumeAt = time.Now().Truncate(time.Minute).Add(time.Minute)
+		}
+
+	case ExchangeBybit:
+		remaining, err := strconv.Atoi(resp.Header.Get("X-Bapi-Limit-Status"))
+		if err != nil {
+			return
+		}
+		limit, err := strconv.Atoi(resp.Header.Get("X-Bapi-Limit"))
+		if err != nil || limit <= 0 {
+			return
+		}
+		slog.Debug("Rate limit usage", "exchange", exchange, "remaining", remaining, "limit", limit)
+		if float64(limit-remaining) >= float64(limit)*rateLimitHeadroom {
+			resetMillis, err := strconv.ParseInt(resp.Header.Get("X-Bapi-Limit-Reset-Timestamp"), 10, 64)
+			if err != nil {
+				resumeAt = time.Now().Add(time.Second)
+			} else {
+				resumeAt = time.Unix(0, resetMillis*int64(time.Millisecond))
+			}
+		}
+
+	default:
+		return
+	}
+
+	if resumeAt.IsZero() {
+		return
+	}
+	t.mu.Lock()
+	if resumeAt.After(t.resumeAt[exchange]) {
+		t.resumeAt[exchange] = resumeAt
+	}
+	t.mu.Unlock()
+}
+
+const (
+	// DefaultRequestRate and DefaultRequestBurst pace requests to each API
+	// host. Coinbase, which needs one request per product, allows 10 per
+	// second; the rest allow far more.
+	DefaultRequestRate  = 10
+	DefaultRequestBurst = 10
+
+	// minRequestRate is the floor a host's rate is cut to by repeated 429
+	// responses.
+	minRequestRate = 0.5
+	// requestRateRecovery is the factor a throttled host's rate grows by
+	// with every successful response, until it is back at the configured
+	// rate.
+	requestRateRecovery = 1.1
+)
+
+// hostLimiters hands out a token bucket per API host, so exchanges fetched
+// concurrently are paced independently while every request to the same host
+// shares one budget. A 429 halves the host's rate; it then recovers gradually.
+type hostLimiters struct {
+	mu       sync.Mutex
+	limit    rate.Limit
+	burst    int
+	limiters map[string]*rate.Limiter
+}
+
+var requestLimits = newHostLimiters(DefaultRequestRate, DefaultRequestBurst)
+
+// newHostLimiters allows perSecond requests per host with bursts of burst.
+// A perSecond of 0 disables pacing.
+func newHostLimiters(perSecond float64, burst int) *hostLimiters {
+	limit := rate.Limit(perSecond)
+	if perSecond <= 0 {
+		limit = rate.Inf
+	}
+	return &hostLimiters{limit: limit, burst: burst, limiters: make(map[string]*rate.Limiter)}
+}
+
+// SetRequestRate sets the pacing of requests to every host, see
+// newHostLimiters.
+func SetRequestRate(perSecond float64, burst int) {
+	requestLimits = newHostLimiters(perSecond, burst)
+}
+
+func (h *hostLimiters) limiter(apiURL string) *rate.Limiter {
+	host := apiURL
+	if parsed, err := url.Parse(apiURL); err == nil {
+		host = parsed.Host
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	limiter, ok := h.limiters[host]
+	if !ok {
+		limiter = rate.NewLimiter(h.limit, h.burst)
+		h.limiters[host] = limiter
+	}
+	return limiter
+}
+
+// wait blocks until a request to apiURL's host may be sent, or ctx is
+// cancelled.
+func (h *hostLimiters) wait(ctx context.Context, apiURL string) error {
+	return h.limiter(apiURL).Wait(ctx)
+}
+
+// observe slows down apiURL's host after a 429 and otherwise lets a
+// throttled host recover towards the configured rate.
+func (h *hostLimiters) observe(apiURL string, resp *http.Response) {
+	if h.limit == rate.Inf {
+		return
+	}
+	limiter := h.limiter(apiURL)
+	current := limiter.Limit()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		reduced := current / 2
+		if reduced < minRequestRate {
+			reduced = minRequestRate
+		}
+		limiter.SetLimit(reduced)
+		slog.Warn("Rate limited, slowing down", "url", apiURL, "requests_per_second", float64(reduced))
+		return
+	}
+	if current < h.limit {
+		raised := current * requestRateRecovery
+		if raised > h.limit {
+			raised = h.limit
+		}
+		limiter.SetLimit(raised)
+	}
+}
@@ -0,0 +1,45 @@
+package arbitrage
+
+import (
+	"net/http"
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func TestHostLimitersThrottleAndRecover(t *testing.T) {
+	limits := newHostLimiters(10, 1)
+	tooMany := &http.Response{StatusCode: http.StatusTooManyRequests}
+	ok := &http.Response{StatusCode: http.StatusOK}
+
+	limits.observe("https://api.example.com/a", tooMany)
+	limits.observe("https://api.example.com/b", tooMany)
+	if got := limits.limiter("https://api.example.com/c").Limit(); got != 2.5 {
+		t.Errorf("rate after two 429s = %v, want 2.5", got)
+	}
+	if got := limits.limiter("https://other.example.com/").Limit(); got != 10 {
+		t.Errorf("other host's rate = %v, want 10", got)
+	}
+
+	for i := 0; i < 50; i++ {
+		limits.observe("https://api.example.com/a", ok)
+	}
+	if got := limits.limiter("https://api.example.com/a").Limit(); got != 10 {
+		t.Errorf("rate after recovering = %v, want 10", got)
+	}
+
+	for i := 0; i < 10; i++ {
+		limits.observe("https://api.example.com/a", tooMany)
+	}
+	if got := limits.limiter("https://api.example.com/a").Limit(); got != minRequestRate {
+		t.Errorf("rate after many 429s = %v, want %v", got, minRequestRate)
+	}
+}
+
+func TestHostLimitersDisabled(t *testing.T) {
+	limits := newHostLimiters(0, 0)
+	limits.observe("https://api.example.com/", &http.Response{StatusCode: http.StatusTooManyRequests})
+	if got := limits.limiter("https://api.example.com/").Limit(); got != rate.Inf {
+		t.Errorf("disabled rate = %v, want unlimited", got)
+	}
+}
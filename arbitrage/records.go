@@ -0,0 +1,82 @@
+package arbitrage
+
+import (
+	"encoding/json"
+	"log/slog"
+)
+
+// Records is a JSON array decoded one element at a time: an element that
+// doesn't match T, such as a ticker whose price turned from a string into a
+// number, is skipped and counted rather than failing the whole response.
+// The exchanges' market listings use it so that a schema change in a few
+// records doesn't take down the rest.
+type Records[T any] []T
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *Records[T]) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	records := make(Records[T], 0, len(raw))
+	var skipped []json.RawMessage
+	var firstErr error
+	for _, element := range raw {
+		var record T
+		if err := json.Unmarshal(element, &record); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			skipped = append(skipped, element)
+			continue
+		}
+		records = append(records, record)
+	}
+	reportSkippedRecords(skipped, len(raw), firstErr)
+	*r = records
+	return nil
+}
+
+// RecordMap is a JSON object decoded one value at a time, like Records, for
+// exchanges such as Kraken that key their markets by name.
+type RecordMap[T any] map[string]T
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *RecordMap[T]) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	records := make(RecordMap[T], len(raw))
+	var skipped []json.RawMessage
+	var firstErr error
+	for key, element := range raw {
+		var record T
+		if err := json.Unmarshal(element, &record); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			skipped = append(skipped, element)
+			continue
+		}
+		records[key] = record
+	}
+	reportSkippedRecords(skipped, len(raw), firstErr)
+	*m = records
+	return nil
+}
+
+// reportSkippedRecords warns about records that failed to decode. The
+// decoder doesn't know which exchange it is reading, so the first skipped
+// record is included to tell.
+func reportSkippedRecords(skipped []json.RawMessage, total int, firstErr error) {
+	if len(skipped) == 0 {
+		return
+	}
+	example := string(skipped[0])
+	if len(example) > maxErrorBodySnippet {
+		example = example[:maxErrorBodySnippet] + "..."
+	}
+	slog.Warn("Skipped records that failed to decode; the API format may have changed",
+		"skipped", len(skipped), "records", total, "err", firstErr, "example", example)
+}
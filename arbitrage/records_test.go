@@ -0,0 +1,59 @@
+package arbitrage
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestRecordsSkipMalformed(t *testing.T) {
+	type ticker struct {
+		Symbol string `json:"symbol"`
+		Bid    string `json:"bid"`
+	}
+
+	var list Records[ticker]
+	if err := json.Unmarshal([]byte(`[{"symbol":"A","bid":"1"},{"symbol":"B","bid":2},{"symbol":"C","bid":"3"}]`), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Symbol != "A" || list[1].Symbol != "C" {
+		t.Errorf("got %+v, want A and C with B skipped", list)
+	}
+
+	var byName RecordMap[ticker]
+	if err := json.Unmarshal([]byte(`{"A":{"bid":"1"},"B":{"bid":["2"]}}`), &byName); err != nil {
+		t.Fatal(err)
+	}
+	if len(byName) != 1 || byName["A"].Bid != "1" {
+		t.Errorf("got %+v, want only A", byName)
+	}
+
+	// Anything other than an array or object is still an error.
+	if err := json.Unmarshal([]byte(`"maintenance"`), &list); err == nil {
+		t.Error("expected an error for a string instead of a list")
+	}
+}
+
+func TestGetOKXPairsSkipsMalformedTicker(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/api/v5/market/tickers": `{"code":"0","msg":"","data":[
+			{"instId":"BTC-USDT","bidPx":"60000","askPx":"60001","volCcy24h":"1000"},
+			{"instId":"ETH-USDT","bidPx":3000,"askPx":3001,"volCcy24h":"1000"}
+		]}`,
+		"/api/v5/public/instruments": `{"code":"0","msg":"","data":[
+			{"instId":"BTC-USDT","baseCcy":"BTC","quoteCcy":"USDT","tickSz":"1"},
+			{"instId":"ETH-USDT","baseCcy":"ETH","quoteCcy":"USDT","tickSz":"1"}
+		]}`,
+	})
+	defer func(old string) { OKXBaseURL = old }(OKXBaseURL)
+	OKXBaseURL = server.URL
+
+	pairs, err := getOKXPairs(context.Background())
+	if err != nil {
+		t.Fatalf("getOKXPairs: %v", err)
+	}
+	if len(pairs) != 1 {
+		t.Fatalf("got %d pairs, want only BTC/USDT: %v", len(pairs), pairs)
+	}
+	assertPrice(t, pairs, "BTC/USDT", "BTC-USDT", "60000", "60001")
+}
@@ -0,0 +1,19 @@
+package arbitrage
+
+import "github.com/shopspring/decimal"
+
+// withRoundTrip fills in what one unit of starting capital is worth after
+// the whole round trip of opportunity: buying, selling and, once withdrawal
+// fees are applied, moving the asset and the proceeds between the
+// exchanges. With an Amount, quote left over from rounding the quantity
+// down counts as capital that was kept rather than invested.
+func withRoundTrip(opportunity ArbitrageOpportunity) ArbitrageOpportunity {
+	one := decimal.NewFromInt(1)
+	multiplier := one.Add(opportunity.ProfitPercentage.DivRound(hundred, DivisionPrecision))
+	if opportunity.Amount.IsPositive() {
+		multiplier = opportunity.Amount.Add(opportunity.NetProfit).DivRound(opportunity.Amount, DivisionPrecision)
+	}
+	opportunity.RoundTrip = multiplier
+	opportunity.RoundTripPct = multiplier.Sub(one).Mul(hundred)
+	return opportunity
+}
@@ -0,0 +1,32 @@
+package arbitrage
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRoundTrip(t *testing.T) {
+	buy := ExchangePrice{Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, "99"), AskPrice: mustDecimal(t, "100")}
+	sell := ExchangePrice{Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, "103"), AskPrice: mustDecimal(t, "104")}
+	fees := map[string]ExchangeFees{"A": {Taker: decimal.Zero}, "B": {Taker: decimal.Zero}}
+	opportunity, ok, _ := ComputeOpportunity("BTC/USDT", "A", "B", buy, sell, fees, mustDecimal(t, "0.01"), mustDecimal(t, "0.5"))
+	if !ok {
+		t.Fatal("no opportunity")
+	}
+	if !opportunity.RoundTrip.Equal(mustDecimal(t, "1.03")) || !opportunity.RoundTripPct.Equal(mustDecimal(t, "3")) {
+		t.Errorf("round trip = %s (%s%%), want 1.03 (3%%)", opportunity.RoundTrip, opportunity.RoundTripPct)
+	}
+
+	// 1000 USDT buys 10 BTC, sold for 1030 USDT. Withdrawing 0.1 BTC and 3
+	// USDT of the proceeds leaves 1016.7 USDT.
+	opportunity = ApplyAmount(opportunity, mustDecimal(t, "1000"))
+	withdrawal := WithdrawalFees{"A": {"BTC": mustDecimal(t, "0.1")}, "B": {"USDT": mustDecimal(t, "3")}}
+	kept := ApplyWithdrawalFees([]ArbitrageOpportunity{opportunity}, withdrawal, mustDecimal(t, "0.01"))
+	if len(kept) != 1 {
+		t.Fatalf("got %d opportunities after withdrawal fees, want 1", len(kept))
+	}
+	if got := kept[0]; !got.RoundTrip.Equal(mustDecimal(t, "1.0167")) || !got.RoundTripPct.Equal(mustDecimal(t, "1.67")) {
+		t.Errorf("round trip with withdrawal fees = %s (%s%%), want 1.0167 (1.67%%)", got.RoundTrip, got.RoundTripPct)
+	}
+}
@@ -0,0 +1,26 @@
+package arbitrage
+
+import "time"
+
+// DefaultMaxSkew is the default -max-skew. Prices taken further apart than
+// this can show a spread that has already closed on the faster exchange.
+const DefaultMaxSkew = 2 * time.Second
+
+// AnnotateSkew sets TimestampSkew on every opportunity to how far apart its
+// buy and sell prices were taken, according to priceTimes, and flags it as
+// stale when that exceeds maxSkew. A maxSkew of 0 never flags anything.
+func AnnotateSkew(opportunities []ArbitrageOpportunity, priceTimes map[string]time.Time, maxSkew time.Duration) {
+	for i := range opportunities {
+		buyTime, buyOK := priceTimes[opportunities[i].BuyExchange]
+		sellTime, sellOK := priceTimes[opportunities[i].SellExchange]
+		if !buyOK || !sellOK {
+			continue
+		}
+		skew := buyTime.Sub(sellTime)
+		if skew < 0 {
+			skew = -skew
+		}
+		opportunities[i].TimestampSkew = Duration(skew)
+		opportunities[i].Stale = maxSkew > 0 && skew > maxSkew
+	}
+}
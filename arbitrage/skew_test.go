@@ -0,0 +1,27 @@
+package arbitrage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnnotateSkew(t *testing.T) {
+	now := time.Now()
+	priceTimes := map[string]time.Time{
+		"A": now,
+		"B": now.Add(500 * time.Millisecond),
+		"C": now.Add(-3 * time.Second),
+	}
+	opportunities := []ArbitrageOpportunity{
+		{Symbol: "FRESH/USDT", BuyExchange: "A", SellExchange: "B"},
+		{Symbol: "STALE/USDT", BuyExchange: "A", SellExchange: "C"},
+	}
+
+	AnnotateSkew(opportunities, priceTimes, 2*time.Second)
+	if got := opportunities[0]; got.Stale || time.Duration(got.TimestampSkew) != 500*time.Millisecond {
+		t.Errorf("FRESH/USDT: stale = %v, skew = %s", got.Stale, time.Duration(got.TimestampSkew))
+	}
+	if got := opportunities[1]; !got.Stale || time.Duration(got.TimestampSkew) != 3*time.Second {
+		t.Errorf("STALE/USDT: stale = %v, skew = %s", got.Stale, time.Duration(got.TimestampSkew))
+	}
+}
@@ -0,0 +1,28 @@
+package arbitrage
+
+import "github.com/shopspring/decimal"
+
+// Slippage models, selected with -slippage-model.
+const (
+	// SlippageModelFlat worsens both legs by a fixed number of basis points.
+	SlippageModelFlat = "flat"
+	// SlippageModelDepth prices both legs by walking the order books for
+	// -trade-size, so the slippage is whatever the visible depth implies.
+	SlippageModelDepth = "depth"
+)
+
+var SlippageModel = SlippageModelFlat
+
+// Slippage is the fraction each leg is expected to slip under the flat
+// model. Zero leaves quoted prices untouched.
+var Slippage = decimal.Zero
+
+// applySlippage worsens fee-adjusted buy and sell prices by the flat
+// slippage: the buy fills higher and the sell lower than quoted.
+func applySlippage(buyPrice, sellPrice decimal.Decimal) (decimal.Decimal, decimal.Decimal) {
+	if SlippageModel != SlippageModelFlat || Slippage.IsZero() {
+		return buyPrice, sellPrice
+	}
+	one := decimal.NewFromInt(1)
+	return buyPrice.Mul(one.Add(Slippage)), sellPrice.Mul(one.Sub(Slippage))
+}
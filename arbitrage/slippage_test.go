@@ -0,0 +1,59 @@
+package arbitrage
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func withSlippage(t *testing.T, model, bps string) {
+	t.Helper()
+	oldModel, oldSlippage := SlippageModel, Slippage
+	t.Cleanup(func() { SlippageModel, Slippage = oldModel, oldSlippage })
+	SlippageModel = model
+	Slippage = mustDecimal(t, bps).Div(decimal.NewFromInt(10000))
+}
+
+func TestComputeOpportunityFlatSlippage(t *testing.T) {
+	a := ExchangePrice{Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, "99"), AskPrice: mustDecimal(t, "100")}
+	b := ExchangePrice{Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, "103"), AskPrice: mustDecimal(t, "104")}
+	fees := map[string]ExchangeFees{"A": {}, "B": {}}
+	minProfit, maxProfit := mustDecimal(t, "0.01"), mustDecimal(t, "0.5")
+
+	withSlippage(t, SlippageModelFlat, "50")
+	got, ok, _ := ComputeOpportunity("BTC/USDT", "A", "B", a, b, fees, minProfit, maxProfit)
+	if !ok {
+		t.Fatal("expected an opportunity with 50 bps of slippage")
+	}
+	if !got.BuyPrice.Equal(mustDecimal(t, "100.5")) || !got.SellPrice.Equal(mustDecimal(t, "102.485")) {
+		t.Errorf("buy %s, sell %s; want 100.5 and 102.485", got.BuyPrice, got.SellPrice)
+	}
+
+	// 150 bps per leg eats the 3% spread.
+	withSlippage(t, SlippageModelFlat, "150")
+	if _, ok, _ := ComputeOpportunity("BTC/USDT", "A", "B", a, b, fees, minProfit, maxProfit); ok {
+		t.Error("expected no opportunity with 150 bps of slippage")
+	}
+
+	// The depth model leaves the ticker screen to top-of-book prices.
+	withSlippage(t, SlippageModelDepth, "150")
+	if _, ok, _ := ComputeOpportunity("BTC/USDT", "A", "B", a, b, fees, minProfit, maxProfit); !ok {
+		t.Error("expected the depth model to ignore the flat slippage")
+	}
+}
+
+func TestCheckDepthFlatSlippage(t *testing.T) {
+	buyBook := OrderBook{Asks: []OrderBookLevel{{Price: mustDecimal(t, "100"), Quantity: mustDecimal(t, "10")}}}
+	sellBook := OrderBook{Bids: []OrderBookLevel{{Price: mustDecimal(t, "102"), Quantity: mustDecimal(t, "10")}}}
+	tradeSize, minProfit := mustDecimal(t, "100"), mustDecimal(t, "0.01")
+
+	withSlippage(t, SlippageModelFlat, "0")
+	if _, ok := checkDepth(ArbitrageOpportunity{}, buyBook, sellBook, ExchangeFees{}, ExchangeFees{}, tradeSize, minProfit); !ok {
+		t.Fatal("expected the 2% spread to survive without slippage")
+	}
+
+	withSlippage(t, SlippageModelFlat, "60")
+	if _, ok := checkDepth(ArbitrageOpportunity{}, buyBook, sellBook, ExchangeFees{}, ExchangeFees{}, tradeSize, minProfit); ok {
+		t.Error("expected 60 bps per leg to push the profit below 1%")
+	}
+}
@@ -0,0 +1,64 @@
+package arbitrage
+
+import (
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// PriceSmoother replaces each market's bid and ask with an exponential
+// moving average over about the last window cycles, so a single bad tick
+// can't produce an opportunity on its own. It keeps the averages between
+// cycles and must be given every cycle's prices.
+type PriceSmoother struct {
+	alpha decimal.Decimal
+
+	mu sync.Mutex
+	// averages are the current averages by exchange and symbol.
+	averages map[string]map[string]smoothedPrice
+}
+
+type smoothedPrice struct {
+	bid, ask decimal.Decimal
+}
+
+// NewPriceSmoother returns a smoother weighting the latest prices by
+// 2/(window+1), the usual weight of an EMA over window periods.
+func NewPriceSmoother(window int) *PriceSmoother {
+	return &PriceSmoother{
+		alpha:    decimal.NewFromInt(2).DivRound(decimal.NewFromInt(int64(window)+1), DivisionPrecision),
+		averages: make(map[string]map[string]smoothedPrice),
+	}
+}
+
+// Smooth returns exchange's pairs with BidPrice and AskPrice replaced by
+// their moving averages, and the fetched prices kept in RawBidPrice and
+// RawAskPrice. The average of a market seen for the first time starts at
+// its fetched prices. Markets missing from pairs are forgotten, so they
+// start over if they come back.
+func (s *PriceSmoother) Smooth(exchange string, pairs map[string]ExchangePrice) map[string]ExchangePrice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.averages[exchange]
+	current := make(map[string]smoothedPrice, len(pairs))
+	smoothed := make(map[string]ExchangePrice, len(pairs))
+	one := decimal.NewFromInt(1)
+	for symbol, price := range pairs {
+		average, seen := previous[symbol]
+		if seen {
+			// Rounding keeps the digits of the averages from growing with
+			// every cycle.
+			average.bid = s.alpha.Mul(price.BidPrice).Add(one.Sub(s.alpha).Mul(average.bid)).Round(DivisionPrecision)
+			average.ask = s.alpha.Mul(price.AskPrice).Add(one.Sub(s.alpha).Mul(average.ask)).Round(DivisionPrecision)
+		} else {
+			average = smoothedPrice{bid: price.BidPrice, ask: price.AskPrice}
+		}
+		current[symbol] = average
+
+		price.RawBidPrice, price.RawAskPrice = price.BidPrice, price.AskPrice
+		price.BidPrice, price.AskPrice = average.bid, average.ask
+		smoothed[symbol] = price
+	}
+	s.averages[exchange] = current
+	return smoothed
+}
@@ -0,0 +1,81 @@
+package arbitrage
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPriceSmoother(t *testing.T) {
+	price := func(bid, ask string) ExchangePrice {
+		return ExchangePrice{Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, bid), AskPrice: mustDecimal(t, ask)}
+	}
+	// A window of 3 weights the latest prices by a half.
+	smoother := NewPriceSmoother(3)
+	smoothed := smoother.Smooth("A", map[string]ExchangePrice{"BTC/USDT": price("100", "101")})
+	if got := smoothed["BTC/USDT"]; !got.BidPrice.Equal(mustDecimal(t, "100")) || !got.AskPrice.Equal(mustDecimal(t, "101")) {
+		t.Errorf("first prices smoothed to %s/%s, want them unchanged", got.BidPrice, got.AskPrice)
+	}
+	smoothed = smoother.Smooth("A", map[string]ExchangePrice{"BTC/USDT": price("110", "111")})
+	got := smoothed["BTC/USDT"]
+	if !got.BidPrice.Equal(mustDecimal(t, "105")) || !got.AskPrice.Equal(mustDecimal(t, "106")) {
+		t.Errorf("smoothed to %s/%s, want 105/106", got.BidPrice, got.AskPrice)
+	}
+	if !got.RawBidPrice.Equal(mustDecimal(t, "110")) || !got.RawAskPrice.Equal(mustDecimal(t, "111")) {
+		t.Errorf("raw prices = %s/%s, want 110/111", got.RawBidPrice, got.RawAskPrice)
+	}
+
+	// Exchanges are averaged separately, and a market that disappears
+	// starts over when it comes back.
+	smoothed = smoother.Smooth("B", map[string]ExchangePrice{"BTC/USDT": price("200", "201")})
+	if got := smoothed["BTC/USDT"]; !got.BidPrice.Equal(mustDecimal(t, "200")) {
+		t.Errorf("B smoothed with A's average: bid %s", got.BidPrice)
+	}
+	smoother.Smooth("A", map[string]ExchangePrice{})
+	smoothed = smoother.Smooth("A", map[string]ExchangePrice{"BTC/USDT": price("90", "91")})
+	if got := smoothed["BTC/USDT"]; !got.BidPrice.Equal(mustDecimal(t, "90")) {
+		t.Errorf("returning market smoothed to %s, want it to start over at 90", got.BidPrice)
+	}
+}
+
+func TestPriceSmootherIgnoresSingleSpike(t *testing.T) {
+	fees := map[string]ExchangeFees{"A": {Taker: decimal.NewFromFloat(0.001)}, "B": {Taker: decimal.NewFromFloat(0.001)}}
+	minProfit, maxProfit := decimal.NewFromFloat(0.01), decimal.NewFromFloat(0.5)
+	steady := ExchangePrice{Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, "100"), AskPrice: mustDecimal(t, "100.1")}
+	spike := steady
+	spike.BidPrice = mustDecimal(t, "103")
+
+	smoother := NewPriceSmoother(9)
+	for i := 0; i < 5; i++ {
+		smoother.Smooth("A", map[string]ExchangePrice{"BTC/USDT": steady})
+		smoother.Smooth("B", map[string]ExchangePrice{"BTC/USDT": steady})
+	}
+	raw := map[string]map[string]ExchangePrice{
+		"A": {"BTC/USDT": steady},
+		"B": {"BTC/USDT": spike},
+	}
+	if len(FindArbitrage(raw, fees, minProfit, maxProfit)) != 1 {
+		t.Fatal("the spike should be an opportunity without smoothing")
+	}
+	smoothed := map[string]map[string]ExchangePrice{
+		"A": smoother.Smooth("A", raw["A"]),
+		"B": smoother.Smooth("B", raw["B"]),
+	}
+	if opportunities := FindArbitrage(smoothed, fees, minProfit, maxProfit); len(opportunities) != 0 {
+		t.Errorf("a single spike was reported with smoothing: %+v", opportunities)
+	}
+
+	// A spread that persists is reported, with the last quoted prices.
+	for i := 0; i < 20; i++ {
+		smoothed["A"] = smoother.Smooth("A", raw["A"])
+		smoothed["B"] = smoother.Smooth("B", raw["B"])
+	}
+	opportunities := FindArbitrage(smoothed, fees, minProfit, maxProfit)
+	if len(opportunities) != 1 {
+		t.Fatalf("got %d opportunities for a persistent spread, want 1", len(opportunities))
+	}
+	if opportunity := opportunities[0]; opportunity.RawAsk == nil || !opportunity.RawAsk.Equal(steady.AskPrice) ||
+		opportunity.RawBid == nil || !opportunity.RawBid.Equal(spike.BidPrice) {
+		t.Errorf("raw ask/bid = %v/%v, want 100.1/103", opportunity.RawAsk, opportunity.RawBid)
+	}
+}
@@ -0,0 +1,106 @@
+package arbitrage
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store holds the latest prices of every exchange and the latest
+// opportunities for readers on other goroutines, such as HTTP handlers,
+// while cycles keep replacing them. The zero value is an empty store. The
+// maps and slices handed to and returned by a Store are shared, not copied,
+// so they must not be modified once stored.
+type Store struct {
+	mu             sync.RWMutex
+	pairs          map[string]map[string]ExchangePrice
+	pairsUpdatedAt map[string]time.Time
+	opportunities  []ArbitrageOpportunity
+	updatedAt      time.Time
+}
+
+// Update replaces everything held with the results of a cycle whose prices
+// were fetched at updatedAt. Exchanges missing from pairs, such as one whose
+// fetch failed, are dropped.
+func (s *Store) Update(pairs map[string]map[string]ExchangePrice, opportunities []ArbitrageOpportunity, updatedAt time.Time) {
+	pairsUpdatedAt := make(map[string]time.Time, len(pairs))
+	for name := range pairs {
+		pairsUpdatedAt[name] = updatedAt
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pairs, s.pairsUpdatedAt = pairs, pairsUpdatedAt
+	s.opportunities, s.updatedAt = opportunities, updatedAt
+}
+
+// SetPairs replaces the prices of a single exchange, fetched at updatedAt,
+// leaving the other exchanges and the opportunities as they are.
+func (s *Store) SetPairs(exchange string, pairs map[string]ExchangePrice, updatedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	updated := make(map[string]map[string]ExchangePrice, len(s.pairs)+1)
+	for name, prices := range s.pairs {
+		updated[name] = prices
+	}
+	updated[exchange] = pairs
+	if s.pairsUpdatedAt == nil {
+		s.pairsUpdatedAt = make(map[string]time.Time)
+	}
+	s.pairs = updated
+	s.pairsUpdatedAt[exchange] = updatedAt
+}
+
+// Opportunities returns the latest opportunities and when the prices they
+// were found in were fetched. The time is zero until the first Update.
+func (s *Store) Opportunities() ([]ArbitrageOpportunity, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.opportunities, s.updatedAt
+}
+
+// UpdatedAt returns when the prices of the latest Update were fetched, or
+// zero before the first one.
+func (s *Store) UpdatedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.updatedAt
+}
+
+// Pairs returns the latest prices of exchange, whose name is matched
+// case-insensitively, with its name as stored and when they were fetched.
+// ok is false when the store holds no prices from it.
+func (s *Store) Pairs(exchange string) (name string, pairs map[string]ExchangePrice, updatedAt time.Time, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for name, pairs := range s.pairs {
+		if strings.EqualFold(name, exchange) {
+			return name, pairs, s.pairsUpdatedAt[name], true
+		}
+	}
+	return "", nil, time.Time{}, false
+}
+
+// Exchanges returns the names of the exchanges whose prices are held,
+// sorted.
+func (s *Store) Exchanges() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.pairs))
+	for name := range s.pairs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PairCounts returns how many pairs each exchange's latest prices hold.
+func (s *Store) PairCounts() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	counts := make(map[string]int, len(s.pairs))
+	for name, pairs := range s.pairs {
+		counts[name] = len(pairs)
+	}
+	return counts
+}
@@ -0,0 +1,78 @@
+package arbitrage
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	var store Store
+	if opportunities, at := store.Opportunities(); opportunities != nil || !at.IsZero() {
+		t.Errorf("empty store: opportunities = %v at %v", opportunities, at)
+	}
+	if _, _, _, ok := store.Pairs("Binance"); ok {
+		t.Error("empty store: Pairs(Binance) ok")
+	}
+
+	first := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	store.Update(map[string]map[string]ExchangePrice{
+		"Binance": {"BTC/USDT": {Symbol: "BTCUSDT"}},
+		"Kraken":  {"BTC/USDT": {Symbol: "XBTUSDT"}, "ETH/USDT": {Symbol: "ETHUSDT"}},
+	}, []ArbitrageOpportunity{{Symbol: "BTC/USDT"}}, first)
+
+	name, pairs, at, ok := store.Pairs("binance")
+	if !ok || name != "Binance" || pairs["BTC/USDT"].Symbol != "BTCUSDT" || !at.Equal(first) {
+		t.Errorf("Pairs(binance) = %q %v %v %v", name, pairs, at, ok)
+	}
+	if opportunities, at := store.Opportunities(); len(opportunities) != 1 || !at.Equal(first) {
+		t.Errorf("Opportunities() = %v at %v", opportunities, at)
+	}
+	if counts := store.PairCounts(); counts["Binance"] != 1 || counts["Kraken"] != 2 {
+		t.Errorf("PairCounts() = %v", counts)
+	}
+
+	second := first.Add(time.Minute)
+	store.SetPairs("Kraken", map[string]ExchangePrice{"BTC/USDT": {Symbol: "XBTUSDT"}}, second)
+	if _, pairs, at, _ := store.Pairs("Kraken"); len(pairs) != 1 || !at.Equal(second) {
+		t.Errorf("after SetPairs: Kraken = %v at %v", pairs, at)
+	}
+	if _, _, at, _ := store.Pairs("Binance"); !at.Equal(first) || !store.UpdatedAt().Equal(first) {
+		t.Errorf("SetPairs changed Binance (%v) or the update time (%v)", at, store.UpdatedAt())
+	}
+
+	// A later cycle without Kraken drops its prices.
+	store.Update(map[string]map[string]ExchangePrice{"Binance": {}}, nil, second)
+	if names := store.Exchanges(); len(names) != 1 || names[0] != "Binance" {
+		t.Errorf("Exchanges() = %v, want [Binance]", names)
+	}
+}
+
+func TestStoreConcurrentAccess(t *testing.T) {
+	var store Store
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				at := time.Unix(int64(i*100+j), 0)
+				store.Update(map[string]map[string]ExchangePrice{"Binance": {"BTC/USDT": {}}}, []ArbitrageOpportunity{{}}, at)
+				store.SetPairs("Kraken", map[string]ExchangePrice{}, at)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				store.Opportunities()
+				store.Pairs("binance")
+				store.Exchanges()
+				store.PairCounts()
+			}
+		}()
+	}
+	wg.Wait()
+	if _, pairs, _, ok := store.Pairs("Binance"); !ok || len(pairs) != 1 {
+		t.Errorf("after concurrent updates: Binance = %v, %v", pairs, ok)
+	}
+}
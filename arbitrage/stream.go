@@ -0,0 +1,176 @@
+package arbitrage
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	streamReconnectBaseDelay = time.Second
+	streamReconnectMaxDelay  = time.Minute
+	// streamReadTimeout drops a connection that has gone quiet, so a
+	// half-open socket is noticed and replaced.
+	streamReadTimeout = 30 * time.Second
+	// streamMaxAge is how long a stream may go without an update before
+	// its prices are considered dead and the exchange falls back to REST.
+	streamMaxAge = 10 * time.Second
+)
+
+// streamDialer opens every stream connection. Like websocket.DefaultDialer it
+// honours the proxy environment variables until SetProxy is called.
+var streamDialer = &websocket.Dialer{
+	Proxy:            http.ProxyFromEnvironment,
+	HandshakeTimeout: 45 * time.Second,
+}
+
+// priceStream keeps an in-memory copy of an exchange's prices up to date
+// from a WebSocket feed, reconnecting whenever the connection drops.
+type priceStream struct {
+	name string
+	url  string
+	// seed loads the full set of prices over REST. It runs before every
+	// connection, so markets that rarely update are never older than the
+	// last reconnect.
+	seed func(ctx context.Context) (map[string]ExchangePrice, error)
+	// subscribe, if set, is called on every new connection before reading,
+	// with the freshly seeded prices.
+	subscribe func(conn *websocket.Conn, prices map[string]ExchangePrice) error
+	// ping, if set, is sent every pingInterval for exchanges that expect
+	// application-level keepalives rather than WebSocket pings.
+	ping         []byte
+	pingInterval time.Duration
+	// handle applies one message to prices, which are keyed by the
+	// exchange's own symbol. It runs with the lock held.
+	handle func(message []byte, prices map[string]ExchangePrice) error
+
+	mu         sync.RWMutex
+	prices     map[string]ExchangePrice
+	connected  bool
+	lastUpdate time.Time
+}
+
+// start runs the stream in the background until ctx is cancelled.
+func (s *priceStream) start(ctx context.Context) {
+	go s.run(ctx)
+}
+
+func (s *priceStream) run(ctx context.Context) {
+	delay := streamReconnectBaseDelay
+	for {
+		err := s.connect(ctx)
+		s.mu.Lock()
+		s.connected = false
+		s.mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("Stream disconnected, reconnecting", "exchange", s.name, "delay", delay, "err", err)
+
+		if sleepContext(ctx, delay) != nil {
+			return
+		}
+		delay *= 2
+		if delay > streamReconnectMaxDelay {
+			delay = streamReconnectMaxDelay
+		}
+	}
+}
+
+// connect seeds the prices, dials the stream and reads from it until the
+// connection fails.
+func (s *priceStream) connect(ctx context.Context) error {
+	seeded, err := s.seed(ctx)
+	if err != nil {
+		return err
+	}
+	// Messages name markets by the exchange's symbol. Prices the seed
+	// doesn't date count as updated now; every message then moves the time
+	// of the markets it touches forward.
+	seededAt := time.Now()
+	prices := make(map[string]ExchangePrice, len(seeded))
+	for _, price := range seeded {
+		if price.UpdatedAt.IsZero() {
+			price.UpdatedAt = seededAt
+		}
+		prices[price.Symbol] = price
+	}
+
+	conn, _, err := streamDialer.DialContext(ctx, s.url, RequestHeaders.Clone())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// Closing the connection unblocks the read loop on cancellation.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if s.subscribe != nil {
+		if err := s.subscribe(conn, prices); err != nil {
+			return err
+		}
+	}
+	if s.ping != nil {
+		done := make(chan struct{})
+		defer close(done)
+		go s.keepalive(conn, done)
+	}
+
+	s.mu.Lock()
+	s.prices = prices
+	s.connected = true
+	s.mu.Unlock()
+	slog.Info("Stream connected", "exchange", s.name, "pairs", len(prices))
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(streamReadTimeout))
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		err = s.handle(message, s.prices)
+		if err == nil {
+			s.lastUpdate = time.Now()
+		}
+		s.mu.Unlock()
+		if err != nil {
+			slog.Debug("Ignoring stream message", "exchange", s.name, "err", err)
+		}
+	}
+}
+
+// keepalive sends s.ping on conn until done is closed. Write errors are
+// ignored; the read loop notices the broken connection.
+func (s *priceStream) keepalive(conn *websocket.Conn, done chan struct{}) {
+	ticker := time.NewTicker(s.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			conn.WriteMessage(websocket.TextMessage, s.ping)
+		}
+	}
+}
+
+// snapshot returns a copy of the current prices. ok is false when the
+// stream is disconnected or hasn't updated for streamMaxAge, in which case
+// the caller should fetch over REST instead.
+func (s *priceStream) snapshot() (map[string]ExchangePrice, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.connected || time.Since(s.lastUpdate) > streamMaxAge {
+		return nil, false
+	}
+	pairs := make(map[string]ExchangePrice, len(s.prices))
+	for _, price := range s.prices {
+		pairs[CanonicalSymbol(price.Base, price.Quote)] = price
+	}
+	return pairs, true
+}
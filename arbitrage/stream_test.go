@@ -0,0 +1,114 @@
+package arbitrage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newStreamServer serves a WebSocket that sends messages to every client
+// and then stays open until the test ends.
+func newStreamServer(t *testing.T, messages ...string) string {
+	t.Helper()
+	done := make(chan struct{})
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+		for _, message := range messages {
+			conn.WriteMessage(websocket.TextMessage, []byte(message))
+		}
+		<-done
+	}))
+	t.Cleanup(func() {
+		close(done)
+		server.Close()
+	})
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// waitForSnapshot polls the stream until it is live and check accepts its
+// prices.
+func waitForSnapshot(t *testing.T, stream *priceStream, check func(map[string]ExchangePrice) bool) map[string]ExchangePrice {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if pairs, ok := stream.snapshot(); ok && check(pairs) {
+			return pairs
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("stream never produced the expected prices")
+	return nil
+}
+
+func TestBinanceStream(t *testing.T) {
+	stream := newBinanceStream()
+	stream.url = newStreamServer(t,
+		`{"u":1,"s":"BTCUSDT","b":"60100.5","B":"1","a":"60100.6","A":"2"}`,
+		`{"u":2,"s":"UNKNOWN","b":"1","B":"1","a":"1","A":"1"}`,
+	)
+	stream.seed = func(ctx context.Context) (map[string]ExchangePrice, error) {
+		return map[string]ExchangePrice{
+			"BTC/USDT": {Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, "60000"), AskPrice: mustDecimal(t, "60001")},
+		}, nil
+	}
+
+	if _, ok := stream.snapshot(); ok {
+		t.Fatal("snapshot is live before the stream started")
+	}
+	stream.start(context.Background())
+
+	pairs := waitForSnapshot(t, stream, func(pairs map[string]ExchangePrice) bool {
+		return pairs["BTC/USDT"].BidPrice.Equal(mustDecimal(t, "60100.5"))
+	})
+	if len(pairs) != 1 {
+		t.Errorf("got %d pairs, want 1: %v", len(pairs), pairs)
+	}
+	assertPrice(t, pairs, "BTC/USDT", "BTCUSDT", "60100.5", "60100.6")
+}
+
+func TestHandleBybitStreamMessage(t *testing.T) {
+	prices := map[string]ExchangePrice{
+		"BTCUSDT": {Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, "1"), AskPrice: mustDecimal(t, "2")},
+		"ETHUSDT": {Symbol: "ETHUSDT", Base: "ETH", Quote: "USDT", BidPrice: mustDecimal(t, "1"), AskPrice: mustDecimal(t, "2")},
+	}
+	messages := []string{
+		`{"success":true,"op":"subscribe"}`,
+		// Spot order book: a snapshot, then a delta that only moves the ask.
+		`{"topic":"orderbook.1.BTCUSDT","type":"snapshot","data":{"s":"BTCUSDT","b":[["60000","1"]],"a":[["60001","2"]]}}`,
+		`{"topic":"orderbook.1.BTCUSDT","type":"delta","ts":1700000000123,"data":{"s":"BTCUSDT","b":[],"a":[["60002","1"]]}}`,
+		// Linear tickers: a delta without an ask keeps the previous one.
+		`{"topic":"tickers.ETHUSDT","type":"snapshot","data":{"symbol":"ETHUSDT","bid1Price":"3000","ask1Price":"3000.5","turnover24h":"100"}}`,
+		`{"topic":"tickers.ETHUSDT","type":"delta","data":{"symbol":"ETHUSDT","bid1Price":"3000.1"}}`,
+	}
+	for _, message := range messages {
+		if err := handleBybitStreamMessage([]byte(message), prices); err != nil {
+			t.Fatalf("%s: %v", message, err)
+		}
+	}
+
+	assertPrice(t, prices, "BTCUSDT", "BTCUSDT", "60000", "60002")
+	assertPrice(t, prices, "ETHUSDT", "ETHUSDT", "3000.1", "3000.5")
+	if got := prices["BTCUSDT"].UpdatedAt; !got.Equal(time.UnixMilli(1700000000123)) {
+		t.Errorf("BTCUSDT updated at %s, want the message time", got)
+	}
+
+	// Removing the only bid leaves no usable price.
+	removal := `{"topic":"orderbook.1.BTCUSDT","type":"delta","data":{"s":"BTCUSDT","b":[["60000","0"]],"a":[]}}`
+	if err := handleBybitStreamMessage([]byte(removal), prices); err != nil {
+		t.Fatal(err)
+	}
+	if !prices["BTCUSDT"].BidPrice.IsZero() {
+		t.Errorf("bid after removal = %s, want 0", prices["BTCUSDT"].BidPrice)
+	}
+}
@@ -0,0 +1,108 @@
+package arbitrage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// SymbolMap is a user-provided override of how assets are matched across
+// exchanges, loaded from the file given by -symbol-map, e.g.
+//
+//	{
+//	  "aliases": {"Bybit": {"MATIC": "POL"}, "Kraken": {"MATIC": "POL"}},
+//	  "separate": ["NEIRO"]
+//	}
+//
+// aliases renames an exchange's asset codes to the code they should be
+// compared under, for assets that are listed under different tickers.
+// separate lists assets whose ticker is shared by unrelated tokens on
+// different exchanges, so their markets are never compared with each other.
+type SymbolMap struct {
+	aliases  map[string]map[string]string
+	separate map[string]bool
+}
+
+func LoadSymbolMap(path string) (*SymbolMap, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading symbol map: %v", err)
+	}
+
+	var raw struct {
+		Aliases  map[string]map[string]string `json:"aliases"`
+		Separate []string                     `json:"separate"`
+	}
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling symbol map: %v", err)
+	}
+
+	m := &SymbolMap{
+		aliases:  make(map[string]map[string]string, len(raw.Aliases)),
+		separate: make(map[string]bool, len(raw.Separate)),
+	}
+	for exchange, aliases := range raw.Aliases {
+		m.aliases[exchange] = make(map[string]string, len(aliases))
+		for from, to := range aliases {
+			to = CanonicalAsset(to)
+			if to == "" {
+				return nil, fmt.Errorf("alias of %s on %s is empty", from, exchange)
+			}
+			m.aliases[exchange][CanonicalAsset(from)] = to
+		}
+	}
+	for _, asset := range raw.Separate {
+		m.separate[CanonicalAsset(asset)] = true
+	}
+	return m, nil
+}
+
+// alias re-keys the pairs of exchange under the aliased base and quote
+// assets, which are also what the prices report from then on. When an alias
+// collides with a market the exchange already lists under that name, the
+// market with the higher quote volume is kept.
+func (m *SymbolMap) Alias(exchange string, pairs map[string]ExchangePrice) map[string]ExchangePrice {
+	aliases := m.aliases[exchange]
+	if len(aliases) == 0 {
+		return pairs
+	}
+	aliased := make(map[string]ExchangePrice, len(pairs))
+	for key, price := range pairs {
+		base, renamedBase := aliases[price.Base]
+		quote, renamedQuote := aliases[price.Quote]
+		if renamedBase {
+			price.Base = base
+		}
+		if renamedQuote {
+			price.Quote = quote
+		}
+		if renamedBase || renamedQuote {
+			key = CanonicalSymbol(price.Base, price.Quote)
+		}
+		if existing, ok := aliased[key]; ok && existing.QuoteVolume.GreaterThanOrEqual(price.QuoteVolume) {
+			continue
+		}
+		aliased[key] = price
+	}
+	return aliased
+}
+
+// isolate re-keys the pairs of exchange whose base asset is listed as
+// separate under a key no other exchange shares, e.g. "NEIRO@BINANCE/USDT".
+// It runs after every other normalization step, so the quote part of the
+// key is kept as it is.
+func (m *SymbolMap) Isolate(exchange string, pairs map[string]ExchangePrice) map[string]ExchangePrice {
+	if len(m.separate) == 0 {
+		return pairs
+	}
+	isolated := make(map[string]ExchangePrice, len(pairs))
+	for key, price := range pairs {
+		if m.separate[price.Base] {
+			key = CanonicalSymbol(price.Base+"@"+exchange, key[strings.LastIndex(key, "/")+1:])
+		}
+		isolated[key] = price
+	}
+	return isolated
+}
@@ -0,0 +1,71 @@
+package arbitrage
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func writeSymbolMap(t *testing.T, content string) *SymbolMap {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "symbols.json")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadSymbolMap(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestSymbolMapAlias(t *testing.T) {
+	m := writeSymbolMap(t, `{"aliases": {"Bybit": {"matic": "pol"}}}`)
+	pairs := map[string]ExchangePrice{
+		"MATIC/USDT": {Symbol: "MATICUSDT", Base: "MATIC", Quote: "USDT", QuoteVolume: mustDecimal(t, "1000")},
+		"POL/USDT":   {Symbol: "POLUSDT", Base: "POL", Quote: "USDT", QuoteVolume: mustDecimal(t, "10")},
+		"BTC/USDT":   {Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT"},
+	}
+
+	aliased := m.Alias(ExchangeBybit, pairs)
+	if len(aliased) != 2 {
+		t.Fatalf("got %d pairs, want 2: %v", len(aliased), aliased)
+	}
+	got := aliased["POL/USDT"]
+	if got.Symbol != "MATICUSDT" || got.Base != "POL" {
+		t.Errorf("POL/USDT = %s with base %s, want the higher-volume MATICUSDT with base POL", got.Symbol, got.Base)
+	}
+	if _, ok := aliased["BTC/USDT"]; !ok {
+		t.Error("BTC/USDT should keep its key")
+	}
+
+	if other := m.Alias(ExchangeBinance, pairs); len(other) != 3 {
+		t.Errorf("aliases for Bybit changed Binance's pairs: %v", other)
+	}
+}
+
+func TestSymbolMapIsolate(t *testing.T) {
+	m := writeSymbolMap(t, `{"separate": ["neiro"]}`)
+	pairs := map[string]ExchangePrice{
+		"NEIRO/USD*": {Symbol: "NEIROUSDT", Base: "NEIRO", Quote: "USDT"},
+		"BTC/USDT":   {Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT"},
+	}
+
+	isolated := m.Isolate(ExchangeBinance, pairs)
+	if _, ok := isolated["NEIRO@BINANCE/USD*"]; !ok {
+		t.Errorf("NEIRO should be keyed by exchange, keeping the merged quote: %v", isolated)
+	}
+	if _, ok := isolated["BTC/USDT"]; !ok {
+		t.Error("BTC/USDT should keep its key")
+	}
+}
+
+func TestLoadSymbolMapEmptyAlias(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "symbols.json")
+	if err := ioutil.WriteFile(path, []byte(`{"aliases": {"Bybit": {"MATIC": " "}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSymbolMap(path); err == nil {
+		t.Error("expected an error for an empty alias")
+	}
+}
@@ -0,0 +1,164 @@
+package arbitrage
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// CanonicalAsset returns the form of an asset code used for matching across
+// exchanges.
+func CanonicalAsset(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// CanonicalSymbol builds the key pairs are compared on. Keys are derived from
+// the base and quote assets reported by each exchange rather than from the
+// exchange's own symbol string, so "BTCUSDT", "BTC-USDT" and "BTC/USDT" all
+// end up as "BTC/USDT". Different quote assets always give different keys:
+// BTC/USD and BTC/USDT are never compared unless -treat-stables-equal merges
+// them.
+func CanonicalSymbol(base, quote string) string {
+	return CanonicalAsset(base) + "/" + CanonicalAsset(quote)
+}
+
+// marketInfo is what an exchange's instrument list says about one of its
+// markets.
+type marketInfo struct {
+	base, quote string
+	tickSize    decimal.Decimal
+}
+
+// lookupMarket returns the metadata of symbol from markets. Base and quote
+// come from the metadata rather than from the position of each part of the
+// symbol, so an exchange writing a market as QUOTE-BASE still lines up with
+// the others. Markets missing from a loaded list are unknown. Only when the
+// list couldn't be loaded at all is symbol split by position around sep,
+// BASE first, without a tick size.
+func lookupMarket(markets map[string]marketInfo, loaded bool, symbol, sep string) (marketInfo, bool) {
+	if loaded {
+		market, known := markets[symbol]
+		return market, known
+	}
+	parts := strings.Split(symbol, sep)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return marketInfo{}, false
+	}
+	return marketInfo{base: parts[0], quote: parts[1]}, true
+}
+
+// stableQuoteAssets are the dollar-pegged quote currencies that
+// -treat-stables-equal compares as if they were the same asset.
+var stableQuoteAssets = map[string]bool{
+	"USD": true, "USDT": true, "USDC": true, "FDUSD": true,
+	"BUSD": true, "TUSD": true, "DAI": true,
+}
+
+// stableQuoteKey replaces the quote asset in the keys of pairs merged by
+// MergeStableQuotes, e.g. "BTC/USD*".
+const stableQuoteKey = "USD*"
+
+// StableQuoteList returns the stablecoins merged by -treat-stables-equal, for
+// logging.
+func StableQuoteList() string {
+	assets := make([]string, 0, len(stableQuoteAssets))
+	for asset := range stableQuoteAssets {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+	return strings.Join(assets, ", ")
+}
+
+// MergeStableQuotes re-keys every pair quoted in a stablecoin under a shared
+// "BASE/USD*" key, so BTC/USD on one exchange is compared with BTC/USDT on
+// another. The prices keep their real quote asset. When an exchange lists the
+// same base against several stablecoins, the market with the highest quote
+// volume is kept. Pairs whose base is itself a stablecoin are left alone,
+// since comparing USDC/USD with USDT/USDC would be meaningless.
+func MergeStableQuotes(pairs map[string]ExchangePrice) map[string]ExchangePrice {
+	merged := make(map[string]ExchangePrice, len(pairs))
+	for key, price := range pairs {
+		if !stableQuoteAssets[price.Quote] || stableQuoteAssets[price.Base] {
+			merged[key] = price
+			continue
+		}
+		key = CanonicalSymbol(price.Base, stableQuoteKey)
+		if existing, ok := merged[key]; ok && existing.QuoteVolume.GreaterThanOrEqual(price.QuoteVolume) {
+			continue
+		}
+		merged[key] = price
+	}
+	return merged
+}
+
+// Sources of the stablecoin exchange rates used with -treat-stables-equal.
+const (
+	// StableRatesParity treats every stablecoin as worth exactly one dollar.
+	StableRatesParity = "parity"
+	// StableRatesMarket converts prices with the stablecoins' own markets
+	// against USDT, as returned by StableRates.
+	StableRatesMarket = "market"
+)
+
+// stableRateReference is the stablecoin StableRates values the others in.
+const stableRateReference = "USDT"
+
+// StableRates returns what one unit of each stablecoin is worth in USDT:
+// the mid price of its market against USDT, averaged over the exchanges in
+// pairs that list one. USDT itself is worth 1. Stablecoins without a market
+// against USDT are missing.
+func StableRates(pairs map[string]map[string]ExchangePrice) map[string]decimal.Decimal {
+	sums := make(map[string]decimal.Decimal)
+	counts := make(map[string]int64)
+	two := decimal.NewFromInt(2)
+	for _, exchangePairs := range pairs {
+		for _, price := range exchangePairs {
+			if !stableQuoteAssets[price.Base] || !stableQuoteAssets[price.Quote] || price.Base == price.Quote {
+				continue
+			}
+			if !price.BidPrice.IsPositive() || !price.AskPrice.IsPositive() {
+				continue
+			}
+			mid := price.BidPrice.Add(price.AskPrice).DivRound(two, DivisionPrecision)
+			switch stableRateReference {
+			case price.Quote:
+				sums[price.Base] = sums[price.Base].Add(mid)
+				counts[price.Base]++
+			case price.Base:
+				sums[price.Quote] = sums[price.Quote].Add(decimal.NewFromInt(1).DivRound(mid, DivisionPrecision))
+				counts[price.Quote]++
+			}
+		}
+	}
+	rates := map[string]decimal.Decimal{stableRateReference: decimal.NewFromInt(1)}
+	for asset, sum := range sums {
+		rates[asset] = sum.DivRound(decimal.NewFromInt(counts[asset]), DivisionPrecision)
+	}
+	return rates
+}
+
+// ConvertStableQuotes re-prices every pair quoted in a stablecoin in USDT at
+// the given rates, so markets in different stablecoins merged by
+// MergeStableQuotes are compared at market rates instead of at parity. The
+// pairs keep their real quote asset. Pairs whose stablecoin has no rate, and
+// those whose base is a stablecoin, are left unchanged.
+func ConvertStableQuotes(pairs map[string]ExchangePrice, rates map[string]decimal.Decimal) map[string]ExchangePrice {
+	converted := make(map[string]ExchangePrice, len(pairs))
+	for key, price := range pairs {
+		if rate, ok := stableQuoteRate(price, rates); ok {
+			price.BidPrice = price.BidPrice.Mul(rate)
+			price.AskPrice = price.AskPrice.Mul(rate)
+			price.TickSize = price.TickSize.Mul(rate)
+		}
+		converted[key] = price
+	}
+	return converted
+}
+
+// stableQuoteRate returns the rate ConvertStableQuotes re-prices price at.
+// ok is false for pairs it leaves unchanged.
+func stableQuoteRate(price ExchangePrice, rates map[string]decimal.Decimal) (rate decimal.Decimal, ok bool) {
+	rate, ok = rates[price.Quote]
+	return rate, ok && stableQuoteAssets[price.Quote] && !stableQuoteAssets[price.Base]
+}
@@ -0,0 +1,73 @@
+package arbitrage
+
+import "testing"
+
+func TestMergeStableQuotes(t *testing.T) {
+	pairs := map[string]ExchangePrice{
+		"BTC/USDT":  {Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT", QuoteVolume: mustDecimal(t, "1000")},
+		"BTC/USDC":  {Symbol: "BTCUSDC", Base: "BTC", Quote: "USDC", QuoteVolume: mustDecimal(t, "10")},
+		"ETH/BTC":   {Symbol: "ETHBTC", Base: "ETH", Quote: "BTC"},
+		"USDC/USDT": {Symbol: "USDCUSDT", Base: "USDC", Quote: "USDT"},
+	}
+
+	merged := MergeStableQuotes(pairs)
+	if len(merged) != 3 {
+		t.Fatalf("got %d pairs, want 3: %v", len(merged), merged)
+	}
+	if got := merged["BTC/USD*"].Symbol; got != "BTCUSDT" {
+		t.Errorf("BTC/USD* = %s, want the higher-volume BTCUSDT", got)
+	}
+	if _, ok := merged["ETH/BTC"]; !ok {
+		t.Error("ETH/BTC should keep its key")
+	}
+	if _, ok := merged["USDC/USDT"]; !ok {
+		t.Error("USDC/USDT should keep its key")
+	}
+}
+
+func TestStableRates(t *testing.T) {
+	price := func(base, quote, bid, ask string) ExchangePrice {
+		return ExchangePrice{Base: base, Quote: quote, BidPrice: mustDecimal(t, bid), AskPrice: mustDecimal(t, ask)}
+	}
+	pairs := map[string]map[string]ExchangePrice{
+		"A": {"USDC/USDT": price("USDC", "USDT", "0.998", "1"), "BTC/USDT": price("BTC", "USDT", "60000", "60001")},
+		"B": {"USDC/USDT": price("USDC", "USDT", "0.996", "0.998"), "USDT/DAI": price("USDT", "DAI", "1.25", "1.25")},
+	}
+
+	rates := StableRates(pairs)
+	want := map[string]string{"USDT": "1", "USDC": "0.998", "DAI": "0.8"}
+	if len(rates) != len(want) {
+		t.Fatalf("rates = %v, want %v", rates, want)
+	}
+	for asset, rate := range want {
+		if !rates[asset].Equal(mustDecimal(t, rate)) {
+			t.Errorf("%s rate = %s, want %s", asset, rates[asset], rate)
+		}
+	}
+
+	converted := ConvertStableQuotes(map[string]ExchangePrice{
+		"BTC/USDC": price("BTC", "USDC", "60000", "60010"),
+		"BTC/USD":  price("BTC", "USD", "60000", "60010"),
+	}, rates)
+	assertPrice(t, converted, "BTC/USDC", "", "59880", "59889.98")
+	// USD has no market against USDT here, so it stays at parity.
+	assertPrice(t, converted, "BTC/USD", "", "60000", "60010")
+	if converted["BTC/USDC"].Quote != "USDC" {
+		t.Errorf("converted quote = %s, want the real USDC", converted["BTC/USDC"].Quote)
+	}
+}
+
+func TestComputeOpportunityMarksCrossQuotes(t *testing.T) {
+	buy := ExchangePrice{Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, "99"), AskPrice: mustDecimal(t, "100")}
+	sell := ExchangePrice{Base: "BTC", Quote: "USDC", BidPrice: mustDecimal(t, "105"), AskPrice: mustDecimal(t, "106")}
+	minProfit, maxProfit := mustDecimal(t, "0.01"), mustDecimal(t, "0.5")
+
+	got, ok, _ := ComputeOpportunity("BTC/USD*", "A", "B", buy, sell, nil, minProfit, maxProfit)
+	if !ok || got.Quote != "USDT" || got.SellQuote != "USDC" {
+		t.Errorf("ok = %v, quote %q, sell quote %q; want USDT and USDC", ok, got.Quote, got.SellQuote)
+	}
+	sell.Quote = "USDT"
+	if got, _, _ := ComputeOpportunity("BTC/USD*", "A", "B", buy, sell, nil, minProfit, maxProfit); got.SellQuote != "" {
+		t.Errorf("sell quote = %q for the same quote, want none", got.SellQuote)
+	}
+}
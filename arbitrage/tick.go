@@ -0,0 +1,22 @@
+package arbitrage
+
+import "github.com/shopspring/decimal"
+
+// coarserTick returns the larger of two tick sizes. An unknown (zero) tick
+// size is ignored, so zero is only returned when both are unknown.
+func coarserTick(a, b decimal.Decimal) decimal.Decimal {
+	return decimal.Max(a, b, decimal.Zero)
+}
+
+// tickRoundedPrices returns the ask of buy and the bid of sell rounded to
+// the coarser of the two markets' tick sizes: the ask up and the bid down,
+// so the rounding never adds profit. A spread finer than the coarser tick
+// can't be captured, since one of the two orders can't be priced inside it.
+func tickRoundedPrices(buy, sell ExchangePrice) (ask, bid decimal.Decimal) {
+	ask, bid = buy.AskPrice, sell.BidPrice
+	tick := coarserTick(buy.TickSize, sell.TickSize)
+	if !tick.IsPositive() {
+		return ask, bid
+	}
+	return ask.DivRound(tick, DivisionPrecision).Ceil().Mul(tick), bid.DivRound(tick, DivisionPrecision).Floor().Mul(tick)
+}
@@ -0,0 +1,41 @@
+package arbitrage
+
+import "testing"
+
+func TestTickRoundedPrices(t *testing.T) {
+	for _, test := range []struct {
+		buyTick, sellTick, wantAsk, wantBid string
+	}{
+		// The ask rounds up and the bid down to the coarser 0.1 tick.
+		{"0.01", "0.1", "100.1", "101"},
+		{"0.1", "0.01", "100.1", "101"},
+		// An unknown tick size defers to the other market's.
+		{"0", "0.1", "100.1", "101"},
+		{"0", "0", "100.01", "101.04"},
+	} {
+		buy := ExchangePrice{AskPrice: mustDecimal(t, "100.01"), TickSize: mustDecimal(t, test.buyTick)}
+		sell := ExchangePrice{BidPrice: mustDecimal(t, "101.04"), TickSize: mustDecimal(t, test.sellTick)}
+		ask, bid := tickRoundedPrices(buy, sell)
+		if !ask.Equal(mustDecimal(t, test.wantAsk)) || !bid.Equal(mustDecimal(t, test.wantBid)) {
+			t.Errorf("ticks %s/%s: ask %s, bid %s; want %s, %s", test.buyTick, test.sellTick, ask, bid, test.wantAsk, test.wantBid)
+		}
+	}
+}
+
+func TestComputeOpportunityRoundsToTick(t *testing.T) {
+	fees := map[string]ExchangeFees{"A": {}, "B": {}}
+	buy := ExchangePrice{AskPrice: mustDecimal(t, "100.01"), TickSize: mustDecimal(t, "0.01")}
+	sell := ExchangePrice{BidPrice: mustDecimal(t, "101.04"), TickSize: mustDecimal(t, "0.1")}
+
+	// 1.03% at the raw prices, but only 0.9% once both are on the 0.1 tick.
+	if _, ok, _ := ComputeOpportunity("X/USDT", "A", "B", buy, sell, fees, mustDecimal(t, "0.01"), mustDecimal(t, "0.5")); ok {
+		t.Error("the spread only clears 1% below the coarser tick, want it dropped")
+	}
+	opportunity, ok, _ := ComputeOpportunity("X/USDT", "A", "B", buy, sell, fees, mustDecimal(t, "0.005"), mustDecimal(t, "0.5"))
+	if !ok {
+		t.Fatal("want the 0.9% spread kept at a 0.5% threshold")
+	}
+	if !opportunity.BuyPrice.Equal(mustDecimal(t, "100.1")) || !opportunity.SellPrice.Equal(mustDecimal(t, "101")) {
+		t.Errorf("prices buy %s, sell %s; want 100.1 and 101", opportunity.BuyPrice, opportunity.SellPrice)
+	}
+}
@@ -0,0 +1,52 @@
+package arbitrage
+
+import (
+	"sort"
+	"sync"
+)
+
+// MaxWatchlistSymbols is the longest watchlist that is fetched symbol by
+// symbol. Past it the bulk ticker endpoints are cheaper than one request per
+// symbol, so everything is fetched and the symbol filter does the rest.
+const MaxWatchlistSymbols = 10
+
+// Watchlist, if set, lists the only markets Binance and Bybit fetch, by
+// their exchange symbol such as BTCUSDT. Their per-symbol ticker endpoints
+// are queried instead of the full ticker lists, which cuts the payload and
+// latency of each cycle when only a handful of symbols is watched.
+var Watchlist []string
+
+// Watchlist returns the whitelisted symbols if there are few enough of them
+// to fetch one by one, and nil otherwise.
+func (f SymbolFilter) Watchlist() []string {
+	if len(f.whitelist) == 0 || len(f.whitelist) > MaxWatchlistSymbols {
+		return nil
+	}
+	symbols := make([]string, 0, len(f.whitelist))
+	for symbol := range f.whitelist {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// forEachWatched calls fetch for every symbol in Watchlist at once and
+// returns the first error.
+func forEachWatched(fetch func(symbol string) error) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(Watchlist))
+	for i, symbol := range Watchlist {
+		wg.Add(1)
+		go func(i int, symbol string) {
+			defer wg.Done()
+			errs[i] = fetch(symbol)
+		}(i, symbol)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package arbitrage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newQueryTestServer serves canned bodies keyed by request path and query,
+// so a test can tell per-symbol requests from bulk ones. Any other request
+// fails the test.
+func newQueryTestServer(t *testing.T, responses map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path+"?"+r.URL.RawQuery]
+		if !ok {
+			t.Errorf("unexpected request to %s", r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		if body == `{"code":-1121,"msg":"Invalid symbol."}` {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func withWatchlist(t *testing.T, symbols ...string) {
+	t.Helper()
+	old := Watchlist
+	t.Cleanup(func() { Watchlist = old })
+	Watchlist = symbols
+}
+
+func TestGetBinancePairsWatchlist(t *testing.T) {
+	server := newQueryTestServer(t, map[string]string{
+		"/api/v3/exchangeInfo?symbol=BTCUSDT":                   `{"symbols":[{"symbol":"BTCUSDT","status":"TRADING","baseAsset":"BTC","quoteAsset":"USDT"}]}`,
+		"/api/v3/exchangeInfo?symbol=NOPEUSDT":                  `{"code":-1121,"msg":"Invalid symbol."}`,
+		"/api/v3/ticker/bookTicker?symbols=%5B%22BTCUSDT%22%5D": `[{"symbol":"BTCUSDT","bidPrice":"60000","askPrice":"60001"}]`,
+		"/api/v3/ticker/24hr?symbols=%5B%22BTCUSDT%22%5D":       `[{"symbol":"BTCUSDT","quoteVolume":"1000"}]`,
+		"/api/v3/time?": `{"serverTime":1700000000000}`,
+	})
+	defer func(old string) { BinanceBaseURL = old }(BinanceBaseURL)
+	BinanceBaseURL = server.URL
+	withWatchlist(t, "BTCUSDT", "NOPEUSDT")
+
+	pairs, _, err := getBinancePairs(context.Background())
+	if err != nil {
+		t.Fatalf("getBinancePairs: %v", err)
+	}
+	if len(pairs) != 1 {
+		t.Fatalf("got %d pairs, want 1: %v", len(pairs), pairs)
+	}
+	assertPrice(t, pairs, "BTC/USDT", "BTCUSDT", "60000", "60001")
+	if got := pairs["BTC/USDT"].QuoteVolume; !got.Equal(mustDecimal(t, "1000")) {
+		t.Errorf("BTC/USDT quote volume = %s, want 1000", got)
+	}
+}
+
+func TestGetBybitPairsWatchlist(t *testing.T) {
+	server := newQueryTestServer(t, map[string]string{
+		"/v5/market/instruments-info?category=spot": `{"result":{"list":[
+			{"symbol":"BTCUSDT","baseCoin":"BTC","quoteCoin":"USDT","status":"Trading"},
+			{"symbol":"ETHUSDT","baseCoin":"ETH","quoteCoin":"USDT","status":"Trading"}
+		]}}`,
+		"/v5/market/tickers?category=spot&symbol=BTCUSDT": `{"time":1700000000123,"result":{"list":[
+			{"symbol":"BTCUSDT","bid1Price":"60000.5","ask1Price":"60001","turnover24h":"1000"}
+		]}}`,
+	})
+	defer func(old string) { BybitBaseURL = old }(BybitBaseURL)
+	BybitBaseURL = server.URL
+	withWatchlist(t, "BTCUSDT", "NOPEUSDT")
+
+	pairs, _, err := getBybitPairs(context.Background())
+	if err != nil {
+		t.Fatalf("getBybitPairs: %v", err)
+	}
+	if len(pairs) != 1 {
+		t.Fatalf("got %d pairs, want 1: %v", len(pairs), pairs)
+	}
+	assertPrice(t, pairs, "BTC/USDT", "BTCUSDT", "60000.5", "60001")
+}
+
+func TestSymbolFilterWatchlist(t *testing.T) {
+	filter, err := NewSymbolFilter([]string{"eth/usdt", "BTC-USDT"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(filter.Watchlist()); got != "[BTCUSDT ETHUSDT]" {
+		t.Errorf("watchlist = %s, want [BTCUSDT ETHUSDT]", got)
+	}
+
+	var many []string
+	for i := 0; i <= MaxWatchlistSymbols; i++ {
+		many = append(many, fmt.Sprintf("COIN%dUSDT", i))
+	}
+	if filter, err = NewSymbolFilter(many, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := filter.Watchlist(); got != nil {
+		t.Errorf("watchlist of %d symbols = %v, want bulk fetching", len(many), got)
+	}
+}
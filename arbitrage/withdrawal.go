@@ -0,0 +1,85 @@
+package arbitrage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+
+	"github.com/shopspring/decimal"
+)
+
+// WithdrawalFees holds the fixed fee each exchange charges for withdrawing an
+// asset, keyed by exchange name and then by canonical asset code, e.g.
+//
+//	{"Binance": {"BTC": "0.0002", "USDT": "1"}, "Bybit": {"BTC": "0.0003"}}
+type WithdrawalFees map[string]map[string]decimal.Decimal
+
+func LoadWithdrawalFees(path string) (WithdrawalFees, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading withdrawal fees: %v", err)
+	}
+
+	var raw WithdrawalFees
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling withdrawal fees: %v", err)
+	}
+
+	fees := make(WithdrawalFees, len(raw))
+	for exchange, assets := range raw {
+		fees[exchange] = make(map[string]decimal.Decimal, len(assets))
+		for asset, fee := range assets {
+			fees[exchange][CanonicalAsset(asset)] = fee
+		}
+	}
+	return fees, nil
+}
+
+func (w WithdrawalFees) lookup(exchange, asset string) (decimal.Decimal, bool) {
+	fee, ok := w[exchange][asset]
+	return fee, ok
+}
+
+// ApplyWithdrawalFees charges every opportunity for the round trip of moving
+// the bought base asset from the buying exchange to the selling exchange and
+// the quote proceeds back again. Opportunities without fee data for either
+// asset are kept but flagged TransferCostUnknown; those whose profit falls
+// below minProfit (a fraction) once the fees are paid are dropped. The
+// opportunities must already have an Amount applied.
+func ApplyWithdrawalFees(opportunities []ArbitrageOpportunity, fees WithdrawalFees, minProfit decimal.Decimal) []ArbitrageOpportunity {
+	kept := make([]ArbitrageOpportunity, 0, len(opportunities))
+	for _, opportunity := range opportunities {
+		baseFee, baseKnown := fees.lookup(opportunity.BuyExchange, opportunity.Base)
+		quoteFee, quoteKnown := fees.lookup(opportunity.SellExchange, opportunity.Quote)
+		if !baseKnown || !quoteKnown {
+			opportunity.TransferCostUnknown = true
+			kept = append(kept, opportunity)
+			continue
+		}
+
+		arrived := opportunity.BaseQuantity.Sub(baseFee)
+		if !arrived.IsPositive() {
+			slog.Info("Dropping opportunity whose withdrawal fee exceeds the quantity bought", "symbol", opportunity.Symbol,
+				"buy_exchange", opportunity.BuyExchange, "sell_exchange", opportunity.SellExchange)
+			continue
+		}
+
+		cost := opportunity.BaseQuantity.Mul(opportunity.BuyPrice)
+		proceeds := arrived.Mul(opportunity.SellPrice).Sub(quoteFee)
+		opportunity.TransferCost = opportunity.Proceeds.Sub(proceeds)
+		opportunity.Proceeds = proceeds
+		opportunity.NetProfit = proceeds.Sub(cost)
+
+		profit, ok := profitFraction(cost, proceeds)
+		if !ok || profit.LessThan(minProfit) {
+			slog.Info("Dropping opportunity below the threshold after withdrawal fees", "symbol", opportunity.Symbol,
+				"buy_exchange", opportunity.BuyExchange, "sell_exchange", opportunity.SellExchange,
+				"profit_pct", profit.Mul(hundred).StringFixed(2))
+			continue
+		}
+		kept = append(kept, withRoundTrip(withProfit(opportunity, profit)))
+	}
+	return kept
+}
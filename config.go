@@ -0,0 +1,638 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+	"github.com/shopspring/decimal"
+)
+
+// Config gathers every tunable setting. Values come from, in increasing
+// order of precedence, defaultConfig, the file given with -config and the
+// command-line flags.
+type Config struct {
+	// MinProfit and MaxProfit are percentages (1 is 1%).
+	MinProfit float64 `json:"min_profit"`
+	MaxProfit float64 `json:"max_profit"`
+
+	Fees map[string]arbitrage.ExchangeFees `json:"fees"`
+
+	// Exchanges enables or disables exchanges by name. Exchanges that are
+	// missing from the map are enabled.
+	Exchanges map[string]bool `json:"exchanges"`
+
+	// Whitelist and Blacklist restrict the compared symbols. Each holds
+	// symbols or the path of a file listing them.
+	Whitelist arbitrage.StringList `json:"whitelist"`
+	Blacklist arbitrage.StringList `json:"blacklist"`
+	// Bases and Quotes restrict the compared symbols to these base and
+	// quote assets, whatever the other side of the pair.
+	Bases  arbitrage.StringList `json:"base"`
+	Quotes arbitrage.StringList `json:"quote"`
+
+	// SymbolMap is the path of a JSON file of per-exchange asset aliases
+	// and of assets never to match across exchanges.
+	SymbolMap string `json:"symbol_map"`
+
+	// MinPairs is the number of pairs below which an exchange's response is
+	// treated as broken: a warning is logged, or the cycle is aborted if
+	// AbortOnFewPairs is set.
+	MinPairs        int  `json:"min_pairs"`
+	AbortOnFewPairs bool `json:"abort_on_few_pairs"`
+
+	// MinVolume is the minimum 24h volume in quote currency a pair needs
+	// on every exchange to be compared.
+	MinVolume float64 `json:"min_volume"`
+
+	// MinPrice is the minimum bid and ask a pair needs on every exchange to
+	// be compared. 0 compares pairs at any price.
+	MinPrice float64 `json:"min_price"`
+
+	// MinAge excludes markets listed more recently than this, on exchanges
+	// that report a listing time. 0 keeps new listings.
+	MinAge arbitrage.Duration `json:"min_age"`
+
+	// MaxUpdateAge excludes markets whose prices were last updated longer
+	// ago than this, on exchanges that report update times. 0 keeps them.
+	MaxUpdateAge arbitrage.Duration `json:"max_update_age"`
+
+	// TreatStablesEqual compares markets quoted in different dollar
+	// stablecoins (and USD) as if they had the same quote currency.
+	TreatStablesEqual bool `json:"treat_stables_equal"`
+	// StableRates is what the stablecoins are assumed to be worth against
+	// each other: "parity" or "market" rates.
+	StableRates string `json:"stable_rates"`
+
+	// BinanceWS and BybitWS stream that exchange's prices over WebSocket
+	// instead of polling them every cycle.
+	BinanceWS bool `json:"binance_ws"`
+	BybitWS   bool `json:"bybit_ws"`
+
+	// BybitCategory is the Bybit market scanned: spot, linear or inverse.
+	BybitCategory string `json:"bybit_category"`
+
+	// BinanceRegion is the Binance deployment scanned: global or us.
+	BinanceRegion string `json:"binance_region"`
+
+	// InstrumentsTTL is how long the Bybit instruments list is reused before
+	// it is fetched again. 0 fetches it every cycle.
+	InstrumentsTTL arbitrage.Duration `json:"instruments_ttl"`
+
+	// MaxSkew is how far apart two exchanges' prices may have been taken
+	// before an opportunity between them is flagged as potentially stale.
+	MaxSkew arbitrage.Duration `json:"max_skew"`
+
+	// Interval is the polling period; 0 runs a single cycle, as does Once
+	// regardless of Interval.
+	Interval arbitrage.Duration `json:"interval"`
+	Once     bool               `json:"once"`
+	Timeout  arbitrage.Duration `json:"timeout"`
+	Retries  int                `json:"retries"`
+
+	// SelfTest fetches every exchange once, reports whether their data
+	// looks usable and exits instead of comparing.
+	SelfTest bool `json:"selftest"`
+
+	// BaseURLs and StreamURLs override the REST and WebSocket endpoints of
+	// exchanges by name, for example to use a testnet. The Binance stream
+	// URL is the full book ticker endpoint, the Bybit one the host only.
+	BaseURLs   urlMap `json:"base_urls"`
+	StreamURLs urlMap `json:"stream_urls"`
+
+	// RequestRate and RequestBurst pace the requests to each API host, in
+	// requests per second. A RequestRate of 0 disables pacing.
+	RequestRate  float64 `json:"request_rate"`
+	RequestBurst int     `json:"request_burst"`
+
+	// Proxy is an http, https or socks5 URL that every exchange request is
+	// sent through. Empty uses HTTP_PROXY and HTTPS_PROXY, if set.
+	Proxy string `json:"proxy"`
+
+	// UserAgent is sent with every request, exchange or notification.
+	// Headers are extra headers sent to the exchanges, such as an API key
+	// header a proxy in front of them requires.
+	UserAgent string    `json:"user_agent"`
+	Headers   headerMap `json:"headers"`
+
+	// Top limits the printed opportunities to the most profitable ones.
+	// 0 prints all of them.
+	Top int `json:"top"`
+
+	// LogLevel is the least severe log level written: debug, info, warn or
+	// error.
+	LogLevel string `json:"log_level"`
+
+	// Verbose prints sample comparisons with their best spread when a cycle
+	// finds no opportunities.
+	Verbose bool `json:"verbose"`
+	// NearMissBand is how many percentage points below MinProfit a spread
+	// may be to be shown as a near miss with Verbose.
+	NearMissBand float64 `json:"near_miss_band"`
+
+	// SummaryByQuote ends every cycle's output with the number of
+	// opportunities and the best one for each quote currency.
+	SummaryByQuote bool `json:"summary_by_quote"`
+
+	// TUI redraws a live table of the current opportunities every cycle
+	// instead of printing them, with the logs shown below it.
+	TUI bool `json:"tui"`
+
+	// Stats prints statistics of every opportunity found since the program
+	// started when polling stops, and every StatsInterval if that is set.
+	Stats         bool               `json:"stats"`
+	StatsInterval arbitrage.Duration `json:"stats_interval"`
+
+	// Precision is the number of decimals prices are printed with in text
+	// output and alerts when the market's tick size is unknown.
+	// PercentPrecision is the number of decimals of profit percentages.
+	Precision        int `json:"precision"`
+	PercentPrecision int `json:"percent_precision"`
+
+	// Output is the format opportunities are printed in: text, json or
+	// csv. They go to OutFile, or to stdout if it is empty.
+	Output         string  `json:"output"`
+	OutFile        string  `json:"out_file"`
+	Amount         float64 `json:"amount"`
+	TradeSize      float64 `json:"trade_size"`
+	WithdrawalFees string  `json:"withdrawal_fees"`
+
+	// OutBuffer is the number of bytes of output held in memory before
+	// writing to OutFile, which is also written at the end of every cycle.
+	// OutRotate, if set, is the size or age at which OutFile is renamed
+	// aside and a new one started.
+	OutBuffer int    `json:"out_buffer"`
+	OutRotate string `json:"out_rotate"`
+
+	// FormatTemplate, if set, replaces the text output of every
+	// opportunity. It is a text/template or the path of a file holding one.
+	FormatTemplate string `json:"format_template"`
+
+	// SmoothWindow, if 2 or more, compares exponential moving averages of
+	// each market's bid and ask over about that many cycles rather than
+	// the latest prices.
+	SmoothWindow int `json:"smooth_window"`
+
+	// Confirm re-fetches both legs of every opportunity before reporting
+	// it and drops those whose spread didn't persist.
+	Confirm bool `json:"confirm"`
+
+	// Concurrency is how many opportunities are depth-checked or confirmed
+	// at the same time.
+	Concurrency int `json:"concurrency"`
+
+	// SlippageModel is how fills are expected to slip from the quoted
+	// prices: "flat" worsens each leg by SlippageBps, "depth" walks the order
+	// books for TradeSize.
+	SlippageModel string  `json:"slippage_model"`
+	SlippageBps   float64 `json:"slippage_bps"`
+
+	// Funding also reports spot-vs-perpetual basis trades whose funding
+	// yields at least MinFundingAPR percent a year.
+	Funding       bool    `json:"funding"`
+	MinFundingAPR float64 `json:"min_funding_apr"`
+
+	// MakerLeg is the leg executed as a limit order at maker fees: "none",
+	// "buy", "sell" or "both".
+	MakerLeg string `json:"maker_leg"`
+
+	// RankBy is what opportunities are ranked on: percent, absolute or
+	// achievable.
+	RankBy string `json:"rank_by"`
+
+	// BestDirectionOnly reports only the most profitable pair of exchanges
+	// for each symbol rather than every one that qualifies.
+	BestDirectionOnly bool `json:"best_direction_only"`
+
+	// IncludeLeveraged keeps leveraged tokens such as Binance's BTCUP and
+	// Bybit's BTC3L, which are excluded by default.
+	IncludeLeveraged bool `json:"include_leveraged"`
+
+	// DB is the path of an SQLite database that every reported opportunity
+	// is recorded in. Empty disables recording.
+	DB string `json:"db"`
+
+	// SimulateTrade is the path of a ledger file that a hypothetical trade
+	// of Amount is appended to for every reported opportunity. Empty
+	// disables paper trading.
+	SimulateTrade string `json:"simulate_trade"`
+
+	// KafkaBrokers is a comma-separated list of Kafka brokers that every
+	// opportunity found is published to, on KafkaTopic. Empty disables it.
+	KafkaBrokers string `json:"kafka_brokers"`
+	KafkaTopic   string `json:"kafka_topic"`
+
+	// TelegramToken and TelegramChatID enable a summary message to a
+	// Telegram chat for every cycle that finds opportunities.
+	TelegramToken  string `json:"telegram_token"`
+	TelegramChatID string `json:"telegram_chat_id"`
+	// WebhookURL receives every cycle's opportunities as a JSON array.
+	// SlackWebhookURL and DiscordWebhookURL are incoming webhooks that
+	// receive the same summary message as Telegram.
+	WebhookURL        string `json:"webhook_url"`
+	SlackWebhookURL   string `json:"slack_webhook_url"`
+	DiscordWebhookURL string `json:"discord_webhook_url"`
+
+	// AlertCooldown suppresses an opportunity, in printed output and
+	// alerts, for this long after it was reported unless its profit moved by
+	// at least AlertProfitChange percentage points. 0 reports it every cycle.
+	AlertCooldown     arbitrage.Duration `json:"alert_cooldown"`
+	AlertProfitChange float64            `json:"alert_profit_change"`
+
+	// MetricsAddr is the address to serve Prometheus metrics on, such as
+	// ":9090". Empty disables the metrics server.
+	MetricsAddr string `json:"metrics_addr"`
+
+	// APIAddr is the address to serve the latest cycle's opportunities and
+	// pairs on as JSON, such as ":8080". Empty disables the API.
+	APIAddr string `json:"api_addr"`
+
+	// HealthAddr is the address to serve the /health endpoint on. It
+	// reports unhealthy when an exchange hasn't been fetched successfully
+	// within HealthMaxAge.
+	HealthAddr   string             `json:"health_addr"`
+	HealthMaxAge arbitrage.Duration `json:"health_max_age"`
+
+	// Record is a directory that a snapshot of the fetched prices is written
+	// to every cycle. Replay is a directory of such snapshots to run the
+	// comparison against instead of the live exchanges.
+	Record string `json:"record"`
+	Replay string `json:"replay"`
+
+	// DumpDir is a directory every raw exchange response is written to
+	// before it is parsed. Empty disables dumping.
+	DumpDir string `json:"dump_dir"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		MinProfit: minProfitPercentage * 100,
+		MaxProfit: maxProfitPercentage * 100,
+		Fees:      arbitrage.DefaultExchangeFees(),
+		Exchanges: map[string]bool{},
+
+		BybitCategory:  arbitrage.BybitCategorySpot,
+		BinanceRegion:  arbitrage.BinanceRegionGlobal,
+		UserAgent:      arbitrage.DefaultUserAgent,
+		InstrumentsTTL: arbitrage.Duration(arbitrage.DefaultInstrumentsTTL),
+		MaxSkew:        arbitrage.Duration(arbitrage.DefaultMaxSkew),
+		MinPairs:       defaultMinPairs,
+		Timeout:        arbitrage.Duration(arbitrage.DefaultHTTPTimeout),
+		HealthMaxAge:   arbitrage.Duration(defaultHealthMaxAge),
+		Retries:        arbitrage.DefaultMaxRetries,
+		RequestRate:    arbitrage.DefaultRequestRate,
+		RequestBurst:   arbitrage.DefaultRequestBurst,
+		Concurrency:    arbitrage.DefaultConcurrency,
+		Output:         "text",
+		OutBuffer:      defaultOutBuffer,
+		LogLevel:       "info",
+		SlippageModel:  arbitrage.SlippageModelFlat,
+		MakerLeg:       arbitrage.MakerLegNone,
+		RankBy:         arbitrage.RankByPercent,
+		StableRates:    arbitrage.StableRatesParity,
+
+		BestDirectionOnly: true,
+
+		Precision:        defaultPricePrecision,
+		PercentPrecision: defaultPercentPrecision,
+
+		AlertProfitChange: defaultAlertProfitChange,
+		NearMissBand:      defaultNearMissBand,
+	}
+}
+
+// registerFlags binds the command-line flags to the fields of cfg, using the
+// current field values as defaults.
+func (cfg *Config) registerFlags(fs *flag.FlagSet) {
+	fs.Float64Var(&cfg.MinProfit, "min-profit", cfg.MinProfit, "minimum profit percentage to report an opportunity")
+	fs.Float64Var(&cfg.MaxProfit, "max-profit", cfg.MaxProfit, "profit percentage above which an opportunity is discarded as bad data")
+	fs.StringVar(&cfg.Output, "output", cfg.Output, "output format: text, json or csv")
+	fs.StringVar(&cfg.FormatTemplate, "format-template", cfg.FormatTemplate, "Go text/template, or a file holding one, to print each opportunity with in text output")
+	fs.StringVar(&cfg.OutFile, "out-file", cfg.OutFile, "write the opportunities to this file instead of stdout")
+	fs.IntVar(&cfg.OutBuffer, "out-buffer", cfg.OutBuffer, "bytes of output to buffer before writing to -out-file, which is also written at the end of every cycle; 0 writes straight through")
+	fs.StringVar(&cfg.OutRotate, "out-rotate", cfg.OutRotate, "rotate -out-file once it reaches a size such as 100MB or an age such as 24h")
+	fs.IntVar(&cfg.Precision, "precision", cfg.Precision, "decimals to print prices with when the exchange doesn't report a tick size")
+	fs.IntVar(&cfg.PercentPrecision, "percent-precision", cfg.PercentPrecision, "decimals to print profit percentages with")
+	fs.IntVar(&cfg.Top, "top", cfg.Top, "only print the N most profitable opportunities; 0 prints all")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "least severe log level to write: debug, info, warn or error")
+	fs.BoolVar(&cfg.Verbose, "verbose", cfg.Verbose, "print sample comparisons with their best spread when no opportunities are found, and near misses")
+	fs.Float64Var(&cfg.NearMissBand, "near-miss-band", cfg.NearMissBand, "with -verbose, show spreads up to this many percentage points below -min-profit with their break-even fee")
+	fs.BoolVar(&cfg.SummaryByQuote, "summary-by-quote", cfg.SummaryByQuote, "end each cycle with the opportunity count and best opportunity per quote currency")
+	fs.BoolVar(&cfg.TUI, "tui", cfg.TUI, "show a live, color-coded table of the current opportunities instead of scrolling output (requires -interval)")
+	fs.BoolVar(&cfg.Stats, "stats", cfg.Stats, "print statistics of the opportunities found over the whole session on shutdown")
+	fs.Var(&cfg.StatsInterval, "stats-interval", "also print the session statistics this often, e.g. 1h")
+	fs.Var(&cfg.Whitelist, "whitelist", "only compare these symbols (comma-separated, or a file path); takes precedence over -blacklist")
+	fs.Var(&cfg.Blacklist, "blacklist", "never compare these symbols (comma-separated, or a file path)")
+	fs.Var(&cfg.Bases, "base", "only compare pairs with these base assets (comma-separated, e.g. BTC,ETH), across every quote")
+	fs.Var(&cfg.Quotes, "quote", "only compare pairs with these quote assets (comma-separated, e.g. USDT,USDC)")
+	fs.StringVar(&cfg.SymbolMap, "symbol-map", cfg.SymbolMap, "JSON file of per-exchange asset aliases and assets never to match across exchanges")
+	fs.IntVar(&cfg.MinPairs, "min-pairs", cfg.MinPairs, "warn when an exchange returns fewer pairs than this")
+	fs.BoolVar(&cfg.AbortOnFewPairs, "abort-on-few-pairs", cfg.AbortOnFewPairs, "abort the cycle instead of warning when an exchange returns fewer than -min-pairs pairs")
+	fs.Float64Var(&cfg.MinVolume, "min-volume", cfg.MinVolume, "minimum 24h quote volume a pair needs on each exchange to be compared")
+	fs.Float64Var(&cfg.MinPrice, "min-price", cfg.MinPrice, "minimum bid and ask a pair needs on each exchange to be compared")
+	fs.Var(&cfg.MinAge, "min-age", "skip markets listed more recently than this, e.g. 168h; only Bybit reports listing times")
+	fs.Var(&cfg.MaxUpdateAge, "max-update-age", "skip markets whose prices haven't updated for longer than this, e.g. 5m; known for OKX, Coinbase and the -binance-ws and -bybit-ws streams")
+	fs.BoolVar(&cfg.TreatStablesEqual, "treat-stables-equal", cfg.TreatStablesEqual, "compare markets quoted in USD, USDT, USDC and other dollar stablecoins as the same symbol")
+	fs.StringVar(&cfg.StableRates, "stable-rates", cfg.StableRates, "with -treat-stables-equal, value the stablecoins at parity or at the market rates of their pairs against USDT")
+	fs.BoolVar(&cfg.BinanceWS, "binance-ws", cfg.BinanceWS, "stream Binance book tickers over WebSocket, falling back to REST when the stream is down")
+	fs.BoolVar(&cfg.BybitWS, "bybit-ws", cfg.BybitWS, "stream Bybit tickers over WebSocket, falling back to REST when the stream is down")
+	fs.StringVar(&cfg.BybitCategory, "bybit-category", cfg.BybitCategory, "Bybit market to scan: spot, linear or inverse")
+	fs.StringVar(&cfg.BinanceRegion, "binance-region", cfg.BinanceRegion, "Binance deployment to scan: global (binance.com) or us (binance.us)")
+	fs.Var(&cfg.Interval, "interval", "poll continuously at this interval (e.g. 30s); 0 runs once")
+	fs.BoolVar(&cfg.Once, "once", cfg.Once, "run a single cycle and exit, even if an interval is configured")
+	fs.BoolVar(&cfg.SelfTest, "selftest", cfg.SelfTest, "fetch every exchange once, report pair counts, shared symbols and sample prices, and exit non-zero if anything looks wrong")
+	fs.Var(&cfg.MaxSkew, "max-skew", "flag opportunities whose two prices were taken further apart than this as potentially stale")
+	fs.Var(&cfg.Timeout, "timeout", "timeout for each HTTP request to an exchange")
+	fs.Float64Var(&cfg.Amount, "amount", cfg.Amount, "stake in quote currency used to report the absolute profit of each opportunity")
+	fs.StringVar(&cfg.WithdrawalFees, "withdrawal-fees", cfg.WithdrawalFees, "JSON file of per-exchange, per-asset withdrawal fees to include in the profit (requires -amount)")
+	fs.Float64Var(&cfg.TradeSize, "trade-size", cfg.TradeSize, "trade size in quote currency to check against order book depth; 0 disables depth checks")
+	fs.IntVar(&cfg.SmoothWindow, "smooth-window", cfg.SmoothWindow, "compare moving averages of each market's bid and ask over about N cycles instead of the latest prices; 0 disables smoothing")
+	fs.BoolVar(&cfg.Confirm, "confirm", cfg.Confirm, "re-fetch both legs of every opportunity and only report it if the spread persists")
+	fs.IntVar(&cfg.Concurrency, "concurrency", cfg.Concurrency, "number of opportunities to depth-check or confirm at the same time")
+	fs.StringVar(&cfg.SlippageModel, "slippage-model", cfg.SlippageModel, "slippage model: flat (-slippage-bps on each leg) or depth (order book fills for -trade-size)")
+	fs.Float64Var(&cfg.SlippageBps, "slippage-bps", cfg.SlippageBps, "slippage in basis points charged on each leg by the flat model")
+	fs.BoolVar(&cfg.Funding, "funding", cfg.Funding, "also report basis trades between spot markets and Bybit and Binance perpetuals that pay funding")
+	fs.Float64Var(&cfg.MinFundingAPR, "min-funding-apr", cfg.MinFundingAPR, "minimum annualized funding percentage to report a basis trade")
+	fs.StringVar(&cfg.MakerLeg, "maker-leg", cfg.MakerLeg, "legs priced at maker fees as limit orders: none, buy, sell or both")
+	fs.StringVar(&cfg.RankBy, "rank-by", cfg.RankBy, "rank opportunities by percent profit, absolute net profit on -amount, or achievable profit over the profitable book depth, which requires -trade-size")
+	fs.BoolVar(&cfg.BestDirectionOnly, "best-direction-only", cfg.BestDirectionOnly, "report only the most profitable pair of exchanges for each symbol; false reports every pair whose spread qualifies")
+	fs.BoolVar(&cfg.IncludeLeveraged, "include-leveraged", cfg.IncludeLeveraged, "keep leveraged tokens such as Binance's BTCUP and Bybit's BTC3L, which never track the spot price of their underlying")
+	fs.StringVar(&cfg.DB, "db", cfg.DB, "path of an SQLite database to record opportunities in")
+	fs.StringVar(&cfg.SimulateTrade, "simulate-trade", cfg.SimulateTrade, "path of a JSON Lines ledger to paper-trade every reported opportunity into, with its fees and cumulative PnL (requires -amount)")
+	fs.StringVar(&cfg.KafkaBrokers, "kafka-brokers", cfg.KafkaBrokers, "publish every opportunity to these Kafka brokers (comma-separated host:port)")
+	fs.StringVar(&cfg.KafkaTopic, "kafka-topic", cfg.KafkaTopic, "Kafka topic for -kafka-brokers")
+	fs.StringVar(&cfg.TelegramToken, "telegram-token", cfg.TelegramToken, "Telegram bot token for opportunity alerts")
+	fs.StringVar(&cfg.TelegramChatID, "telegram-chat-id", cfg.TelegramChatID, "Telegram chat ID for opportunity alerts")
+	fs.StringVar(&cfg.WebhookURL, "webhook-url", cfg.WebhookURL, "POST every cycle's opportunities as JSON to this URL")
+	fs.StringVar(&cfg.SlackWebhookURL, "slack-webhook-url", cfg.SlackWebhookURL, "Slack incoming webhook URL for opportunity alerts")
+	fs.StringVar(&cfg.DiscordWebhookURL, "discord-webhook-url", cfg.DiscordWebhookURL, "Discord webhook URL for opportunity alerts")
+	fs.Var(&cfg.AlertCooldown, "alert-cooldown", "don't report the same opportunity again for this long (e.g. 10m) unless its profit changes by -alert-profit-change")
+	fs.Float64Var(&cfg.AlertProfitChange, "alert-profit-change", cfg.AlertProfitChange, "change in profit percentage points that reports an opportunity again during -alert-cooldown")
+	fs.StringVar(&cfg.MetricsAddr, "metrics-addr", cfg.MetricsAddr, "address to serve Prometheus metrics on (e.g. :9090)")
+	fs.StringVar(&cfg.Record, "record", cfg.Record, "directory to write a price snapshot to every cycle, for use with -replay")
+	fs.StringVar(&cfg.Replay, "replay", cfg.Replay, "directory of recorded price snapshots to replay instead of querying the exchanges")
+	fs.StringVar(&cfg.DumpDir, "dump-dir", cfg.DumpDir, "directory to write every raw exchange response to, before it is parsed, for debugging")
+	fs.StringVar(&cfg.APIAddr, "api-addr", cfg.APIAddr, "address to serve the latest opportunities and pairs on as JSON (e.g. :8080)")
+	fs.StringVar(&cfg.HealthAddr, "health-addr", cfg.HealthAddr, "address to serve a /health endpoint on (e.g. :8081)")
+	fs.Var(&cfg.HealthMaxAge, "health-max-age", "report unhealthy when an exchange hasn't been fetched successfully for this long")
+	fs.Var(&cfg.InstrumentsTTL, "instruments-ttl", "how long to reuse the Bybit instruments list before fetching it again; 0 fetches it every cycle")
+	fs.IntVar(&cfg.Retries, "retries", cfg.Retries, "number of times to retry a request after a network error or 5xx response")
+	fs.Var(&cfg.BaseURLs, "base-url", "override an exchange's REST endpoint as Name=URL, e.g. Bybit=https://api-testnet.bybit.com; repeatable")
+	fs.Var(&cfg.StreamURLs, "stream-url", "override an exchange's WebSocket endpoint as Name=URL; repeatable")
+	fs.Var(exchangeSelection{cfg.Exchanges}, "exchanges", "only query these exchanges (comma-separated, e.g. Bybit,Kraken,OKX)")
+	for _, name := range exchangeNames {
+		fs.Var(exchangeToggle{cfg.Exchanges, name}, strings.ToLower(name), "query "+name+"; -"+strings.ToLower(name)+"=false skips it")
+	}
+	fs.StringVar(&cfg.Proxy, "proxy", cfg.Proxy, "send exchange requests through this proxy, e.g. socks5://127.0.0.1:1080; defaults to HTTP_PROXY/HTTPS_PROXY")
+	fs.StringVar(&cfg.UserAgent, "user-agent", cfg.UserAgent, "User-Agent header sent with every request; empty sends Go's default")
+	fs.Var(&cfg.Headers, "header", "extra header sent to the exchanges as \"Name: value\"; repeatable")
+	fs.Float64Var(&cfg.RequestRate, "request-rate", cfg.RequestRate, "requests per second allowed to each API host, reduced automatically after a 429; 0 disables pacing")
+	fs.IntVar(&cfg.RequestBurst, "request-burst", cfg.RequestBurst, "requests that may be sent to an API host at once before -request-rate applies")
+}
+
+// loadConfigFile overlays the settings in a JSON config file onto cfg.
+// Settings the file doesn't mention keep their current values.
+func loadConfigFile(path string, cfg *Config) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+	err = json.Unmarshal(data, cfg)
+	if err != nil {
+		return fmt.Errorf("error unmarshalling config file: %v", err)
+	}
+	return nil
+}
+
+// parseConfig builds the configuration from the defaults, the optional
+// -config file and the command-line arguments.
+func parseConfig(fs *flag.FlagSet, args []string) (Config, error) {
+	cfg := defaultConfig()
+	cfg.BaseURLs = endpointsFromEnv(restURLs, "BASE")
+	cfg.StreamURLs = endpointsFromEnv(streamURLs, "STREAM")
+	configPath := fs.String("config", "", "path to a JSON config file; command-line flags override its settings")
+	cfg.registerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	if *configPath != "" {
+		if err := loadConfigFile(*configPath, &cfg); err != nil {
+			return Config{}, err
+		}
+		// Parse again so flags given explicitly win over the file.
+		if err := fs.Parse(args); err != nil {
+			return Config{}, err
+		}
+	}
+
+	return cfg, cfg.validate()
+}
+
+func (cfg Config) validate() error {
+	switch cfg.Output {
+	case "text", "json", "csv":
+	default:
+		return fmt.Errorf("unknown output format %q", cfg.Output)
+	}
+	if cfg.Precision < 0 || cfg.Precision > arbitrage.DivisionPrecision {
+		return fmt.Errorf("-precision must be between 0 and %d", arbitrage.DivisionPrecision)
+	}
+	if cfg.PercentPrecision < 0 || cfg.PercentPrecision > arbitrage.DivisionPrecision {
+		return fmt.Errorf("-percent-precision must be between 0 and %d", arbitrage.DivisionPrecision)
+	}
+	if _, ok := logLevels[cfg.LogLevel]; !ok {
+		return fmt.Errorf("unknown log level %q", cfg.LogLevel)
+	}
+	switch cfg.BybitCategory {
+	case arbitrage.BybitCategorySpot, arbitrage.BybitCategoryLinear, arbitrage.BybitCategoryInverse:
+	default:
+		return fmt.Errorf("unknown Bybit category %q", cfg.BybitCategory)
+	}
+	if !arbitrage.IsBinanceRegion(cfg.BinanceRegion) {
+		return fmt.Errorf("unknown Binance region %q", cfg.BinanceRegion)
+	}
+	if err := validateExchanges(cfg.Exchanges); err != nil {
+		return err
+	}
+	if err := validateEndpoints(cfg.BaseURLs, restURLs, "base-url", "https", "http"); err != nil {
+		return err
+	}
+	if err := validateEndpoints(cfg.StreamURLs, streamURLs, "stream-url", "wss", "ws"); err != nil {
+		return err
+	}
+	if err := validateHeaders(cfg.Headers); err != nil {
+		return err
+	}
+	if cfg.Proxy != "" {
+		parsed, err := url.Parse(cfg.Proxy)
+		if err != nil || parsed.Host == "" {
+			return fmt.Errorf("-proxy: invalid URL %q", cfg.Proxy)
+		}
+		switch parsed.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("-proxy: URL must use http, https or socks5")
+		}
+	}
+	if cfg.RequestRate < 0 {
+		return fmt.Errorf("-request-rate cannot be negative")
+	}
+	if cfg.SmoothWindow < 0 {
+		return fmt.Errorf("-smooth-window cannot be negative")
+	}
+	if cfg.Concurrency < 1 {
+		return fmt.Errorf("-concurrency must be at least 1")
+	}
+	if cfg.RequestRate > 0 && cfg.RequestBurst < 1 {
+		return fmt.Errorf("-request-burst must be at least 1")
+	}
+	if (cfg.TelegramToken == "") != (cfg.TelegramChatID == "") {
+		return fmt.Errorf("-telegram-token and -telegram-chat-id must be set together")
+	}
+	for _, webhook := range []struct{ flag, url string }{
+		{"-webhook-url", cfg.WebhookURL},
+		{"-slack-webhook-url", cfg.SlackWebhookURL},
+		{"-discord-webhook-url", cfg.DiscordWebhookURL},
+	} {
+		if webhook.url == "" {
+			continue
+		}
+		parsed, err := url.Parse(webhook.url)
+		if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			// The URL is usually a secret, so it isn't echoed back.
+			return fmt.Errorf("%s: must be an http or https URL", webhook.flag)
+		}
+	}
+	if cfg.OutBuffer < 0 {
+		return fmt.Errorf("-out-buffer cannot be negative")
+	}
+	if cfg.OutRotate != "" {
+		if cfg.OutFile == "" {
+			return fmt.Errorf("-out-rotate requires -out-file")
+		}
+		if _, err := parseOutRotation(cfg.OutRotate); err != nil {
+			return err
+		}
+	}
+	if cfg.FormatTemplate != "" && (cfg.Output != "text" || cfg.TUI) {
+		return fmt.Errorf("-format-template only applies to -output text without -tui")
+	}
+	if cfg.TUI {
+		switch {
+		case cfg.Interval <= 0 || cfg.Once:
+			return fmt.Errorf("-tui requires -interval, since it redraws every cycle")
+		case cfg.Output != "text" || cfg.OutFile != "":
+			return fmt.Errorf("-tui draws on stdout and cannot be combined with -output or -out-file")
+		case cfg.Replay != "":
+			return fmt.Errorf("-tui cannot be combined with -replay")
+		case cfg.StatsInterval > 0:
+			return fmt.Errorf("-stats-interval would print over -tui; use -stats to print them on shutdown")
+		}
+	}
+	if cfg.StatsInterval < 0 {
+		return fmt.Errorf("-stats-interval cannot be negative")
+	}
+	if cfg.NearMissBand < 0 {
+		return fmt.Errorf("-near-miss-band cannot be negative")
+	}
+	if (len(cfg.kafkaBrokers()) == 0) != (cfg.KafkaTopic == "") {
+		return fmt.Errorf("-kafka-brokers and -kafka-topic must be set together")
+	}
+	if cfg.AlertCooldown < 0 {
+		return fmt.Errorf("-alert-cooldown cannot be negative")
+	}
+	if cfg.AlertProfitChange < 0 {
+		return fmt.Errorf("-alert-profit-change cannot be negative")
+	}
+	switch cfg.StableRates {
+	case arbitrage.StableRatesParity:
+	case arbitrage.StableRatesMarket:
+		if !cfg.TreatStablesEqual {
+			return fmt.Errorf("-stable-rates market requires -treat-stables-equal")
+		}
+	default:
+		return fmt.Errorf("unknown stablecoin rates %q: want parity or market", cfg.StableRates)
+	}
+	if cfg.SimulateTrade != "" && cfg.Amount <= 0 {
+		return fmt.Errorf("-simulate-trade requires -amount, the stake of every simulated trade")
+	}
+	if cfg.WithdrawalFees != "" && cfg.Amount <= 0 {
+		return fmt.Errorf("-withdrawal-fees requires -amount, since withdrawal fees are fixed amounts")
+	}
+	switch cfg.SlippageModel {
+	case arbitrage.SlippageModelFlat:
+		if cfg.SlippageBps < 0 {
+			return fmt.Errorf("-slippage-bps cannot be negative")
+		}
+	case arbitrage.SlippageModelDepth:
+		if cfg.TradeSize <= 0 {
+			return fmt.Errorf("-slippage-model depth requires -trade-size")
+		}
+		if cfg.SlippageBps != 0 {
+			return fmt.Errorf("-slippage-bps only applies to the flat slippage model")
+		}
+	default:
+		return fmt.Errorf("unknown slippage model %q", cfg.SlippageModel)
+	}
+	if cfg.Funding && cfg.Output == "csv" {
+		return fmt.Errorf("-funding basis trades can only be printed as text or json")
+	}
+	if cfg.MinFundingAPR < 0 {
+		return fmt.Errorf("-min-funding-apr cannot be negative")
+	}
+	switch cfg.MakerLeg {
+	case arbitrage.MakerLegNone, arbitrage.MakerLegBuy, arbitrage.MakerLegSell, arbitrage.MakerLegBoth:
+	default:
+		return fmt.Errorf("unknown maker leg %q: want none, buy, sell or both", cfg.MakerLeg)
+	}
+	switch cfg.RankBy {
+	case arbitrage.RankByPercent:
+	case arbitrage.RankByAbsolute:
+		if cfg.Amount <= 0 {
+			return fmt.Errorf("-rank-by absolute requires -amount")
+		}
+	case arbitrage.RankByAchievable:
+		if cfg.TradeSize <= 0 {
+			return fmt.Errorf("-rank-by achievable requires -trade-size, since the book depth comes from the order books")
+		}
+	default:
+		return fmt.Errorf("unknown ranking %q: want percent, absolute or achievable", cfg.RankBy)
+	}
+	if cfg.SelfTest && cfg.Replay != "" {
+		return fmt.Errorf("-selftest checks the live exchanges and cannot be combined with -replay")
+	}
+	if cfg.Record != "" && cfg.Replay != "" {
+		return fmt.Errorf("-record and -replay cannot be used together")
+	}
+	return nil
+}
+
+// exchangeEnabled reports whether name should be queried.
+func (cfg Config) exchangeEnabled(name string) bool {
+	enabled, ok := cfg.Exchanges[name]
+	return !ok || enabled
+}
+
+// kafkaBrokers returns the brokers listed in KafkaBrokers.
+func (cfg Config) kafkaBrokers() []string {
+	var brokers []string
+	for _, broker := range strings.Split(cfg.KafkaBrokers, ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			brokers = append(brokers, broker)
+		}
+	}
+	return brokers
+}
+
+func (cfg Config) minProfitFraction() decimal.Decimal {
+	return decimal.NewFromFloat(cfg.MinProfit).Div(decimal.NewFromInt(100))
+}
+
+func (cfg Config) maxProfitFraction() decimal.Decimal {
+	return decimal.NewFromFloat(cfg.MaxProfit).Div(decimal.NewFromInt(100))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+	"github.com/shopspring/decimal"
+)
+
+// defaultAlertProfitChange is how many percentage points an opportunity's
+// profit has to move before it is reported again within the cooldown.
+const defaultAlertProfitChange = 0.5
+
+// alertCooldown suppresses opportunities that were already reported
+// recently, so a spread that persists over many polling cycles is printed and
+// alerted about once rather than every cycle.
+type alertCooldown struct {
+	window time.Duration
+	// minChange is the profit change, in percentage points, that makes a
+	// suppressed opportunity worth reporting again.
+	minChange decimal.Decimal
+	reported  map[string]reportedOpportunity
+}
+
+// reportedOpportunity is when an opportunity was last reported and the
+// profit it had then.
+type reportedOpportunity struct {
+	at     time.Time
+	profit decimal.Decimal
+}
+
+func newAlertCooldown(window time.Duration, minChange decimal.Decimal) *alertCooldown {
+	return &alertCooldown{window: window, minChange: minChange, reported: make(map[string]reportedOpportunity)}
+}
+
+// filter returns the opportunities that haven't been reported within the
+// window, or whose profit moved by at least minChange since they were. The
+// order of opportunities is kept.
+func (c *alertCooldown) filter(opportunities []arbitrage.ArbitrageOpportunity, now time.Time) []arbitrage.ArbitrageOpportunity {
+	for key, last := range c.reported {
+		if now.Sub(last.at) >= c.window {
+			delete(c.reported, key)
+		}
+	}
+
+	kept := make([]arbitrage.ArbitrageOpportunity, 0, len(opportunities))
+	for _, opportunity := range opportunities {
+		// The direction is part of the key: the reverse trade is a
+		// different opportunity.
+		key := opportunity.Symbol + "|" + opportunity.BuyExchange + "|" + opportunity.SellExchange
+		if last, ok := c.reported[key]; ok && opportunity.ProfitPercentage.Sub(last.profit).Abs().LessThan(c.minChange) {
+			continue
+		}
+		c.reported[key] = reportedOpportunity{at: now, profit: opportunity.ProfitPercentage}
+		kept = append(kept, opportunity)
+	}
+	return kept
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+func TestAlertCooldown(t *testing.T) {
+	opportunity := func(buy, sell, profit string) arbitrage.ArbitrageOpportunity {
+		return arbitrage.ArbitrageOpportunity{Symbol: "BTC/USDT", BuyExchange: buy, SellExchange: sell, ProfitPercentage: mustDecimal(t, profit)}
+	}
+	cooldown := newAlertCooldown(10*time.Minute, mustDecimal(t, "0.5"))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		after         time.Duration
+		opportunities []arbitrage.ArbitrageOpportunity
+		want          int
+	}{
+		{0, []arbitrage.ArbitrageOpportunity{opportunity("A", "B", "2")}, 1},
+		// Same spread a cycle later: suppressed.
+		{time.Minute, []arbitrage.ArbitrageOpportunity{opportunity("A", "B", "2.3")}, 0},
+		// The reverse direction is a different opportunity.
+		{2 * time.Minute, []arbitrage.ArbitrageOpportunity{opportunity("A", "B", "2.2"), opportunity("B", "A", "1.5")}, 1},
+		// A material change is reported again, measured from the last
+		// reported profit.
+		{3 * time.Minute, []arbitrage.ArbitrageOpportunity{opportunity("A", "B", "2.6")}, 1},
+		{4 * time.Minute, []arbitrage.ArbitrageOpportunity{opportunity("A", "B", "2.4")}, 0},
+		// Once the cooldown from the last report has passed, it is
+		// reported again.
+		{13 * time.Minute, []arbitrage.ArbitrageOpportunity{opportunity("A", "B", "2.6")}, 1},
+	}
+	for i, step := range steps {
+		got := cooldown.filter(step.opportunities, start.Add(step.after))
+		if len(got) != step.want {
+			t.Errorf("step %d: %d opportunities reported, want %d: %+v", i, len(got), step.want, got)
+		}
+	}
+}
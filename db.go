@@ -0,0 +1,93 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+	_ "modernc.org/sqlite"
+)
+
+const opportunitySchema = `
+CREATE TABLE IF NOT EXISTS opportunities (
+	id                INTEGER PRIMARY KEY AUTOINCREMENT,
+	detected_at       TEXT NOT NULL,
+	symbol            TEXT NOT NULL,
+	base              TEXT NOT NULL,
+	quote             TEXT NOT NULL,
+	buy_exchange      TEXT NOT NULL,
+	sell_exchange     TEXT NOT NULL,
+	buy_price         TEXT NOT NULL,
+	sell_price        TEXT NOT NULL,
+	profit_percentage TEXT NOT NULL,
+	amount            TEXT NOT NULL,
+	net_profit        TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS opportunities_symbol ON opportunities (symbol, detected_at);
+`
+
+// opportunityDB records opportunities in an SQLite database. Decimals are
+// stored as text so no precision is lost.
+type opportunityDB struct {
+	db *sql.DB
+}
+
+// openOpportunityDB opens, or creates, the database at path and makes sure
+// the schema exists.
+func openOpportunityDB(path string) (*opportunityDB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %v", err)
+	}
+	if _, err := db.Exec(opportunitySchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating database schema: %v", err)
+	}
+	return &opportunityDB{db: db}, nil
+}
+
+// save inserts the opportunities of one cycle, all stamped with detectedAt.
+func (o *opportunityDB) save(opportunities []arbitrage.ArbitrageOpportunity, detectedAt time.Time) error {
+	if len(opportunities) == 0 {
+		return nil
+	}
+
+	tx, err := o.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	stmt, err := tx.Prepare(`INSERT INTO opportunities
+		(detected_at, symbol, base, quote, buy_exchange, sell_exchange, buy_price, sell_price, profit_percentage, amount, net_profit)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error preparing insert: %v", err)
+	}
+	defer stmt.Close()
+
+	timestamp := detectedAt.UTC().Format(time.RFC3339Nano)
+	for _, opportunity := range opportunities {
+		_, err := stmt.Exec(timestamp, opportunity.Symbol, opportunity.Base, opportunity.Quote,
+			opportunity.BuyExchange, opportunity.SellExchange,
+			opportunity.BuyPrice.String(), opportunity.SellPrice.String(), opportunity.ProfitPercentage.String(),
+			opportunity.Amount.String(), opportunity.NetProfit.String())
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error inserting opportunity: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing opportunities: %v", err)
+	}
+	return nil
+}
+
+// Close closes the database. It is safe to call on a nil *opportunityDB.
+func (o *opportunityDB) Close() error {
+	if o == nil {
+		return nil
+	}
+	return o.db.Close()
+}
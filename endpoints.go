@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+// restURLs and streamURLs map exchange names to the variables holding their
+// endpoints, so configuration can point them at a testnet or sandbox.
+var (
+	restURLs = map[string]*string{
+		arbitrage.ExchangeBybit:    &arbitrage.BybitBaseURL,
+		arbitrage.ExchangeBinance:  &arbitrage.BinanceBaseURL,
+		arbitrage.ExchangeKraken:   &arbitrage.KrakenBaseURL,
+		arbitrage.ExchangeOKX:      &arbitrage.OKXBaseURL,
+		arbitrage.ExchangeKuCoin:   &arbitrage.KuCoinBaseURL,
+		arbitrage.ExchangeCoinbase: &arbitrage.CoinbaseBaseURL,
+
+		arbitrage.BinanceFutures: &arbitrage.BinanceFuturesBaseURL,
+	}
+	streamURLs = map[string]*string{
+		arbitrage.ExchangeBybit:   &arbitrage.BybitStreamBaseURL,
+		arbitrage.ExchangeBinance: &arbitrage.BinanceStreamURL,
+	}
+)
+
+// urlMap holds endpoint overrides keyed by exchange name. On the command line
+// each override is written as Name=URL, and the flag may be repeated.
+type urlMap map[string]string
+
+func (m urlMap) String() string {
+	entries := make([]string, 0, len(m))
+	for name, endpoint := range m {
+		entries = append(entries, name+"="+endpoint)
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+// Set implements flag.Value.
+func (m *urlMap) Set(value string) error {
+	name, endpoint, ok := strings.Cut(value, "=")
+	if !ok || name == "" || endpoint == "" {
+		return fmt.Errorf("expected Name=URL, got %q", value)
+	}
+	if *m == nil {
+		*m = urlMap{}
+	}
+	(*m)[name] = endpoint
+	return nil
+}
+
+// endpointEnv returns the environment variable that overrides an exchange's
+// endpoint, such as ARB_BYBIT_BASE_URL or ARB_BINANCE_STREAM_URL.
+func endpointEnv(name, kind string) string {
+	return "ARB_" + strings.ToUpper(name) + "_" + kind + "_URL"
+}
+
+// endpointsFromEnv collects the endpoint overrides set in the environment.
+func endpointsFromEnv(vars map[string]*string, kind string) urlMap {
+	overrides := urlMap{}
+	for name := range vars {
+		if endpoint := os.Getenv(endpointEnv(name, kind)); endpoint != "" {
+			overrides[name] = endpoint
+		}
+	}
+	return overrides
+}
+
+// validateEndpoints checks that every override names a known exchange and
+// is an absolute URL with one of the given schemes.
+func validateEndpoints(overrides urlMap, vars map[string]*string, flagName string, schemes ...string) error {
+	for name, endpoint := range overrides {
+		if _, ok := vars[name]; !ok {
+			return fmt.Errorf("-%s: unknown exchange %q", flagName, name)
+		}
+		parsed, err := url.Parse(endpoint)
+		if err != nil || parsed.Host == "" {
+			return fmt.Errorf("-%s: invalid URL %q for %s", flagName, endpoint, name)
+		}
+		known := false
+		for _, scheme := range schemes {
+			known = known || parsed.Scheme == scheme
+		}
+		if !known {
+			return fmt.Errorf("-%s: %s URL must use %s", flagName, name, strings.Join(schemes, " or "))
+		}
+	}
+	return nil
+}
+
+// applyEndpoints points the fetchers and streams at the configured endpoints.
+func applyEndpoints(cfg Config) {
+	for name, endpoint := range cfg.BaseURLs {
+		*restURLs[name] = strings.TrimSuffix(endpoint, "/")
+	}
+	for name, endpoint := range cfg.StreamURLs {
+		*streamURLs[name] = strings.TrimSuffix(endpoint, "/")
+	}
+}
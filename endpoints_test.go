@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+func TestEndpointOverrides(t *testing.T) {
+	t.Setenv("ARB_BYBIT_BASE_URL", "https://api-testnet.bybit.com")
+	t.Setenv("ARB_BINANCE_BASE_URL", "https://env.example.com")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	cfg, err := parseConfig(fs, []string{
+		"-base-url", "Binance=https://testnet.binance.vision/",
+		"-stream-url", "Binance=wss://testnet.binance.vision/ws/!bookTicker",
+	})
+	if err != nil {
+		t.Fatalf("parseConfig: %v", err)
+	}
+	if got := cfg.BaseURLs[arbitrage.ExchangeBybit]; got != "https://api-testnet.bybit.com" {
+		t.Errorf("Bybit base URL from the environment = %q", got)
+	}
+	if got := cfg.BaseURLs[arbitrage.ExchangeBinance]; got != "https://testnet.binance.vision/" {
+		t.Errorf("the flag should win over the environment, got %q", got)
+	}
+
+	defer func(rest, stream, bybit string) {
+		arbitrage.BinanceBaseURL, arbitrage.BinanceStreamURL, arbitrage.BybitBaseURL = rest, stream, bybit
+	}(arbitrage.BinanceBaseURL, arbitrage.BinanceStreamURL, arbitrage.BybitBaseURL)
+	applyEndpoints(cfg)
+	if arbitrage.BinanceBaseURL != "https://testnet.binance.vision" {
+		t.Errorf("arbitrage.BinanceBaseURL = %q, want the trailing slash trimmed", arbitrage.BinanceBaseURL)
+	}
+	if arbitrage.BinanceStreamURL != "wss://testnet.binance.vision/ws/!bookTicker" {
+		t.Errorf("arbitrage.BinanceStreamURL = %q", arbitrage.BinanceStreamURL)
+	}
+}
+
+func TestEndpointValidation(t *testing.T) {
+	for _, args := range [][]string{
+		{"-base-url", "Nowhere=https://example.com"},
+		{"-base-url", "Bybit=api-testnet.bybit.com"},
+		{"-base-url", "Bybit=wss://stream.bybit.com"},
+		{"-stream-url", "Kraken=wss://ws.kraken.com"},
+		{"-base-url", "Bybit"},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		if _, err := parseConfig(fs, args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+// exchangeNames lists every supported exchange in the order they are
+// queried.
+var exchangeNames = []string{
+	arbitrage.ExchangeBybit,
+	arbitrage.ExchangeBinance,
+	arbitrage.ExchangeKraken,
+	arbitrage.ExchangeOKX,
+	arbitrage.ExchangeKuCoin,
+	arbitrage.ExchangeCoinbase,
+}
+
+// lookupExchange returns the exchange called name, ignoring case.
+func lookupExchange(name string) (string, bool) {
+	for _, known := range exchangeNames {
+		if strings.EqualFold(known, name) {
+			return known, true
+		}
+	}
+	return "", false
+}
+
+// exchangeSelection is the -exchanges flag: it enables the listed exchanges
+// in Config.Exchanges and disables all others.
+type exchangeSelection struct {
+	exchanges map[string]bool
+}
+
+func (s exchangeSelection) String() string {
+	if s.exchanges == nil {
+		return ""
+	}
+	var enabled []string
+	for _, name := range exchangeNames {
+		if enabled, ok := s.exchanges[name]; ok && !enabled {
+			continue
+		}
+		enabled = append(enabled, name)
+	}
+	return strings.Join(enabled, ",")
+}
+
+// Set implements flag.Value.
+func (s exchangeSelection) Set(value string) error {
+	listed := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, ok := lookupExchange(entry)
+		if !ok {
+			return fmt.Errorf("unknown exchange %q, expected one of %s", entry, strings.Join(exchangeNames, ", "))
+		}
+		listed[name] = true
+	}
+	for _, name := range exchangeNames {
+		s.exchanges[name] = listed[name]
+	}
+	return nil
+}
+
+// exchangeToggle is a boolean flag such as -kraken=false that enables or
+// disables one exchange in Config.Exchanges.
+type exchangeToggle struct {
+	exchanges map[string]bool
+	name      string
+}
+
+func (t exchangeToggle) String() string {
+	if t.exchanges == nil {
+		return "true"
+	}
+	enabled, ok := t.exchanges[t.name]
+	return strconv.FormatBool(!ok || enabled)
+}
+
+// Set implements flag.Value.
+func (t exchangeToggle) Set(value string) error {
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	t.exchanges[t.name] = enabled
+	return nil
+}
+
+// IsBoolFlag lets the flag be given without a value, like a flag.Bool.
+func (exchangeToggle) IsBoolFlag() bool { return true }
+
+// validateExchanges checks that the exchanges setting only names known
+// exchanges, spelled as they are in exchangeNames, and leaves at least two
+// enabled, since an arbitrage needs two venues.
+func validateExchanges(exchanges map[string]bool) error {
+	for name := range exchanges {
+		if known, ok := lookupExchange(name); !ok || known != name {
+			return fmt.Errorf("unknown exchange %q, expected one of %s", name, strings.Join(exchangeNames, ", "))
+		}
+	}
+	var enabled []string
+	for _, name := range exchangeNames {
+		if on, ok := exchanges[name]; !ok || on {
+			enabled = append(enabled, name)
+		}
+	}
+	if len(enabled) < 2 {
+		return fmt.Errorf("arbitrage needs at least two exchanges, but only %d are enabled %v; check -exchanges and the exchanges setting", len(enabled), enabled)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+func TestExchangeFlags(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := ioutil.WriteFile(configPath, []byte(`{"exchanges": {"Kraken": false}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		args    []string
+		enabled []string
+	}{
+		{nil, exchangeNames},
+		{[]string{"-exchanges", "bybit, Kraken,OKX"}, []string{arbitrage.ExchangeBybit, arbitrage.ExchangeKraken, arbitrage.ExchangeOKX}},
+		{[]string{"-coinbase=false", "-kucoin=false"}, []string{arbitrage.ExchangeBybit, arbitrage.ExchangeBinance, arbitrage.ExchangeKraken, arbitrage.ExchangeOKX}},
+		// Flags apply in order, so a toggle can adjust a selection.
+		{[]string{"-exchanges", "Bybit,Binance", "-okx"}, []string{arbitrage.ExchangeBybit, arbitrage.ExchangeBinance, arbitrage.ExchangeOKX}},
+		// Flags win over the config file.
+		{[]string{"-config", configPath}, []string{arbitrage.ExchangeBybit, arbitrage.ExchangeBinance, arbitrage.ExchangeOKX, arbitrage.ExchangeKuCoin, arbitrage.ExchangeCoinbase}},
+		{[]string{"-config", configPath, "-kraken"}, exchangeNames},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		cfg, err := parseConfig(fs, test.args)
+		if err != nil {
+			t.Errorf("%v: %v", test.args, err)
+			continue
+		}
+		var enabled []string
+		for _, name := range exchangeNames {
+			if cfg.exchangeEnabled(name) {
+				enabled = append(enabled, name)
+			}
+		}
+		if len(enabled) != len(test.enabled) {
+			t.Errorf("%v: enabled %v, want %v", test.args, enabled, test.enabled)
+			continue
+		}
+		for i := range enabled {
+			if enabled[i] != test.enabled[i] {
+				t.Errorf("%v: enabled %v, want %v", test.args, enabled, test.enabled)
+				break
+			}
+		}
+	}
+}
+
+func TestExchangeFlagValidation(t *testing.T) {
+	for _, args := range [][]string{
+		{"-exchanges", "Bybit"},
+		{"-exchanges", "Bybit,Nowhere"},
+		{"-exchanges", ""},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		if _, err := parseConfig(fs, args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
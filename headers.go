@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+// headerMap holds extra headers sent to the exchanges, keyed by name. On the
+// command line each header is written as "Name: value", and the flag may be
+// repeated.
+type headerMap map[string]string
+
+func (m headerMap) String() string {
+	entries := make([]string, 0, len(m))
+	for name, value := range m {
+		entries = append(entries, name+": "+value)
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+// Set implements flag.Value.
+func (m *headerMap) Set(value string) error {
+	name, headerValue, ok := strings.Cut(value, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return fmt.Errorf("expected Name: value, got %q", value)
+	}
+	if *m == nil {
+		*m = headerMap{}
+	}
+	(*m)[name] = strings.TrimSpace(headerValue)
+	return nil
+}
+
+// validateHeaders checks that every header can be sent as-is: a name without
+// spaces or separators and a value on a single line.
+func validateHeaders(headers headerMap) error {
+	for name, value := range headers {
+		if strings.ContainsAny(name, " \t\r\n:") {
+			return fmt.Errorf("-header: invalid header name %q", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("-header: value of %s spans several lines", name)
+		}
+	}
+	return nil
+}
+
+// applyRequestHeaders sets the User-Agent and extra headers sent with every
+// exchange request. An extra User-Agent header overrides userAgent.
+func applyRequestHeaders(userAgent string, headers headerMap) {
+	arbitrage.RequestHeaders = http.Header{}
+	if userAgent != "" {
+		arbitrage.RequestHeaders.Set("User-Agent", userAgent)
+	}
+	for name, value := range headers {
+		arbitrage.RequestHeaders.Set(name, value)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+func TestRequestHeadersConfig(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	cfg, err := parseConfig(fs, []string{"-user-agent", "scanner/1.0", "-header", "X-Api-Key: secret", "-header", "Accept-Language:en"})
+	if err != nil {
+		t.Fatalf("parseConfig: %v", err)
+	}
+
+	defer func(old http.Header) { arbitrage.RequestHeaders = old }(arbitrage.RequestHeaders)
+	applyRequestHeaders(cfg.UserAgent, cfg.Headers)
+	for name, want := range map[string]string{"User-Agent": "scanner/1.0", "X-Api-Key": "secret", "Accept-Language": "en"} {
+		if got := arbitrage.RequestHeaders.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	for _, args := range [][]string{{"-header", "no colon"}, {"-header", "Bad Name: value"}} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		if _, err := parseConfig(fs, args); err == nil {
+			t.Errorf("parseConfig(%q) succeeded, want an error", args)
+		}
+	}
+}
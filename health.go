@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+// defaultHealthMaxAge is how old an exchange's last successful fetch may be
+// before /health reports the scanner as unhealthy.
+const defaultHealthMaxAge = 5 * time.Minute
+
+// healthState tracks when each exchange was last fetched successfully and
+// when the last comparison finished.
+type healthState struct {
+	maxAge    time.Duration
+	exchanges []string
+	// store, when set, holds the latest cycle's pairs, whose counts the
+	// report includes.
+	store *arbitrage.Store
+
+	mu             sync.Mutex
+	lastFetch      map[string]time.Time
+	lastComparison time.Time
+}
+
+func newHealthState(exchanges []string, maxAge time.Duration) *healthState {
+	return &healthState{maxAge: maxAge, exchanges: exchanges, lastFetch: make(map[string]time.Time)}
+}
+
+func (h *healthState) recordFetch(exchange string, at time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastFetch[exchange] = at
+}
+
+func (h *healthState) recordComparison(at time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastComparison = at
+}
+
+// healthReport is the body served by /health. Times are omitted until the
+// first success.
+type healthReport struct {
+	Healthy        bool                  `json:"healthy"`
+	Exchanges      map[string]*time.Time `json:"exchanges"`
+	Stale          []string              `json:"stale,omitempty"`
+	LastComparison *time.Time            `json:"last_comparison"`
+
+	// Pairs is how many pairs of each exchange the latest cycle compared.
+	Pairs map[string]int `json:"pairs,omitempty"`
+}
+
+// report builds the health report as of now. The scanner is healthy once
+// every exchange has been fetched within maxAge.
+func (h *healthState) report(now time.Time) healthReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	report := healthReport{Healthy: true, Exchanges: make(map[string]*time.Time, len(h.exchanges))}
+	for _, exchange := range h.exchanges {
+		last, ok := h.lastFetch[exchange]
+		if ok {
+			last := last
+			report.Exchanges[exchange] = &last
+		}
+		if !ok || now.Sub(last) > h.maxAge {
+			report.Healthy = false
+			report.Stale = append(report.Stale, exchange)
+		}
+	}
+	sort.Strings(report.Stale)
+	if !h.lastComparison.IsZero() {
+		last := h.lastComparison
+		report.LastComparison = &last
+	}
+	if h.store != nil {
+		report.Pairs = h.store.PairCounts()
+	}
+	return report
+}
+
+func (h *healthState) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := h.report(time.Now())
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// startHealthServer serves the health report on addr at /health in the
+// background.
+func startHealthServer(addr string, health *healthState) {
+	mux := http.NewServeMux()
+	mux.Handle("/health", health)
+
+	go func() {
+		slog.Info("Serving health checks", "url", addr+"/health")
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			slog.Error("Health server stopped", "err", err)
+		}
+	}()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+func TestHealthState(t *testing.T) {
+	health := newHealthState([]string{"A", "B"}, time.Minute)
+
+	recorder := httptest.NewRecorder()
+	health.ServeHTTP(recorder, httptest.NewRequest("GET", "/health", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("before any fetch: status = %d, want 503", recorder.Code)
+	}
+
+	now := time.Now()
+	health.recordFetch("A", now)
+	health.recordFetch("B", now.Add(-2*time.Minute))
+	health.recordComparison(now)
+	report := health.report(now)
+	if report.Healthy || len(report.Stale) != 1 || report.Stale[0] != "B" {
+		t.Errorf("with B stale: healthy = %v, stale = %v", report.Healthy, report.Stale)
+	}
+
+	health.recordFetch("B", now)
+	recorder = httptest.NewRecorder()
+	health.ServeHTTP(recorder, httptest.NewRequest("GET", "/health", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("with fresh fetches: status = %d, want 200", recorder.Code)
+	}
+	if report := health.report(now); report.Pairs != nil {
+		t.Errorf("without a store: pairs = %v, want none", report.Pairs)
+	}
+
+	health.store = &arbitrage.Store{}
+	health.store.Update(map[string]map[string]arbitrage.ExchangePrice{
+		"A": {"BTC/USDT": {}, "ETH/USDT": {}},
+		"B": {"BTC/USDT": {}},
+	}, nil, now)
+	if report := health.report(now); report.Pairs["A"] != 2 || report.Pairs["B"] != 1 {
+		t.Errorf("pairs = %v, want A: 2, B: 1", report.Pairs)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+const (
+	// kafkaBufferSize is how many messages may wait for the brokers. When
+	// it is full, new opportunities are dropped rather than stalling the
+	// scan.
+	kafkaBufferSize = 1000
+	// kafkaBatchSize caps how many buffered messages are written at once.
+	kafkaBatchSize = 100
+	// kafkaMaxRetries is how many times a failed batch is written again
+	// before it is dropped, the first time after kafkaRetryDelay and then
+	// twice as long each time.
+	kafkaMaxRetries = 5
+	kafkaRetryDelay = 500 * time.Millisecond
+	// kafkaFlushTimeout bounds how long closing the sink waits for the
+	// buffered messages to be written.
+	kafkaFlushTimeout = 5 * time.Second
+)
+
+// kafkaMessage is one opportunity, encoded as JSON and keyed by symbol so
+// that every opportunity of a symbol lands on the same partition.
+type kafkaMessage struct {
+	Key   []byte
+	Value []byte
+	Time  time.Time
+}
+
+// kafkaWriter publishes messages to the configured topic. newKafkaWriter
+// returns one backed by segmentio/kafka-go; tests substitute a fake.
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, messages []kafkaMessage) error
+	Close() error
+}
+
+// kafkaSink publishes opportunities in the background. publish only queues
+// them, so slow or unreachable brokers never delay a cycle; the queue is
+// bounded, and failed writes are retried with exponential backoff.
+type kafkaSink struct {
+	writer   kafkaWriter
+	messages chan kafkaMessage
+	done     chan struct{}
+	// retryDelay is the wait before the first retry, doubled for each one
+	// after it.
+	retryDelay time.Duration
+	// dropped counts the messages lost to a full buffer since it was last
+	// reported.
+	dropped int
+}
+
+func newKafkaSink(writer kafkaWriter) *kafkaSink {
+	return newKafkaSinkWithBuffer(writer, kafkaBufferSize, kafkaRetryDelay)
+}
+
+func newKafkaSinkWithBuffer(writer kafkaWriter, size int, retryDelay time.Duration) *kafkaSink {
+	k := &kafkaSink{
+		writer:     writer,
+		messages:   make(chan kafkaMessage, size),
+		done:       make(chan struct{}),
+		retryDelay: retryDelay,
+	}
+	go k.run()
+	return k
+}
+
+// publish queues one message per opportunity, timestamped with when the
+// prices were fetched.
+func (k *kafkaSink) publish(opportunities []arbitrage.ArbitrageOpportunity, fetchedAt time.Time) {
+	for _, opportunity := range opportunities {
+		value, err := json.Marshal(opportunity)
+		if err != nil {
+			slog.Error("Failed to encode opportunity for Kafka", "symbol", opportunity.Symbol, "err", err)
+			continue
+		}
+		select {
+		case k.messages <- kafkaMessage{Key: []byte(opportunity.Symbol), Value: value, Time: fetchedAt}:
+		default:
+			k.dropped++
+		}
+	}
+	if k.dropped > 0 {
+		slog.Warn("Kafka buffer full, dropped opportunities", "dropped", k.dropped, "buffer", cap(k.messages))
+		k.dropped = 0
+	}
+}
+
+// run writes the queued messages in batches until the sink is closed.
+func (k *kafkaSink) run() {
+	defer close(k.done)
+	for message := range k.messages {
+		// Take whatever else is already buffered, without waiting for more.
+		batch := []kafkaMessage{message}
+	collect:
+		for len(batch) < kafkaBatchSize {
+			select {
+			case next, ok := <-k.messages:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, next)
+			default:
+				break collect
+			}
+		}
+		if err := k.write(batch); err != nil {
+			slog.Error("Failed to publish opportunities to Kafka, dropping them", "messages", len(batch), "err", err)
+		}
+	}
+}
+
+// write writes one batch, retrying up to kafkaMaxRetries times.
+func (k *kafkaSink) write(batch []kafkaMessage) error {
+	delay := k.retryDelay
+	for attempt := 0; ; attempt++ {
+		err := k.writer.WriteMessages(context.Background(), batch)
+		if err == nil {
+			return nil
+		}
+		if attempt >= kafkaMaxRetries {
+			return fmt.Errorf("error writing to Kafka after %d attempts: %v", attempt+1, err)
+		}
+		slog.Warn("Kafka write failed, retrying", "attempt", attempt+1, "delay", delay, "err", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// Close stops accepting opportunities and waits up to kafkaFlushTimeout for
+// the buffered ones to be written before closing the writer.
+func (k *kafkaSink) Close() error {
+	close(k.messages)
+	select {
+	case <-k.done:
+	case <-time.After(kafkaFlushTimeout):
+		slog.Warn("Timed out flushing opportunities to Kafka", "unsent", len(k.messages))
+	}
+	return k.writer.Close()
+}
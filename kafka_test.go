@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+// fakeKafkaWriter records the messages written to it, failing the first
+// failures writes and blocking every write while block is open.
+type fakeKafkaWriter struct {
+	mu       sync.Mutex
+	failures int
+	block    chan struct{}
+	writes   int
+	messages []kafkaMessage
+	closed   bool
+}
+
+func (w *fakeKafkaWriter) WriteMessages(ctx context.Context, messages []kafkaMessage) error {
+	if w.block != nil {
+		<-w.block
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes++
+	if w.writes <= w.failures {
+		return errors.New("broker unavailable")
+	}
+	w.messages = append(w.messages, messages...)
+	return nil
+}
+
+func (w *fakeKafkaWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return nil
+}
+
+func TestKafkaSinkPublishes(t *testing.T) {
+	writer := &fakeKafkaWriter{failures: 2}
+	sink := newKafkaSinkWithBuffer(writer, 10, time.Millisecond)
+	fetchedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	sink.publish([]arbitrage.ArbitrageOpportunity{
+		{Symbol: "BTC/USDT", BuyExchange: "A", SellExchange: "B"},
+		{Symbol: "ETH/USDT", BuyExchange: "B", SellExchange: "A"},
+	}, fetchedAt)
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !writer.closed {
+		t.Error("writer not closed")
+	}
+	// Two failed attempts are retried before the batch goes through.
+	if writer.writes < 3 || len(writer.messages) != 2 {
+		t.Fatalf("%d writes delivered %d messages, want both after the retries", writer.writes, len(writer.messages))
+	}
+	for i, symbol := range []string{"BTC/USDT", "ETH/USDT"} {
+		message := writer.messages[i]
+		var opportunity arbitrage.ArbitrageOpportunity
+		if err := json.Unmarshal(message.Value, &opportunity); err != nil {
+			t.Fatal(err)
+		}
+		if string(message.Key) != symbol || opportunity.Symbol != symbol || !message.Time.Equal(fetchedAt) {
+			t.Errorf("message %d: key %q, symbol %q at %s; want %s at %s", i, message.Key, opportunity.Symbol, message.Time, symbol, fetchedAt)
+		}
+	}
+}
+
+func TestKafkaSinkDoesNotBlock(t *testing.T) {
+	writer := &fakeKafkaWriter{block: make(chan struct{})}
+	sink := newKafkaSinkWithBuffer(writer, 2, time.Millisecond)
+
+	opportunities := make([]arbitrage.ArbitrageOpportunity, 10)
+	for i := range opportunities {
+		opportunities[i].Symbol = "BTC/USDT"
+	}
+	published := make(chan struct{})
+	go func() {
+		sink.publish(opportunities, time.Now())
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a stalled broker")
+	}
+
+	close(writer.block)
+	sink.Close()
+	// One message may have been taken by the stalled write, two more fit
+	// in the buffer; the rest are dropped.
+	if len(writer.messages) > 3 {
+		t.Errorf("delivered %d messages through a buffer of 2", len(writer.messages))
+	}
+}
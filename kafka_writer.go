@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// segmentioWriter publishes through segmentio/kafka-go.
+type segmentioWriter struct {
+	writer *kafka.Writer
+}
+
+// newKafkaWriter connects to brokers lazily: the first write dials them,
+// so an unreachable broker shows up as retried write errors.
+func newKafkaWriter(brokers []string, topic string) kafkaWriter {
+	return &segmentioWriter{writer: &kafka.Writer{
+		Addr:  kafka.TCP(brokers...),
+		Topic: topic,
+		// Hash the key so a symbol's opportunities stay in order on
+		// one partition.
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireOne,
+	}}
+}
+
+func (w *segmentioWriter) WriteMessages(ctx context.Context, messages []kafkaMessage) error {
+	converted := make([]kafka.Message, len(messages))
+	for i, message := range messages {
+		converted[i] = kafka.Message{Key: message.Key, Value: message.Value, Time: message.Time}
+	}
+	return w.writer.WriteMessages(ctx, converted...)
+}
+
+func (w *segmentioWriter) Close() error {
+	return w.writer.Close()
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+)
+
+// logLevels are the accepted values of -log-level.
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// setupLogging sends operational logs to w, normally stderr, as key=value
+// lines at level and above. Opportunities are written separately, to stdout
+// or -out-file.
+func setupLogging(w io.Writer, level string) {
+	handler := slog.NewTextHandler(w, &slog.HandlerOptions{Level: logLevels[level]})
+	slog.SetDefault(slog.New(handler))
+}
+
+// Exit codes, so scripts can branch on the outcome of a run. Invalid flags
+// also exit with exitError, as the flag package does.
+const (
+	// exitOK is returned by successful runs, except a single comparison
+	// that found no opportunities (exitNoOpportunities).
+	exitOK = 0
+	// exitNoOpportunities is returned when a single run found none.
+	exitNoOpportunities = 1
+	// exitError is returned for invalid configuration and failed runs.
+	exitError = 2
+)
+
+// fail logs msg at error level and returns exitError, for run to return.
+func fail(msg string, args ...any) int {
+	slog.Error(msg, args...)
+	return exitError
+}
@@ -1,262 +1,755 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"net/http"
-
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
 	"github.com/shopspring/decimal"
 )
 
-type ExchangePrice struct {
-	Symbol   string
-	BidPrice decimal.Decimal
-	AskPrice decimal.Decimal
-}
+const minProfitPercentage = 0.01 // Minimum 1% profit
 
-type BybitInstrumentsInfo struct {
-	Result struct {
-		List []struct {
-			Symbol    string `json:"symbol"`
-			BaseCoin  string `json:"baseCoin"`
-			QuoteCoin string `json:"quoteCoin"`
-			Status    string `json:"status"`
-		} `json:"list"`
-	} `json:"result"`
-}
+const maxProfitPercentage = 0.5 // Anything above 50% is treated as bad data
 
-type BybitTickers struct {
-	Result struct {
-		List []struct {
-			Symbol    string `json:"symbol"`
-			Bid1Price string `json:"bid1Price"`
-			Ask1Price string `json:"ask1Price"`
-		} `json:"list"`
-	} `json:"result"`
-}
+const defaultMinPairs = 10 // Fewer pairs than this from an exchange means broken data
 
-type BinanceTicker struct {
-	Symbol   string `json:"symbol"`
-	BidPrice string `json:"bidPrice"`
-	AskPrice string `json:"askPrice"`
+func main() {
+	os.Exit(run())
 }
 
-const minProfitPercentage = 0.01 // Minimum 2% profit
-const transactionFee = 0.001     // 0.1% transaction fee per exchange
-
-func main() {
-	bybitPairs, err := getBybitPairs()
+// run is the program; it returns the exit code rather than exiting itself,
+// so the deferred closes flush every output before main exits.
+func run() int {
+	cfg, err := parseConfig(flag.CommandLine, os.Args[1:])
 	if err != nil {
-		log.Fatal(err)
+		return fail("Invalid configuration", "err", err)
+	}
+	setupLogging(os.Stderr, cfg.LogLevel)
+
+	// SIGINT and SIGTERM cancel ctx, which aborts any request in flight, so
+	// the program shuts down promptly even mid-cycle.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	arbitrage.SetBinanceRegion(cfg.BinanceRegion)
+	applyEndpoints(cfg)
+	applyRequestHeaders(cfg.UserAgent, cfg.Headers)
+	if cfg.DumpDir != "" {
+		if err := os.MkdirAll(cfg.DumpDir, 0755); err != nil {
+			return fail("Failed to create dump directory", "err", err)
+		}
+		arbitrage.DumpDir = cfg.DumpDir
+	}
+	arbitrage.HTTPClient.Timeout = time.Duration(cfg.Timeout)
+	arbitrage.MaxRetries = cfg.Retries
+	arbitrage.SetRequestRate(cfg.RequestRate, cfg.RequestBurst)
+	arbitrage.Concurrency = cfg.Concurrency
+	if cfg.Proxy != "" {
+		// validate has already checked that the URL parses.
+		proxyURL, _ := url.Parse(cfg.Proxy)
+		arbitrage.SetProxy(proxyURL)
+	}
+	arbitrage.BybitCategory = cfg.BybitCategory
+	arbitrage.InstrumentsTTL = time.Duration(cfg.InstrumentsTTL)
+	arbitrage.SlippageModel = cfg.SlippageModel
+	arbitrage.Slippage = decimal.NewFromFloat(cfg.SlippageBps).Div(decimal.NewFromInt(10000))
+	arbitrage.MakerLeg = cfg.MakerLeg
+	arbitrage.BestDirectionOnly = cfg.BestDirectionOnly
+	arbitrage.IncludeLeveraged = cfg.IncludeLeveraged
+	arbitrage.RankBy = cfg.RankBy
+	pricePrecision = int32(cfg.Precision)
+	percentPrecision = int32(cfg.PercentPrecision)
+	if arbitrage.BybitCategory != arbitrage.BybitCategorySpot {
+		slog.Info("Scanning Bybit perpetuals; their prices are compared against the other exchanges' spot markets", "category", arbitrage.BybitCategory)
+	}
+	if cfg.TreatStablesEqual {
+		slog.Info("Treating stablecoins as the same quote currency", "quotes", arbitrage.StableQuoteList(), "rates", cfg.StableRates)
 	}
-	log.Printf("Retrieved %d pairs from Bybit", len(bybitPairs))
 
-	binancePairs, err := getBinancePairs()
+	filter, err := arbitrage.NewSymbolFilter(cfg.Whitelist, cfg.Blacklist)
 	if err != nil {
-		log.Fatal(err)
+		return fail("Invalid symbol filter", "err", err)
 	}
-	log.Printf("Retrieved %d pairs from Binance", len(binancePairs))
 
-	findArbitrageBetweenExchanges(bybitPairs, binancePairs)
-}
-
-func getBybitPairs() (map[string]ExchangePrice, error) {
-	instrumentsInfo, err := getBybitInstrumentsInfo()
-	if err != nil {
-		return nil, err
+	var formatTemplate *template.Template
+	if cfg.FormatTemplate != "" {
+		formatTemplate, err = loadFormatTemplate(cfg.FormatTemplate)
+		if err != nil {
+			return fail("Invalid format template", "err", err)
+		}
 	}
 
-	tickers, err := getBybitTickers()
-	if err != nil {
-		return nil, err
+	var withdrawalFees arbitrage.WithdrawalFees
+	if cfg.WithdrawalFees != "" {
+		withdrawalFees, err = arbitrage.LoadWithdrawalFees(cfg.WithdrawalFees)
+		if err != nil {
+			return fail("Failed to load withdrawal fees", "err", err)
+		}
 	}
 
-	// Create a map of active trading pairs
-	activePairs := make(map[string]bool)
-	for _, instrument := range instrumentsInfo.Result.List {
-		if instrument.Status == "Trading" {
-			activePairs[instrument.Symbol] = true
+	var symbols *arbitrage.SymbolMap
+	if cfg.SymbolMap != "" {
+		symbols, err = arbitrage.LoadSymbolMap(cfg.SymbolMap)
+		if err != nil {
+			return fail("Failed to load symbol map", "err", err)
 		}
 	}
+	// Aliases rename symbols before the whitelist is applied, so with a
+	// symbol map the whitelist doesn't name the exchanges' own symbols.
+	if watchlist := filter.Watchlist(); watchlist != nil && symbols == nil {
+		arbitrage.Watchlist = watchlist
+		slog.Info("Fetching the whitelisted symbols one by one from Binance and Bybit", "symbols", watchlist)
+	}
 
-	pairs := make(map[string]ExchangePrice)
-	for _, ticker := range tickers.Result.List {
-		if !activePairs[ticker.Symbol] {
-			continue
+	if cfg.MetricsAddr != "" {
+		startMetricsServer(cfg.MetricsAddr)
+	}
+
+	scanner := &scanner{
+		cfg:            cfg,
+		filter:         filter,
+		withdrawalFees: withdrawalFees,
+		symbols:        symbols,
+		recordDir:      cfg.Record,
+		formatTemplate: formatTemplate,
+	}
+	if cfg.TelegramToken != "" {
+		scanner.notifiers = append(scanner.notifiers, &telegramNotifier{token: cfg.TelegramToken, chatID: cfg.TelegramChatID})
+	}
+	if cfg.SlackWebhookURL != "" {
+		scanner.notifiers = append(scanner.notifiers, &slackNotifier{url: cfg.SlackWebhookURL})
+	}
+	if cfg.DiscordWebhookURL != "" {
+		scanner.notifiers = append(scanner.notifiers, &discordNotifier{url: cfg.DiscordWebhookURL})
+	}
+	if cfg.WebhookURL != "" {
+		scanner.notifiers = append(scanner.notifiers, &webhookNotifier{url: cfg.WebhookURL})
+	}
+	if cfg.SmoothWindow > 1 {
+		scanner.smoother = arbitrage.NewPriceSmoother(cfg.SmoothWindow)
+	}
+	if cfg.Stats || cfg.StatsInterval > 0 {
+		scanner.stats = newSessionStats()
+	}
+	if cfg.AlertCooldown > 0 {
+		scanner.cooldown = newAlertCooldown(time.Duration(cfg.AlertCooldown), decimal.NewFromFloat(cfg.AlertProfitChange))
+	}
+	if cfg.OutFile != "" {
+		// validate has already checked the rotation.
+		rotation, _ := parseOutRotation(cfg.OutRotate)
+		scanner.outFile, err = createOutputFile(cfg.OutFile, cfg.OutBuffer, rotation)
+		if err != nil {
+			return fail("Failed to create output file", "err", err)
 		}
-		bidPrice, err := decimal.NewFromString(ticker.Bid1Price)
-		if err != nil || bidPrice.IsZero() {
-			continue
+		defer scanner.outFile.Close()
+		scanner.out = scanner.outFile
+	}
+	if cfg.DB != "" {
+		scanner.db, err = openOpportunityDB(cfg.DB)
+		if err != nil {
+			return fail("Failed to open database", "err", err)
 		}
-		askPrice, err := decimal.NewFromString(ticker.Ask1Price)
-		if err != nil || askPrice.IsZero() {
-			continue
+		defer scanner.db.Close()
+	}
+	if cfg.SimulateTrade != "" {
+		scanner.ledger, err = openTradeLedger(cfg.SimulateTrade)
+		if err != nil {
+			return fail("Failed to open trade ledger", "err", err)
 		}
-		pairs[ticker.Symbol] = ExchangePrice{
-			Symbol:   ticker.Symbol,
-			BidPrice: bidPrice,
-			AskPrice: askPrice,
+		defer scanner.ledger.Close()
+	}
+	if cfg.KafkaBrokers != "" {
+		scanner.kafka = newKafkaSink(newKafkaWriter(cfg.kafkaBrokers(), cfg.KafkaTopic))
+		defer scanner.kafka.Close()
+	}
+
+	binance := &arbitrage.BinanceExchange{}
+	if cfg.BinanceWS && cfg.exchangeEnabled(arbitrage.ExchangeBinance) && cfg.Replay == "" && !cfg.SelfTest {
+		binance.StartStream(ctx)
+	}
+	bybit := &arbitrage.BybitExchange{}
+	if cfg.BybitWS && cfg.exchangeEnabled(arbitrage.ExchangeBybit) && cfg.Replay == "" && !cfg.SelfTest {
+		bybit.StartStream(ctx)
+	}
+	for _, exchange := range []arbitrage.Exchange{bybit, binance, arbitrage.KrakenExchange{}, arbitrage.OKXExchange{}, arbitrage.KuCoinExchange{}, arbitrage.CoinbaseExchange{}} {
+		if cfg.exchangeEnabled(exchange.Name()) {
+			scanner.exchanges = append(scanner.exchanges, exchange)
 		}
 	}
 
-	return pairs, nil
-}
+	if cfg.SelfTest {
+		if !runSelfTest(ctx, os.Stdout, scanner.exchanges, cfg.MinPairs) {
+			return exitError
+		}
+		return exitOK
+	}
 
-func getBinancePairs() (map[string]ExchangePrice, error) {
-	apiURL := "https://api.binance.com/api/v3/ticker/bookTicker"
-	resp, err := http.Get(apiURL)
-	if err != nil {
-		return nil, fmt.Errorf("error fetching Binance tickers: %v", err)
+	if cfg.Replay == "" {
+		if scanner.cfg.Fees == nil {
+			scanner.cfg.Fees = make(map[string]arbitrage.ExchangeFees)
+		}
+		applyAccountFees(ctx, scanner.exchanges, scanner.cfg.Fees)
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading Binance response: %v", err)
+	if cfg.HealthAddr != "" || cfg.APIAddr != "" {
+		scanner.store = &arbitrage.Store{}
 	}
 
-	var tickers []BinanceTicker
-	err = json.Unmarshal(body, &tickers)
-	if err != nil {
-		return nil, fmt.Errorf("error unmarshalling Binance tickers: %v", err)
+	if cfg.HealthAddr != "" {
+		var names []string
+		for _, exchange := range scanner.exchanges {
+			names = append(names, exchange.Name())
+		}
+		scanner.health = newHealthState(names, time.Duration(cfg.HealthMaxAge))
+		scanner.health.store = scanner.store
+		startHealthServer(cfg.HealthAddr, scanner.health)
 	}
 
-	pairs := make(map[string]ExchangePrice)
-	for _, ticker := range tickers {
-		bidPrice, err := decimal.NewFromString(ticker.BidPrice)
-		if err != nil || bidPrice.IsZero() {
-			continue
+	if cfg.APIAddr != "" {
+		startAPIServer(cfg.APIAddr, scanner.store)
+	}
+
+	if cfg.Replay != "" {
+		if err := scanner.replay(ctx, cfg.Replay); err != nil {
+			return fail("Replay failed", "err", err)
 		}
-		askPrice, err := decimal.NewFromString(ticker.AskPrice)
-		if err != nil || askPrice.IsZero() {
-			continue
+		return exitOK
+	}
+
+	interval := time.Duration(cfg.Interval)
+	if interval <= 0 || cfg.Once {
+		opportunities, err := scanner.runCycle(ctx)
+		if err != nil {
+			return fail("Cycle failed", "err", err)
 		}
-		pairs[ticker.Symbol] = ExchangePrice{
-			Symbol:   ticker.Symbol,
-			BidPrice: bidPrice,
-			AskPrice: askPrice,
+		if len(opportunities) == 0 {
+			return exitNoOpportunities
 		}
+		return exitOK
 	}
 
-	return pairs, nil
+	if cfg.TUI {
+		if !isTerminal(os.Stdout) {
+			return fail("-tui requires stdout to be a terminal")
+		}
+		screen, err := tcell.NewScreen()
+		if err != nil {
+			return fail("Failed to open the terminal for -tui", "err", err)
+		}
+		logs := newLogTail(tuiLogLines)
+		setupLogging(logs, cfg.LogLevel)
+		scanner.tui = newTUI(screen, cfg, logs, stop)
+		defer scanner.tui.close()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	slog.Info("Polling, press Ctrl+C to stop", "interval", interval)
+	for {
+		if scanner.runTimedCycle(ctx, interval) {
+			// The tick that fired while the cycle overran would start the
+			// next one straight away; wait for the one after instead.
+			select {
+			case <-ticker.C:
+			default:
+			}
+		}
+		if statsInterval := time.Duration(cfg.StatsInterval); statsInterval > 0 && time.Since(scanner.stats.lastPrinted) >= statsInterval {
+			scanner.printStats(time.Now())
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Shutting down")
+			if scanner.tui != nil {
+				// Leave the TUI first so the stats stay on screen.
+				scanner.tui.close()
+			}
+			if scanner.stats != nil {
+				scanner.printStats(time.Now())
+			}
+			return exitOK
+		case <-ticker.C:
+		}
+	}
 }
 
-func getBybitInstrumentsInfo() (BybitInstrumentsInfo, error) {
-	apiURL := "https://api.bybit.com/v5/market/instruments-info?category=spot"
-	resp, err := http.Get(apiURL)
+// runTimedCycle runs one cycle of the polling loop, cancelling it if it is
+// still running after interval so slow exchanges can't make cycles pile up.
+// It reports whether the cycle overran and was cut short, which is logged
+// and counted as a skipped cycle rather than as a failure.
+func (s *scanner) runTimedCycle(ctx context.Context, interval time.Duration) bool {
+	cycleCtx, cancel := context.WithTimeout(ctx, interval)
+	defer cancel()
+	_, err := s.runCycle(cycleCtx)
+	if ctx.Err() != nil {
+		return false
+	}
+	if cycleCtx.Err() == context.DeadlineExceeded {
+		skippedCyclesCounter.Inc()
+		slog.Warn("Cycle overran the interval and was skipped", "interval", interval, "err", err)
+		return true
+	}
 	if err != nil {
-		return BybitInstrumentsInfo{}, fmt.Errorf("error fetching Bybit instruments info: %v", err)
+		slog.Error("Cycle failed", "err", err)
 	}
-	defer resp.Body.Close()
+	return false
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return BybitInstrumentsInfo{}, fmt.Errorf("error reading Bybit response: %v", err)
+// scanner holds everything a comparison cycle needs beyond the Config.
+type scanner struct {
+	cfg            Config
+	exchanges      []arbitrage.Exchange
+	filter         arbitrage.SymbolFilter
+	withdrawalFees arbitrage.WithdrawalFees
+	// symbols overrides how assets are matched across exchanges. It is nil
+	// unless -symbol-map is set.
+	symbols *arbitrage.SymbolMap
+
+	// smoother averages every market's prices over the last cycles. It is
+	// nil unless -smooth-window is set.
+	smoother *arbitrage.PriceSmoother
+
+	// db records every reported opportunity. It is nil unless -db is set.
+	db *opportunityDB
+	// ledger records a simulated trade of every reported opportunity. It
+	// is nil unless -simulate-trade is set.
+	ledger *tradeLedger
+	// kafka publishes every opportunity found. It is nil unless
+	// -kafka-brokers is set.
+	kafka *kafkaSink
+	// notifiers alert about every cycle's opportunities. It is empty unless
+	// Telegram or a webhook is configured.
+	notifiers []Notifier
+	// cooldown keeps persistent opportunities from being printed and
+	// alerted about every cycle. It is nil unless -alert-cooldown is set.
+	cooldown *alertCooldown
+	// stats accumulates every opportunity found during the session. It is
+	// nil unless -stats or -stats-interval is set.
+	stats *sessionStats
+
+	// tui shows the opportunities instead of printing them. It is nil
+	// unless -tui is set.
+	tui *tui
+
+	// store holds the latest cycle's pairs and opportunities for the API and
+	// health servers. It is nil unless -api-addr or -health-addr is set.
+	store *arbitrage.Store
+
+	// health records fetch and comparison times for -health-addr. It is nil
+	// when the health server is disabled.
+	health *healthState
+
+	// out receives the printed opportunities. It is nil for stdout.
+	out io.Writer
+	// outFile is out when it is the -out-file, which is flushed and
+	// rotated at the end of every cycle. It is nil otherwise.
+	outFile *outputFile
+	// formatTemplate prints every opportunity in text output. It is nil
+	// unless -format-template is set.
+	formatTemplate *template.Template
+
+	// csvHeaderWritten is set once the CSV header has been written to out.
+	csvHeaderWritten bool
+
+	// recordDir, if set, receives a snapshot of the fetched prices every
+	// cycle for later replay.
+	recordDir string
+	// clock returns the time a cycle's snapshot was taken. It is nil for
+	// live fetching, which uses the current time.
+	clock func() time.Time
+}
+
+// runCycle fetches every exchange once, finds the best pair of exchanges for
+// each symbol and prints the resulting opportunities. With a trade size configured the
+// opportunities are re-checked against order book depth first, and with an
+// amount each one reports the profit on a stake of that size, net of the
+// withdrawal fees if any are given. Only pairs that pass the symbol filter
+// are compared. The opportunities found are also returned, including any
+// the alert cooldown kept from being printed.
+func (s *scanner) runCycle(ctx context.Context) ([]arbitrage.ArbitrageOpportunity, error) {
+	if s.outFile != nil {
+		defer s.endOutputCycle()
+	}
+	cfg, exchanges, filter, withdrawalFees := s.cfg, s.exchanges, s.filter, s.withdrawalFees
+	fees := cfg.Fees
+	minProfit := cfg.minProfitFraction()
+	maxProfit := cfg.maxProfitFraction()
+	tradeSize := decimal.NewFromFloat(cfg.TradeSize)
+	amount := decimal.NewFromFloat(cfg.Amount)
+	minVolume := decimal.NewFromFloat(cfg.MinVolume)
+	minPrice := decimal.NewFromFloat(cfg.MinPrice)
+
+	// Fetch every exchange at the same time so the snapshots are as close
+	// together as possible.
+	var wg sync.WaitGroup
+	pairs := make([]map[string]arbitrage.ExchangePrice, len(exchanges))
+	errs := make([]error, len(exchanges))
+	durations := make([]time.Duration, len(exchanges))
+	priceTimes := make(map[string]time.Time, len(exchanges))
+	var priceTimesMu sync.Mutex
+	for i, exchange := range exchanges {
+		wg.Add(1)
+		go func(i int, exchange arbitrage.Exchange) {
+			defer wg.Done()
+			start := time.Now()
+			pairs[i], errs[i] = exchange.Pairs(ctx)
+			durations[i] = time.Since(start)
+
+			// Prefer the exchange's own clock; otherwise the prices are as
+			// old as the moment the response arrived.
+			priceTime := time.Now()
+			if reporter, ok := exchange.(arbitrage.ServerTimeReporter); ok && !reporter.ServerTime().IsZero() {
+				priceTime = reporter.ServerTime()
+			}
+			priceTimesMu.Lock()
+			priceTimes[exchange.Name()] = priceTime
+			priceTimesMu.Unlock()
+		}(i, exchange)
+	}
+	wg.Wait()
+	// An overrun cycle is skipped whole: comparing the exchanges that
+	// answered in time would report a partial snapshot.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	fetchedAt := time.Now()
+	if s.clock != nil {
+		fetchedAt = s.clock()
+	}
+	for i, exchange := range exchanges {
+		fetchDurationHistogram.WithLabelValues(exchange.Name()).Observe(durations[i].Seconds())
+		if s.stats != nil && s.cfg.Replay == "" {
+			s.stats.recordFetch(exchange.Name(), durations[i])
+		}
+	}
+	var fetches []exchangeFetch
+	if s.tui != nil {
+		for i, exchange := range exchanges {
+			fetches = append(fetches, exchangeFetch{name: exchange.Name(), pairs: len(pairs[i]), duration: durations[i], err: errs[i]})
+		}
 	}
 
-	var instrumentsInfo BybitInstrumentsInfo
-	err = json.Unmarshal(body, &instrumentsInfo)
+	if s.health != nil {
+		for i, exchange := range exchanges {
+			if errs[i] == nil {
+				s.health.recordFetch(exchange.Name(), fetchedAt)
+			}
+		}
+	}
+	exchanges, pairs, durations, err := dropFailedExchanges(exchanges, pairs, durations, errs)
 	if err != nil {
-		return BybitInstrumentsInfo{}, fmt.Errorf("error unmarshalling Bybit instruments info: %v", err)
+		return nil, err
+	}
+	if s.recordDir != "" {
+		if err := recordSnapshot(s.recordDir, fetchedAt, exchanges, pairs); err != nil {
+			slog.Error("Failed to record snapshot", "err", err)
+		}
 	}
 
-	return instrumentsInfo, nil
-}
+	for i, exchange := range exchanges {
+		var crossed []string
+		pairs[i], crossed = arbitrage.FilterCrossed(pairs[i])
+		if len(crossed) > 0 {
+			slog.Info("Excluded crossed or locked markets, whose bid is at or above the ask", "exchange", exchange.Name(),
+				"excluded", len(crossed), "symbols", crossed)
+		}
+	}
 
-func getBybitTickers() (BybitTickers, error) {
-	apiURL := "https://api.bybit.com/v5/market/tickers?category=spot"
-	resp, err := http.Get(apiURL)
-	if err != nil {
-		return BybitTickers{}, fmt.Errorf("error fetching Bybit tickers: %v", err)
+	// The stablecoin rates come from every exchange's markets before any
+	// other filter can remove them.
+	var stableRates map[string]decimal.Decimal
+	if cfg.TreatStablesEqual && cfg.StableRates == arbitrage.StableRatesMarket {
+		fetched := make(map[string]map[string]arbitrage.ExchangePrice, len(exchanges))
+		for i, exchange := range exchanges {
+			fetched[exchange.Name()] = pairs[i]
+		}
+		stableRates = arbitrage.StableRates(fetched)
+		slog.Debug("Converting stablecoin quotes at market rates", "rates", stableRates)
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return BybitTickers{}, fmt.Errorf("error reading Bybit response: %v", err)
+	for i, exchange := range exchanges {
+		slog.Info("Retrieved pairs", "exchange", exchange.Name(), "pairs", len(pairs[i]), "duration", durations[i].Round(time.Millisecond))
+		if err := checkPairCount(exchange.Name(), len(pairs[i]), cfg.MinPairs, cfg.AbortOnFewPairs); err != nil {
+			return nil, err
+		}
+		pairsFetchedGauge.WithLabelValues(exchange.Name()).Set(float64(len(pairs[i])))
+		if s.symbols != nil {
+			pairs[i] = s.symbols.Alias(exchange.Name(), pairs[i])
+		}
+		if filter.Active() {
+			pairs[i] = filter.Apply(pairs[i])
+			slog.Debug("Filtered by symbol", "exchange", exchange.Name(), "pairs", len(pairs[i]))
+		}
+		if len(cfg.Bases) > 0 || len(cfg.Quotes) > 0 {
+			pairs[i] = arbitrage.FilterByAssets(pairs[i], cfg.Bases, cfg.Quotes)
+			slog.Debug("Filtered by base and quote asset", "exchange", exchange.Name(), "pairs", len(pairs[i]))
+		}
+		if minVolume.IsPositive() {
+			pairs[i] = arbitrage.FilterByVolume(pairs[i], minVolume)
+			slog.Debug("Filtered by 24h quote volume", "exchange", exchange.Name(), "pairs", len(pairs[i]), "min_volume", minVolume)
+		}
+		if minPrice.IsPositive() {
+			pairs[i] = arbitrage.FilterByPrice(pairs[i], minPrice)
+			slog.Debug("Filtered by price", "exchange", exchange.Name(), "pairs", len(pairs[i]), "min_price", minPrice)
+		}
+		if cfg.MinAge > 0 {
+			pairs[i] = arbitrage.FilterByAge(pairs[i], time.Duration(cfg.MinAge), fetchedAt)
+			slog.Debug("Filtered by listing age", "exchange", exchange.Name(), "pairs", len(pairs[i]), "min_age", time.Duration(cfg.MinAge))
+		}
+		if cfg.MaxUpdateAge > 0 {
+			var excluded int
+			pairs[i], excluded = arbitrage.FilterByUpdateAge(pairs[i], time.Duration(cfg.MaxUpdateAge), fetchedAt)
+			if excluded > 0 {
+				slog.Info("Excluded pairs with stale prices", "exchange", exchange.Name(), "excluded", excluded, "max_update_age", time.Duration(cfg.MaxUpdateAge))
+			}
+		}
+		if cfg.TreatStablesEqual {
+			if stableRates != nil {
+				pairs[i] = arbitrage.ConvertStableQuotes(pairs[i], stableRates)
+			}
+			pairs[i] = arbitrage.MergeStableQuotes(pairs[i])
+		}
+		if s.symbols != nil {
+			pairs[i] = s.symbols.Isolate(exchange.Name(), pairs[i])
+		}
+		if s.smoother != nil {
+			pairs[i] = s.smoother.Smooth(exchange.Name(), pairs[i])
+		}
 	}
+	slog.Debug("Snapshots taken", "fetched_at", fetchedAt.Format(time.RFC3339Nano))
 
-	var tickers BybitTickers
-	err = json.Unmarshal(body, &tickers)
-	if err != nil {
-		return BybitTickers{}, fmt.Errorf("error unmarshalling Bybit tickers: %v", err)
+	byName := make(map[string]arbitrage.Exchange, len(exchanges))
+	pairsByName := make(map[string]map[string]arbitrage.ExchangePrice, len(exchanges))
+	for i, exchange := range exchanges {
+		byName[exchange.Name()] = exchange
+		pairsByName[exchange.Name()] = pairs[i]
 	}
 
-	return tickers, nil
-}
+	out := s.out
+	if out == nil {
+		out = os.Stdout
+	}
 
-func findArbitrageBetweenExchanges(bybitPairs, binancePairs map[string]ExchangePrice) {
-	log.Printf("Comparing %d Bybit pairs with %d Binance pairs", len(bybitPairs), len(binancePairs))
+	comparisonStart := time.Now()
+	opportunities := arbitrage.FindArbitrage(pairsByName, fees, minProfit, maxProfit)
+	comparisonDurationHistogram.Observe(time.Since(comparisonStart).Seconds())
+	comparedAt := time.Now()
+	noneFound := len(opportunities) == 0
+	if noneFound {
+		slog.Info("No arbitrage opportunities found", "exchanges", len(exchanges),
+			"min_profit_pct", minProfit.Mul(decimal.NewFromInt(100)))
+		opportunities = []arbitrage.ArbitrageOpportunity{}
+	}
 
-	opportunitiesFound := 0
-	pairsCompared := 0
+	if cfg.Confirm && len(opportunities) > 0 {
+		opportunities = arbitrage.ConfirmOpportunities(ctx, opportunities, byName, pairsByName, fees, stableRates, minProfit, maxProfit)
+	}
 
-	for symbol, bybitPrice := range bybitPairs {
-		binancePrice, exists := binancePairs[symbol]
-		if !exists {
-			continue
+	if tradeSize.IsPositive() && len(opportunities) > 0 {
+		opportunities = arbitrage.FilterByDepth(ctx, opportunities, byName, pairsByName, fees, tradeSize, minProfit)
+		if opportunities == nil {
+			opportunities = []arbitrage.ArbitrageOpportunity{}
 		}
+	}
 
-		pairsCompared++
+	arbitrage.AnnotateSkew(opportunities, priceTimes, time.Duration(cfg.MaxSkew))
 
-		// Check for zero prices
-		if bybitPrice.AskPrice.IsZero() || bybitPrice.BidPrice.IsZero() ||
-			binancePrice.AskPrice.IsZero() || binancePrice.BidPrice.IsZero() {
-			continue
+	if amount.IsPositive() {
+		for i := range opportunities {
+			opportunities[i] = arbitrage.ApplyAmount(opportunities[i], amount)
 		}
+		opportunities = arbitrage.FilterByMinNotional(opportunities, pairsByName, amount)
+	}
+	if withdrawalFees != nil {
+		opportunities = arbitrage.ApplyWithdrawalFees(opportunities, withdrawalFees, minProfit)
+	}
 
-		// Check Bybit buy, Binance sell
-		bybitBuyPrice := bybitPrice.AskPrice.Mul(decimal.NewFromFloat(1 + transactionFee))
-		binanceSellPrice := binancePrice.BidPrice.Mul(decimal.NewFromFloat(1 - transactionFee))
+	var basisTrades []arbitrage.BasisOpportunity
+	if cfg.Funding && s.tui == nil {
+		basisTrades = arbitrage.FindBasisTrades(arbitrage.SpotPairs(pairsByName), arbitrage.FetchFundingRates(ctx, byName), fees,
+			decimal.NewFromFloat(cfg.MinFundingAPR).Div(decimal.NewFromInt(100)))
+		if cfg.Top > 0 && len(basisTrades) > cfg.Top {
+			basisTrades = basisTrades[:cfg.Top]
+		}
+	}
 
-		if bybitBuyPrice.IsPositive() {
-			profitPercentage := binanceSellPrice.Sub(bybitBuyPrice).Div(bybitBuyPrice)
+	// The deadline may also pass during confirmation, the depth check or
+	// the funding fetch, which then fail partway; nothing of such a cycle
+	// is output or recorded.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if s.health != nil {
+		s.health.recordComparison(comparedAt)
+	}
+	if noneFound && cfg.Verbose && cfg.Output == "text" && s.tui == nil {
+		printSampleComparisons(out, pairsByName, fees)
+	}
 
-			if profitPercentage.GreaterThanOrEqual(decimal.NewFromFloat(minProfitPercentage)) {
-				fmt.Printf("Arbitrage opportunity found for %s:\n", symbol)
-				fmt.Printf("  Buy from Bybit at %s\n", bybitBuyPrice.StringFixed(8))
-				fmt.Printf("  Sell on Binance at %s\n", binanceSellPrice.StringFixed(8))
-				fmt.Printf("  Profit percentage: %s%%\n\n", profitPercentage.Mul(decimal.NewFromInt(100)).StringFixed(2))
-				opportunitiesFound++
-			}
+	arbitrage.SortOpportunities(opportunities)
+	recordOpportunityMetrics(opportunities)
+	if s.stats != nil {
+		s.stats.record(opportunities, fetchedAt)
+	}
+	if s.store != nil {
+		s.store.Update(pairsByName, opportunities, fetchedAt)
+	}
+	if s.db != nil {
+		if err := s.db.save(opportunities, fetchedAt); err != nil {
+			slog.Error("Failed to record opportunities", "err", err)
 		}
+	}
+	if s.kafka != nil {
+		s.kafka.publish(opportunities, fetchedAt)
+	}
+	// Everything found is recorded, but only new or changed opportunities
+	// are reported while a cooldown is set.
+	reported := opportunities
+	if s.cooldown != nil {
+		reported = s.cooldown.filter(opportunities, fetchedAt)
+		if suppressed := len(opportunities) - len(reported); suppressed > 0 {
+			slog.Info("Suppressed opportunities reported within the cooldown", "suppressed", suppressed)
+		}
+	}
+	notifyAll(ctx, s.notifiers, reported)
+	if s.ledger != nil {
+		if err := s.ledger.record(reported, pairsByName, fetchedAt); err != nil {
+			slog.Error("Failed to record simulated trades", "err", err)
+		}
+	}
 
-		// Check Binance buy, Bybit sell
-		binanceBuyPrice := binancePrice.AskPrice.Mul(decimal.NewFromFloat(1 + transactionFee))
-		bybitSellPrice := bybitPrice.BidPrice.Mul(decimal.NewFromFloat(1 - transactionFee))
-
-		if binanceBuyPrice.IsPositive() {
-			profitPercentage := bybitSellPrice.Sub(binanceBuyPrice).Div(binanceBuyPrice)
+	if s.tui != nil {
+		// The TUI shows every current opportunity, whether or not the
+		// cooldown reported it this cycle.
+		s.tui.render(fetches, opportunities, fetchedAt)
+		return opportunities, nil
+	}
 
-			if profitPercentage.GreaterThanOrEqual(decimal.NewFromFloat(minProfitPercentage)) {
-				fmt.Printf("Arbitrage opportunity found for %s:\n", symbol)
-				fmt.Printf("  Buy from Binance at %s\n", binanceBuyPrice.StringFixed(8))
-				fmt.Printf("  Sell on Bybit at %s\n", bybitSellPrice.StringFixed(8))
-				fmt.Printf("  Profit percentage: %s%%\n\n", profitPercentage.Mul(decimal.NewFromInt(100)).StringFixed(2))
-				opportunitiesFound++
-			}
+	printed := reported
+	if cfg.Top > 0 && len(printed) > cfg.Top {
+		printed = printed[:cfg.Top]
+	}
+	if cfg.SummaryByQuote && cfg.Output != "text" {
+		// Keep machine-readable output clean; the summary goes to stderr
+		// with the logs.
+		printQuoteSummary(os.Stderr, summarizeByQuote(opportunities))
+	}
+	switch cfg.Output {
+	case "json":
+		return opportunities, printOpportunitiesJSON(out, printed, basisTrades, fetchedAt)
+	case "csv":
+		// The header is only written once, so a polling run produces a
+		// single table.
+		err := printOpportunitiesCSV(out, printed, fetchedAt, !s.csvHeaderWritten)
+		s.csvHeaderWritten = true
+		return opportunities, err
+	}
+	if s.formatTemplate != nil {
+		if err := printOpportunitiesTemplate(out, s.formatTemplate, printed); err != nil {
+			return opportunities, err
 		}
+	} else {
+		printOpportunities(out, printed)
 	}
+	if cfg.Verbose {
+		printNearMisses(out, arbitrage.FindNearMisses(pairsByName, fees, minProfit,
+			decimal.NewFromFloat(cfg.NearMissBand).Div(decimal.NewFromInt(100))))
+	}
+	if cfg.Funding {
+		printBasisTrades(out, basisTrades)
+	}
+	if cfg.SummaryByQuote {
+		printQuoteSummary(out, summarizeByQuote(opportunities))
+	}
+	return opportunities, nil
+}
 
-	log.Printf("Compared %d pairs", pairsCompared)
-	log.Printf("Found %d arbitrage opportunities", opportunitiesFound)
+// endOutputCycle writes the cycle's output to the -out-file and rotates it
+// when due. A new file starts with its own CSV header.
+func (s *scanner) endOutputCycle() {
+	rotated, err := s.outFile.endCycle()
+	if err != nil {
+		slog.Error("Failed to write output file", "err", err)
+	}
+	if rotated {
+		slog.Info("Rotated output file", "file", s.cfg.OutFile)
+		s.csvHeaderWritten = false
+	}
+}
 
-	if opportunitiesFound == 0 {
-		log.Println("No arbitrage opportunities found meeting the 2% profit threshold.")
-		// Print a few sample comparisons for debugging
-		count := 0
-		for symbol, bybitPrice := range bybitPairs {
-			if binancePrice, exists := binancePairs[symbol]; exists {
-				fmt.Printf("Sample comparison for %s:\n", symbol)
-				fmt.Printf("  Bybit  - Bid: %s, Ask: %s\n", bybitPrice.BidPrice.StringFixed(8), bybitPrice.AskPrice.StringFixed(8))
-				fmt.Printf("  Binance - Bid: %s, Ask: %s\n", binancePrice.BidPrice.StringFixed(8), binancePrice.AskPrice.StringFixed(8))
-				count++
-				if count >= 5 {
-					break
-				}
+// printStats prints the session statistics up to now to the output, or to
+// stderr with -output json or csv so the output stays machine-readable.
+func (s *scanner) printStats(now time.Time) {
+	var w io.Writer = os.Stderr
+	if s.cfg.Output == "text" {
+		w = s.out
+		if w == nil {
+			w = os.Stdout
+		}
+	}
+	printSessionStats(w, s.stats, now)
+	s.stats.lastPrinted = now
+}
+
+// dropFailedExchanges removes the exchanges whose fetch failed, logging a
+// warning for each, so the rest can still be compared. It only fails when
+// that leaves fewer than two exchanges to compare.
+func dropFailedExchanges(exchanges []arbitrage.Exchange, pairs []map[string]arbitrage.ExchangePrice, durations []time.Duration, errs []error) ([]arbitrage.Exchange, []map[string]arbitrage.ExchangePrice, []time.Duration, error) {
+	var (
+		keptExchanges []arbitrage.Exchange
+		keptPairs     []map[string]arbitrage.ExchangePrice
+		keptDurations []time.Duration
+		firstErr      error
+	)
+	for i, exchange := range exchanges {
+		if errs[i] != nil {
+			slog.Warn("Failed to fetch pairs, comparing the other exchanges", "exchange", exchange.Name(), "err", errs[i])
+			if firstErr == nil {
+				firstErr = errs[i]
 			}
+			continue
 		}
+		keptExchanges = append(keptExchanges, exchange)
+		keptPairs = append(keptPairs, pairs[i])
+		keptDurations = append(keptDurations, durations[i])
+	}
+	if firstErr != nil && len(keptExchanges) < 2 {
+		return nil, nil, nil, fmt.Errorf("only %d of %d exchanges returned data: %v", len(keptExchanges), len(exchanges), firstErr)
+	}
+	return keptExchanges, keptPairs, keptDurations, nil
+}
+
+// checkPairCount warns when an exchange returned fewer than minPairs pairs,
+// which usually means an API change, an outage or a regional block rather
+// than a quiet market. With abort set it returns an error instead, so the
+// cycle doesn't report "no opportunities" from broken data.
+func checkPairCount(exchange string, count, minPairs int, abort bool) error {
+	if count >= minPairs && count > 0 {
+		return nil
+	}
+	var problem string
+	if count == 0 {
+		problem = fmt.Sprintf("%s returned no pairs", exchange)
+	} else {
+		problem = fmt.Sprintf("%s returned only %d pairs, fewer than the expected %d", exchange, count, minPairs)
+	}
+	if abort {
+		return fmt.Errorf("%s; aborting the cycle", problem)
 	}
+	slog.Warn(problem+"; its data is probably incomplete", "exchange", exchange, "pairs", count, "min_pairs", minPairs)
+	return nil
 }
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+	"github.com/shopspring/decimal"
+)
+
+func mustDecimal(t *testing.T, value string) decimal.Decimal {
+	t.Helper()
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		t.Fatalf("invalid decimal %q: %v", value, err)
+	}
+	return d
+}
+
+func TestPrintOpportunitiesCSV(t *testing.T) {
+	opportunities := []arbitrage.ArbitrageOpportunity{{
+		Symbol:           "BTC/USDT",
+		BuyExchange:      "A",
+		SellExchange:     "B",
+		BuyPrice:         mustDecimal(t, "0.0000001234567891"),
+		SellPrice:        mustDecimal(t, "0.00000013"),
+		ProfitPercentage: mustDecimal(t, "5.301526086372781"),
+		Spread:           mustDecimal(t, "0.0000000065432109"),
+		RoundTrip:        mustDecimal(t, "1.05301526086372781"),
+
+		GrossProfitPercentage: mustDecimal(t, "5.5"),
+	}}
+	fetchedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var b strings.Builder
+	if err := printOpportunitiesCSV(&b, opportunities, fetchedAt, true); err != nil {
+		t.Fatal(err)
+	}
+	want := "symbol,buy_exchange,sell_exchange,buy_price,sell_price,profit_pct,timestamp,spread,round_trip,gross_profit_pct\n" +
+		"BTC/USDT,A,B,0.0000001234567891,0.00000013,5.301526086372781,2024-03-01T12:00:00Z,0.0000000065432109,1.05301526086372781,5.5\n"
+	if b.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestPrintOpportunitiesJSON(t *testing.T) {
+	fetchedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var b strings.Builder
+	if err := printOpportunitiesJSON(&b, nil, nil, fetchedAt); err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n  \"version\": 1,\n  \"generated_at\": \"2024-03-01T12:00:00Z\",\n  \"opportunities\": []\n}\n"
+	if b.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	opportunities := []arbitrage.ArbitrageOpportunity{{Symbol: "BTC/USDT", BuyPrice: mustDecimal(t, "100.5")}}
+	trades := []arbitrage.BasisOpportunity{{Symbol: "ETH/USDT"}}
+	if err := printOpportunitiesJSON(&b, opportunities, trades, fetchedAt); err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Version       int
+		Opportunities []map[string]interface{}
+		BasisTrades   []map[string]interface{} `json:"basis_trades"`
+	}
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Opportunities) != 1 || got.Opportunities[0]["buy_price"] != "100.5" {
+		t.Errorf("opportunities = %v, want BTC/USDT with the price as a string", got.Opportunities)
+	}
+	if len(got.BasisTrades) != 1 || got.BasisTrades[0]["symbol"] != "ETH/USDT" {
+		t.Errorf("basis_trades = %v, want ETH/USDT", got.BasisTrades)
+	}
+}
+
+func TestPrintNearMisses(t *testing.T) {
+	var b strings.Builder
+	printNearMisses(&b, []arbitrage.NearMiss{
+		{Symbol: "BTC/USDT", BuyExchange: "A", SellExchange: "B", ProfitPercentage: mustDecimal(t, "0.898"),
+			RoundTripFee: mustDecimal(t, "0.1998"), BreakEvenFee: mustDecimal(t, "0.0989")},
+		{Symbol: "ETH/USDT", BuyExchange: "B", SellExchange: "A", ProfitPercentage: mustDecimal(t, "0.7"),
+			RoundTripFee: mustDecimal(t, "0.2"), BreakEvenFee: mustDecimal(t, "-0.1")},
+	})
+	want := "Near misses: 2\n" +
+		"  BTC/USDT: buy A, sell B, profit 0.90%, round-trip fee 0.1998% would need to be at most 0.0989%\n" +
+		"  ETH/USDT: buy B, sell A, profit 0.70%, the spread is below the threshold even without fees\n\n"
+	if b.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestPrintSampleComparisons(t *testing.T) {
+	pairs := map[string]map[string]arbitrage.ExchangePrice{
+		"A": {"BTC/USDT": {BidPrice: mustDecimal(t, "99"), AskPrice: mustDecimal(t, "100")}, "ONLY/USDT": {}},
+		"B": {"BTC/USDT": {BidPrice: mustDecimal(t, "100.5"), AskPrice: mustDecimal(t, "101")}},
+	}
+	fees := map[string]arbitrage.ExchangeFees{"A": {}, "B": {}}
+
+	var buf bytes.Buffer
+	printSampleComparisons(&buf, pairs, fees)
+	want := `Sample comparison for BTC/USDT:
+  A - Bid: 99.00000000, Ask: 100.00000000
+  B - Bid: 100.50000000, Ask: 101.00000000
+  Best spread: buy A, sell B, profit 0.5000%
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestCheckPairCount(t *testing.T) {
+	if err := checkPairCount("A", 500, 10, true); err != nil {
+		t.Errorf("500 pairs: unexpected error %v", err)
+	}
+	if err := checkPairCount("A", 3, 10, false); err != nil {
+		t.Errorf("3 pairs without abort: unexpected error %v", err)
+	}
+	if err := checkPairCount("A", 3, 10, true); err == nil {
+		t.Error("3 pairs with abort: expected an error")
+	}
+	if err := checkPairCount("A", 0, 0, true); err == nil {
+		t.Error("0 pairs with abort: expected an error even without a floor")
+	}
+}
+
+func TestDropFailedExchanges(t *testing.T) {
+	exchanges := []arbitrage.Exchange{replayExchange{name: "A"}, replayExchange{name: "B"}, replayExchange{name: "C"}}
+	pairs := []map[string]arbitrage.ExchangePrice{{"X/USDT": {}}, nil, {"Y/USDT": {}}}
+	durations := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+	fetchErr := errors.New("connection refused")
+
+	kept, keptPairs, keptDurations, err := dropFailedExchanges(exchanges, pairs, durations, []error{nil, fetchErr, nil})
+	if err != nil {
+		t.Fatalf("one failure out of three: unexpected error %v", err)
+	}
+	if len(kept) != 2 || kept[0].Name() != "A" || kept[1].Name() != "C" {
+		t.Fatalf("kept %v, want A and C", kept)
+	}
+	if _, ok := keptPairs[1]["Y/USDT"]; !ok || keptDurations[1] != 3*time.Second {
+		t.Errorf("C's pairs or duration were not kept alongside it: %v, %s", keptPairs[1], keptDurations[1])
+	}
+
+	if _, _, _, err := dropFailedExchanges(exchanges, pairs, durations, []error{fetchErr, fetchErr, nil}); err == nil {
+		t.Error("two failures out of three: expected an error")
+	}
+	if _, _, _, err := dropFailedExchanges(exchanges, pairs, durations, make([]error, 3)); err != nil {
+		t.Errorf("no failures: unexpected error %v", err)
+	}
+}
+
+func TestPrintOpportunitiesMakerLegCaveat(t *testing.T) {
+	opportunity := arbitrage.ArbitrageOpportunity{
+		Symbol: "BTC/USDT", Base: "BTC", Quote: "USDT", BuyExchange: "A", SellExchange: "B",
+		BuyPrice: mustDecimal(t, "100"), SellPrice: mustDecimal(t, "101"),
+	}
+
+	var buf bytes.Buffer
+	printOpportunities(&buf, []arbitrage.ArbitrageOpportunity{opportunity})
+	if strings.Contains(buf.String(), "not guaranteed to fill") {
+		t.Errorf("taker-only opportunity has a maker caveat:\n%s", buf.String())
+	}
+
+	opportunity.MakerLeg = arbitrage.MakerLegSell
+	buf.Reset()
+	printOpportunities(&buf, []arbitrage.ArbitrageOpportunity{opportunity})
+	if !strings.Contains(buf.String(), "Maker fees on the sell leg(s): limit orders are not guaranteed to fill") {
+		t.Errorf("missing maker caveat:\n%s", buf.String())
+	}
+}
+
+func assertPrice(t *testing.T, pairs map[string]arbitrage.ExchangePrice, key, symbol, bid, ask string) {
+	t.Helper()
+	price, ok := pairs[key]
+	if !ok {
+		t.Fatalf("missing pair %s in %v", key, pairs)
+	}
+	if price.Symbol != symbol {
+		t.Errorf("%s: symbol = %q, want %q", key, price.Symbol, symbol)
+	}
+	if !price.BidPrice.Equal(mustDecimal(t, bid)) {
+		t.Errorf("%s: bid = %s, want %s", key, price.BidPrice, bid)
+	}
+	if !price.AskPrice.Equal(mustDecimal(t, ask)) {
+		t.Errorf("%s: ask = %s, want %s", key, price.AskPrice, ask)
+	}
+}
+
+// slowExchange never answers before its context is done.
+type slowExchange struct{}
+
+func (slowExchange) Name() string { return "Slow" }
+
+func (slowExchange) Pairs(ctx context.Context) (map[string]arbitrage.ExchangePrice, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRunTimedCycleSkipsOverruns(t *testing.T) {
+	price := func(bid, ask string) map[string]arbitrage.ExchangePrice {
+		return map[string]arbitrage.ExchangePrice{"BTC/USDT": {Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT",
+			BidPrice: mustDecimal(t, bid), AskPrice: mustDecimal(t, ask)}}
+	}
+	fast := []arbitrage.Exchange{
+		replayExchange{name: arbitrage.ExchangeBybit, pairs: price("50000", "50001")},
+		replayExchange{name: arbitrage.ExchangeBinance, pairs: price("52000", "52001")},
+	}
+	var out bytes.Buffer
+	notifier := &fakeNotifier{}
+	store := &arbitrage.Store{}
+	s := &scanner{cfg: defaultConfig(), exchanges: fast, out: &out, store: store, notifiers: []Notifier{notifier}}
+	if s.runTimedCycle(context.Background(), time.Minute) {
+		t.Error("a cycle within the interval was reported as overrun")
+	}
+	if opportunities, _ := store.Opportunities(); out.Len() == 0 || len(opportunities) != 1 || notifier.calls != 1 {
+		t.Fatalf("a cycle within the interval: printed %d bytes, stored %d opportunities, notified %d times",
+			out.Len(), len(opportunities), notifier.calls)
+	}
+
+	out.Reset()
+	updatedAt := store.UpdatedAt()
+	s.exchanges = append(fast, slowExchange{})
+	start := time.Now()
+	if !s.runTimedCycle(context.Background(), 50*time.Millisecond) {
+		t.Error("a cycle past the interval was not reported as overrun")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the overrunning cycle took %s, want it cut short at the interval", elapsed)
+	}
+	if out.Len() != 0 || !store.UpdatedAt().Equal(updatedAt) || notifier.calls != 1 {
+		t.Errorf("the skipped cycle printed %q, updated the store (%v) or notified (%d calls)",
+			out.String(), !store.UpdatedAt().Equal(updatedAt), notifier.calls)
+	}
+}
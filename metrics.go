@@ -0,0 +1,76 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	pairsFetchedGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "arbitrage_pairs_fetched",
+		Help: "Number of pairs fetched from each exchange in the last cycle.",
+	}, []string{"exchange"})
+
+	fetchDurationHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "arbitrage_fetch_duration_seconds",
+		Help:    "Time taken by each fetch of an exchange's pairs, including failed ones.",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"exchange"})
+
+	comparisonDurationHistogram = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "arbitrage_comparison_duration_seconds",
+		Help:    "Time spent comparing the fetched pairs in each cycle.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 12),
+	})
+
+	skippedCyclesCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "arbitrage_skipped_cycles_total",
+		Help: "Number of polling cycles cut short because they overran the interval.",
+	})
+
+	opportunitiesGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "arbitrage_opportunities",
+		Help: "Number of opportunities reported in the last cycle.",
+	})
+
+	bestProfitGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "arbitrage_best_profit_percentage",
+		Help: "Profit percentage of the best opportunity in the last cycle, or 0 if there was none.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(pairsFetchedGauge, fetchDurationHistogram, comparisonDurationHistogram, skippedCyclesCounter, opportunitiesGauge, bestProfitGauge)
+}
+
+// recordOpportunityMetrics updates the per-cycle opportunity gauges.
+func recordOpportunityMetrics(opportunities []arbitrage.ArbitrageOpportunity) {
+	opportunitiesGauge.Set(float64(len(opportunities)))
+
+	best := 0.0
+	for i, opportunity := range opportunities {
+		profit, _ := opportunity.ProfitPercentage.Float64()
+		if i == 0 || profit > best {
+			best = profit
+		}
+	}
+	bestProfitGauge.Set(best)
+}
+
+// startMetricsServer serves the Prometheus metrics on addr in the
+// background.
+func startMetricsServer(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	go func() {
+		slog.Info("Serving metrics", "url", addr+"/metrics")
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			slog.Error("Metrics server stopped", "err", err)
+		}
+	}()
+}
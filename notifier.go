@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+// alertMaxLines caps how many opportunities are listed in one alert
+// message; the rest are summarised as a count.
+const alertMaxLines = 20
+
+// Notifier alerts about the opportunities a cycle reported. Notify is called
+// once per cycle, including cycles that reported none, and implementations
+// decide whether those are worth sending.
+type Notifier interface {
+	Notify(ctx context.Context, opportunities []arbitrage.ArbitrageOpportunity) error
+}
+
+// notifyAll sends the opportunities to every notifier in turn. A failing
+// notifier is logged and doesn't keep the others from being notified.
+func notifyAll(ctx context.Context, notifiers []Notifier, opportunities []arbitrage.ArbitrageOpportunity) {
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(ctx, opportunities); err != nil {
+			slog.Error("Failed to send alert", "err", err)
+		}
+	}
+}
+
+// postJSON posts payload as JSON to apiURL and checks the response status.
+// service names the receiving end in errors.
+func postJSON(ctx context.Context, service, apiURL string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error encoding %s message: %v", service, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating %s request: %v", service, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", arbitrage.RequestHeaders.Get("User-Agent"))
+	resp, err := arbitrage.HTTPClient.Do(req)
+	if err != nil {
+		// Bot tokens and webhook URLs are secrets, so report only the
+		// underlying error rather than the URL.
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("error sending %s message: %v", service, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading %s response: %v", service, err)
+	}
+	return arbitrage.CheckStatus(service, resp, respBody)
+}
+
+// formatAlertMessage summarises opportunities as plain text for chat
+// notifiers, listing up to alertMaxLines of them.
+func formatAlertMessage(opportunities []arbitrage.ArbitrageOpportunity) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d arbitrage opportunities found\n", len(opportunities))
+	for i, opportunity := range opportunities {
+		if i == alertMaxLines {
+			fmt.Fprintf(&b, "...and %d more\n", len(opportunities)-alertMaxLines)
+			break
+		}
+		fmt.Fprintf(&b, "%s: buy %s at %s, sell %s at %s, %s%%\n",
+			opportunity.Symbol,
+			opportunity.BuyExchange, formatPrice(opportunity.BuyPrice, opportunity.BuyTickSize),
+			opportunity.SellExchange, formatPrice(opportunity.SellPrice, opportunity.SellTickSize),
+			formatPercent(opportunity.ProfitPercentage))
+		if opportunity.Stale {
+			b.WriteString("  (potentially stale)\n")
+		}
+		if opportunity.SellQuote != "" {
+			fmt.Fprintf(&b, "  (cross-quote %s/%s, stablecoin risk)\n", opportunity.Quote, opportunity.SellQuote)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+type fakeNotifier struct {
+	err   error
+	calls int
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, opportunities []arbitrage.ArbitrageOpportunity) error {
+	f.calls++
+	return f.err
+}
+
+func TestNotifyAllContinuesAfterFailure(t *testing.T) {
+	failing := &fakeNotifier{err: errors.New("unreachable")}
+	working := &fakeNotifier{}
+	notifyAll(context.Background(), []Notifier{failing, working}, nil)
+	if failing.calls != 1 || working.calls != 1 {
+		t.Errorf("calls = %d, %d, want 1, 1", failing.calls, working.calls)
+	}
+}
+
+func TestWebhookNotifiers(t *testing.T) {
+	opportunities := []arbitrage.ArbitrageOpportunity{{
+		Symbol:           "BTC/USDT",
+		BuyExchange:      "A",
+		SellExchange:     "B",
+		BuyPrice:         mustDecimal(t, "100"),
+		SellPrice:        mustDecimal(t, "102"),
+		ProfitPercentage: mustDecimal(t, "2"),
+	}}
+
+	var got map[string]json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with Content-Type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		got[r.URL.Path] = body
+	}))
+	defer server.Close()
+
+	notifiers := []Notifier{
+		&webhookNotifier{url: server.URL + "/webhook"},
+		&slackNotifier{url: server.URL + "/slack"},
+		&discordNotifier{url: server.URL + "/discord"},
+	}
+	got = make(map[string]json.RawMessage)
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(context.Background(), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(got) != 0 {
+		t.Errorf("sent %d messages for a cycle without opportunities, want none", len(got))
+	}
+
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(context.Background(), opportunities); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var posted jsonOutput
+	if err := json.Unmarshal(got["/webhook"], &posted); err != nil {
+		t.Fatalf("webhook payload %s: %v", got["/webhook"], err)
+	}
+	if posted.Version != jsonOutputVersion || len(posted.Opportunities) != 1 || posted.Opportunities[0].Symbol != "BTC/USDT" {
+		t.Errorf("webhook posted %+v, want the BTC/USDT opportunity", posted)
+	}
+	for path, key := range map[string]string{"/slack": "text", "/discord": "content"} {
+		var message map[string]string
+		if err := json.Unmarshal(got[path], &message); err != nil {
+			t.Fatalf("%s payload %s: %v", path, got[path], err)
+		}
+		if !strings.Contains(message[key], "BTC/USDT: buy A at 100") {
+			t.Errorf("%s %s = %q, want the opportunity summary", path, key, message[key])
+		}
+	}
+}
+
+func TestPostJSONReportsStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	if err := postJSON(context.Background(), "Slack", server.URL, map[string]string{"text": "hi"}); err == nil {
+		t.Error("expected an error for a 403 response")
+	}
+}
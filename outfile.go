@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultOutBuffer is how many bytes of output are held before writing to
+// -out-file.
+const defaultOutBuffer = 64 << 10
+
+// outRotation bounds how much of a run one output file holds. A zero size
+// or age leaves that bound unset.
+type outRotation struct {
+	size int64
+	age  time.Duration
+}
+
+// parseOutRotation parses -out-rotate: a duration such as 24h rotates by
+// time, and a size in bytes, optionally with a K, M or G suffix in powers of
+// 1024 such as 100MB, rotates by size.
+func parseOutRotation(value string) (outRotation, error) {
+	if value == "" {
+		return outRotation{}, nil
+	}
+	if age, err := time.ParseDuration(value); err == nil {
+		if age <= 0 {
+			return outRotation{}, fmt.Errorf("-out-rotate must be positive")
+		}
+		return outRotation{age: age}, nil
+	}
+	number := strings.TrimSuffix(strings.ToUpper(value), "B")
+	multiplier := int64(1)
+	for suffix, unit := range map[string]int64{"K": 1 << 10, "M": 1 << 20, "G": 1 << 30} {
+		if strings.HasSuffix(number, suffix) {
+			number, multiplier = strings.TrimSuffix(number, suffix), unit
+			break
+		}
+	}
+	size, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || size <= 0 {
+		return outRotation{}, fmt.Errorf("-out-rotate must be a duration such as 24h or a size such as 100MB, got %q", value)
+	}
+	return outRotation{size: size * multiplier}, nil
+}
+
+// outputFile is the -out-file sink. Writes are buffered and only reach the
+// file when the buffer fills or at the end of each cycle, and once the file
+// reaches the rotation size or age it is renamed aside with the time of
+// rotation and a new one started. Rotation only happens between cycles, so
+// every file holds whole cycles.
+type outputFile struct {
+	path     string
+	rotation outRotation
+	// now returns the current time. Tests replace it.
+	now func() time.Time
+
+	file     *os.File
+	buf      *bufio.Writer
+	size     int64
+	openedAt time.Time
+}
+
+// createOutputFile creates, or truncates, the file at path. bufferSize is the
+// number of bytes held before writing to the file; 0 writes every print
+// straight through.
+func createOutputFile(path string, bufferSize int, rotation outRotation) (*outputFile, error) {
+	f := &outputFile{path: path, rotation: rotation, now: time.Now}
+	if bufferSize > 0 {
+		f.buf = bufio.NewWriterSize(nil, bufferSize)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *outputFile) open() error {
+	file, err := os.Create(f.path)
+	if err != nil {
+		return fmt.Errorf("error creating output file: %v", err)
+	}
+	f.file, f.size, f.openedAt = file, 0, f.now()
+	if f.buf != nil {
+		f.buf.Reset(file)
+	}
+	return nil
+}
+
+func (f *outputFile) writer() io.Writer {
+	if f.buf != nil {
+		return f.buf
+	}
+	return f.file
+}
+
+func (f *outputFile) Write(p []byte) (int, error) {
+	n, err := f.writer().Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// endCycle flushes what the cycle printed to the file and rotates it if it
+// is due. It reports whether a new file was started, so output that begins
+// with a header can write it again.
+func (f *outputFile) endCycle() (bool, error) {
+	if f.buf != nil {
+		if err := f.buf.Flush(); err != nil {
+			return false, fmt.Errorf("error writing output file: %v", err)
+		}
+	}
+	due := (f.rotation.size > 0 && f.size >= f.rotation.size) ||
+		(f.rotation.age > 0 && f.now().Sub(f.openedAt) >= f.rotation.age)
+	if !due || f.size == 0 {
+		return false, nil
+	}
+	if err := f.file.Close(); err != nil {
+		return false, fmt.Errorf("error closing output file: %v", err)
+	}
+	if err := os.Rename(f.path, f.rotatedPath()); err != nil {
+		return false, fmt.Errorf("error rotating output file: %v", err)
+	}
+	return true, f.open()
+}
+
+// rotatedPath names the file a full output file is renamed to: its path
+// with the UTC time of rotation before the extension, and a counter if a
+// file of that name already exists.
+func (f *outputFile) rotatedPath() string {
+	ext := filepath.Ext(f.path)
+	stem := strings.TrimSuffix(f.path, ext) + "-" + f.now().UTC().Format("20060102T150405Z")
+	path := stem + ext
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = fmt.Sprintf("%s-%d%s", stem, i, ext)
+	}
+}
+
+// Close flushes any buffered output and closes the file.
+func (f *outputFile) Close() error {
+	if f.buf != nil {
+		if err := f.buf.Flush(); err != nil {
+			f.file.Close()
+			return fmt.Errorf("error writing output file: %v", err)
+		}
+	}
+	return f.file.Close()
+}
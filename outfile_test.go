@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseOutRotation(t *testing.T) {
+	tests := []struct {
+		value string
+		want  outRotation
+	}{
+		{"24h", outRotation{age: 24 * time.Hour}},
+		{"100MB", outRotation{size: 100 << 20}},
+		{"5k", outRotation{size: 5 << 10}},
+		{"1G", outRotation{size: 1 << 30}},
+		{"512", outRotation{size: 512}},
+	}
+	for _, tt := range tests {
+		got, err := parseOutRotation(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("parseOutRotation(%q) = %+v, %v; want %+v", tt.value, got, err, tt.want)
+		}
+	}
+	for _, value := range []string{"0", "-1h", "lots", "10TB"} {
+		if _, err := parseOutRotation(value); err == nil {
+			t.Errorf("parseOutRotation(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestOutputFileRotatesBetweenCycles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.csv")
+	f, err := createOutputFile(path, 1024, outRotation{size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return at }
+
+	f.Write([]byte("header\n"))
+	if data, _ := ioutil.ReadFile(path); len(data) != 0 {
+		t.Errorf("output reached the file before the cycle ended: %q", data)
+	}
+	if rotated, err := f.endCycle(); err != nil || rotated {
+		t.Fatalf("endCycle under the size = %v, %v", rotated, err)
+	}
+	// The cycle that passes the size finishes in the same file.
+	f.Write([]byte("row 1\n"))
+	if rotated, err := f.endCycle(); err != nil || !rotated {
+		t.Fatalf("endCycle over the size = %v, %v", rotated, err)
+	}
+	f.Write([]byte("header\nrow 2\n"))
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"out-20240101T000000Z.csv": "header\nrow 1\n",
+		"out.csv":                  "header\nrow 2\n",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+	"github.com/shopspring/decimal"
+)
+
+// lowPriceThreshold is the price below which text output also shows the
+// profit in basis points, since a percentage with two decimals hides most of
+// the difference between rounding noise and a real spread at that scale.
+var lowPriceThreshold = decimal.NewFromFloat(0.001)
+
+// jsonOutputVersion is the version of the document -output json writes. It
+// is bumped whenever a field is removed, renamed or changes type; adding a
+// field doesn't change it.
+const jsonOutputVersion = 1
+
+// jsonOutput is the document -output json writes for every cycle.
+// GeneratedAt is the time the prices were fetched. BasisTrades are only
+// found with -funding.
+type jsonOutput struct {
+	Version       int                              `json:"version"`
+	GeneratedAt   time.Time                        `json:"generated_at"`
+	Opportunities []arbitrage.ArbitrageOpportunity `json:"opportunities"`
+	BasisTrades   []arbitrage.BasisOpportunity     `json:"basis_trades,omitempty"`
+}
+
+func newJSONOutput(opportunities []arbitrage.ArbitrageOpportunity, basisTrades []arbitrage.BasisOpportunity, generatedAt time.Time) jsonOutput {
+	if opportunities == nil {
+		opportunities = []arbitrage.ArbitrageOpportunity{}
+	}
+	return jsonOutput{
+		Version:       jsonOutputVersion,
+		GeneratedAt:   generatedAt.UTC(),
+		Opportunities: opportunities,
+		BasisTrades:   basisTrades,
+	}
+}
+
+// printOpportunitiesJSON writes the opportunities and basis trades to w as
+// one jsonOutput document.
+func printOpportunitiesJSON(w io.Writer, opportunities []arbitrage.ArbitrageOpportunity, basisTrades []arbitrage.BasisOpportunity, fetchedAt time.Time) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(newJSONOutput(opportunities, basisTrades, fetchedAt)); err != nil {
+		return fmt.Errorf("error encoding opportunities: %v", err)
+	}
+	return nil
+}
+
+func printOpportunities(w io.Writer, opportunities []arbitrage.ArbitrageOpportunity) {
+	for _, opportunity := range opportunities {
+		fmt.Fprintf(w, "Arbitrage opportunity found for %s:\n", opportunity.Symbol)
+		fmt.Fprintf(w, "  Buy from %s at %s\n", opportunity.BuyExchange, formatPrice(opportunity.BuyPrice, opportunity.BuyTickSize))
+		fmt.Fprintf(w, "  Sell on %s at %s\n", opportunity.SellExchange, formatPrice(opportunity.SellPrice, opportunity.SellTickSize))
+		if opportunity.BuyPrice.LessThan(lowPriceThreshold) {
+			fmt.Fprintf(w, "  Profit percentage: %s%% (%s bps)\n", formatPercent(opportunity.ProfitPercentage), opportunity.ProfitBps.StringFixed(1))
+		} else {
+			fmt.Fprintf(w, "  Profit percentage: %s%%\n", formatPercent(opportunity.ProfitPercentage))
+		}
+		fmt.Fprintf(w, "  Gross profit before fees: %s%%\n", formatPercent(opportunity.GrossProfitPercentage))
+		if opportunity.RawAsk != nil && opportunity.RawBid != nil {
+			fmt.Fprintf(w, "  Smoothed prices; last quoted ask %s, bid %s before fees\n",
+				formatPrice(*opportunity.RawAsk, opportunity.BuyTickSize), formatPrice(*opportunity.RawBid, opportunity.SellTickSize))
+		}
+		fmt.Fprintf(w, "  Spread: %s %s per %s\n", formatPrice(opportunity.Spread, finerTick(opportunity.BuyTickSize, opportunity.SellTickSize)),
+			opportunity.Quote, opportunity.Base)
+		if opportunity.Amount.IsPositive() {
+			fmt.Fprintf(w, "  With %s: buy %s, sell for %s, net profit %s\n",
+				opportunity.Amount.String(), opportunity.BaseQuantity.String(),
+				opportunity.Proceeds.Truncate(8).String(), opportunity.NetProfit.Truncate(8).String())
+		}
+		fmt.Fprintf(w, "  Round trip: 1 %s ends as %s %s (%s%%)\n", opportunity.Quote,
+			opportunity.RoundTrip.StringFixed(percentPrecision+2), opportunity.Quote, formatPercent(opportunity.RoundTripPct))
+		if opportunity.TransferCostUnknown {
+			fmt.Fprintf(w, "  Transfer cost unknown: no withdrawal fee data for %s or %s\n", opportunity.Base, opportunity.Quote)
+		} else if opportunity.TransferCost.IsPositive() {
+			fmt.Fprintf(w, "  Includes %s %s of withdrawal fees\n", opportunity.TransferCost.Truncate(8).String(), opportunity.Quote)
+		}
+		if opportunity.SellQuote != "" {
+			fmt.Fprintf(w, "  Cross-quote: bought in %s, sold in %s; carries stablecoin risk\n", opportunity.Quote, opportunity.SellQuote)
+		}
+		if opportunity.MakerLeg != "" {
+			fmt.Fprintf(w, "  Maker fees on the %s leg(s): limit orders are not guaranteed to fill\n", opportunity.MakerLeg)
+		}
+		if opportunity.Stale {
+			fmt.Fprintf(w, "  Potentially stale: the prices were taken %s apart\n", time.Duration(opportunity.TimestampSkew))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// csvHeader is the first row written by printOpportunitiesCSV.
+var csvHeader = []string{"symbol", "buy_exchange", "sell_exchange", "buy_price", "sell_price", "profit_pct", "timestamp", "spread", "round_trip", "gross_profit_pct"}
+
+// printOpportunitiesCSV writes the opportunities to w as CSV rows, preceded
+// by csvHeader if header is set. Prices and percentages are written in full
+// precision; timestamp is the time the prices were fetched.
+func printOpportunitiesCSV(w io.Writer, opportunities []arbitrage.ArbitrageOpportunity, fetchedAt time.Time, header bool) error {
+	writer := csv.NewWriter(w)
+	if header {
+		writer.Write(csvHeader)
+	}
+	timestamp := fetchedAt.UTC().Format(time.RFC3339Nano)
+	for _, opportunity := range opportunities {
+		writer.Write([]string{
+			opportunity.Symbol,
+			opportunity.BuyExchange,
+			opportunity.SellExchange,
+			opportunity.BuyPrice.String(),
+			opportunity.SellPrice.String(),
+			opportunity.ProfitPercentage.String(),
+			timestamp,
+			opportunity.Spread.String(),
+			opportunity.RoundTrip.String(),
+			opportunity.GrossProfitPercentage.String(),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing CSV: %v", err)
+	}
+	return nil
+}
+
+// sampleComparisons is how many symbols printSampleComparisons shows.
+const sampleComparisons = 20
+
+// printSampleComparisons prints a few symbols listed on more than one
+// exchange side by side for debugging when no opportunities were found,
+// each with its best fee-adjusted spread to show how close it came to the
+// threshold.
+func printSampleComparisons(w io.Writer, pairs map[string]map[string]arbitrage.ExchangePrice, fees map[string]arbitrage.ExchangeFees) {
+	names := make([]string, 0, len(pairs))
+	for name := range pairs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	count := 0
+	for _, first := range names {
+		symbols := make([]string, 0, len(pairs[first]))
+		for symbol := range pairs[first] {
+			symbols = append(symbols, symbol)
+		}
+		sort.Strings(symbols)
+
+		for _, symbol := range symbols {
+			var listed []string
+			for _, name := range names {
+				if _, exists := pairs[name][symbol]; exists {
+					listed = append(listed, name)
+				}
+			}
+			// Only print each symbol once, from the first exchange listing it.
+			if len(listed) < 2 || listed[0] != first {
+				continue
+			}
+			fmt.Fprintf(w, "Sample comparison for %s:\n", symbol)
+			for _, name := range listed {
+				price := pairs[name][symbol]
+				fmt.Fprintf(w, "  %s - Bid: %s, Ask: %s\n", name, formatPrice(price.BidPrice, price.TickSize), formatPrice(price.AskPrice, price.TickSize))
+			}
+			if buyExchange, sellExchange, profit, ok := arbitrage.BestSpread(symbol, listed, pairs, fees); ok {
+				fmt.Fprintf(w, "  Best spread: buy %s, sell %s, profit %s%%\n",
+					buyExchange, sellExchange, profit.Mul(decimal.NewFromInt(100)).StringFixed(4))
+			}
+			count++
+			if count >= sampleComparisons {
+				return
+			}
+		}
+	}
+}
+
+// defaultNearMissBand is how many percentage points below the minimum
+// profit a spread may be to be printed as a near miss.
+const defaultNearMissBand = 0.5
+
+// nearMissMax caps how many near misses printNearMisses shows.
+const nearMissMax = 20
+
+// printNearMisses prints the spreads that fell just short of the minimum
+// profit with the round-trip fee that would have made each break even at
+// the threshold, to show what a lower fee tier or maker orders would unlock.
+func printNearMisses(w io.Writer, misses []arbitrage.NearMiss) {
+	if len(misses) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Near misses: %d\n", len(misses))
+	for i, miss := range misses {
+		if i == nearMissMax {
+			fmt.Fprintf(w, "  ...and %d more\n", len(misses)-nearMissMax)
+			break
+		}
+		fmt.Fprintf(w, "  %s: buy %s, sell %s, profit %s%%, ", miss.Symbol, miss.BuyExchange, miss.SellExchange, formatPercent(miss.ProfitPercentage))
+		if miss.BreakEvenFee.IsPositive() {
+			fmt.Fprintf(w, "round-trip fee %s%% would need to be at most %s%%\n",
+				miss.RoundTripFee.StringFixed(4), miss.BreakEvenFee.StringFixed(4))
+		} else {
+			fmt.Fprintln(w, "the spread is below the threshold even without fees")
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+func printBasisTrades(w io.Writer, trades []arbitrage.BasisOpportunity) {
+	if len(trades) == 0 {
+		fmt.Fprintln(w, "No basis trades found")
+		return
+	}
+	for _, trade := range trades {
+		fmt.Fprintf(w, "Basis trade found for %s:\n", trade.Symbol)
+		fmt.Fprintf(w, "  Buy spot on %s at %s\n", trade.SpotExchange, trade.SpotPrice.StringFixed(pricePrecision))
+		fmt.Fprintf(w, "  Short %s on %s at %s\n", trade.PerpSymbol, trade.PerpExchange, trade.PerpPrice.StringFixed(pricePrecision))
+		fmt.Fprintf(w, "  Entry basis: %s%%\n", formatPercent(trade.BasisPercentage))
+		fmt.Fprintf(w, "  Funding: %s%% every %s, %s%% annualized\n",
+			trade.FundingRatePercentage.StringFixed(4), time.Duration(trade.FundingInterval), formatPercent(trade.AnnualizedFunding))
+		if !trade.NextFundingTime.IsZero() {
+			fmt.Fprintf(w, "  Next funding at %s\n", trade.NextFundingTime.UTC().Format(time.RFC3339))
+		}
+		fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultPricePrecision   = 8
+	defaultPercentPrecision = 2
+)
+
+// Display precision of prices and profit percentages in text output and
+// alerts, set by -precision and -percent-precision. JSON and CSV output keep
+// full precision.
+var (
+	pricePrecision   int32 = defaultPricePrecision
+	percentPrecision int32 = defaultPercentPrecision
+)
+
+// formatPrice renders price with as many decimals as tickSize, the way the
+// exchange quotes the market, or with pricePrecision decimals when the tick
+// size is unknown.
+func formatPrice(price, tickSize decimal.Decimal) string {
+	return price.StringFixed(tickPlaces(tickSize))
+}
+
+// formatPercent renders a profit percentage with percentPrecision decimals.
+func formatPercent(percent decimal.Decimal) string {
+	return percent.StringFixed(percentPrecision)
+}
+
+// tickPlaces is the number of decimals in tickSize, e.g. 2 for 0.01, or
+// pricePrecision when tickSize is zero.
+func tickPlaces(tickSize decimal.Decimal) int32 {
+	if !tickSize.IsPositive() {
+		return pricePrecision
+	}
+	// String drops trailing zeros, so "0.0100" counts as 2 decimals.
+	s := tickSize.String()
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return int32(len(s) - i - 1)
+	}
+	return 0
+}
+
+// finerTick returns the smaller of two tick sizes, or zero if either is
+// unknown, for values such as a spread that combine prices of two markets.
+func finerTick(a, b decimal.Decimal) decimal.Decimal {
+	if !a.IsPositive() || !b.IsPositive() {
+		return decimal.Zero
+	}
+	return decimal.Min(a, b)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+func TestFormatPrice(t *testing.T) {
+	tests := []struct {
+		price, tickSize string
+		want            string
+	}{
+		{"60000.5", "0.01000000", "60000.50"},
+		{"60000.55", "0.1", "60000.6"},
+		{"60000.5", "1", "60001"},
+		{"0.000001234", "0", "0.00000123"},
+		{"0.000001234", "0.000000001", "0.000001234"},
+	}
+	for _, tt := range tests {
+		if got := formatPrice(mustDecimal(t, tt.price), mustDecimal(t, tt.tickSize)); got != tt.want {
+			t.Errorf("formatPrice(%s, %s) = %s, want %s", tt.price, tt.tickSize, got, tt.want)
+		}
+	}
+}
+
+func TestFinerTick(t *testing.T) {
+	if got := finerTick(mustDecimal(t, "0.01"), mustDecimal(t, "0.001")); !got.Equal(mustDecimal(t, "0.001")) {
+		t.Errorf("finerTick(0.01, 0.001) = %s", got)
+	}
+	if got := finerTick(mustDecimal(t, "0.01"), mustDecimal(t, "0")); !got.IsZero() {
+		t.Errorf("finerTick with an unknown tick = %s, want 0", got)
+	}
+}
+
+func TestPrintOpportunitiesPrecision(t *testing.T) {
+	oldPrice, oldPercent := pricePrecision, percentPrecision
+	defer func() { pricePrecision, percentPrecision = oldPrice, oldPercent }()
+	pricePrecision, percentPrecision = 3, 4
+
+	opportunity := arbitrage.ArbitrageOpportunity{
+		Symbol: "BTC/USDT", Base: "BTC", Quote: "USDT", BuyExchange: "A", SellExchange: "B",
+		BuyPrice: mustDecimal(t, "60000.12345"), SellPrice: mustDecimal(t, "60600.6789"),
+		BuyTickSize:      mustDecimal(t, "0.01"),
+		ProfitPercentage: mustDecimal(t, "1.0009255"),
+		Spread:           mustDecimal(t, "600.55545"),
+	}
+	var buf bytes.Buffer
+	printOpportunities(&buf, []arbitrage.ArbitrageOpportunity{opportunity})
+	for _, want := range []string{
+		"Buy from A at 60000.12\n",
+		"Sell on B at 60600.679\n",
+		"Profit percentage: 1.0009%\n",
+		"Spread: 600.555 USDT per BTC\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, buf.String())
+		}
+	}
+}
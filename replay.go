@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+// snapshotTimeFormat names snapshot files so that sorting them by name
+// orders them by the time they were taken.
+const snapshotTimeFormat = "20060102T150405.000Z"
+
+// priceSnapshot is the prices fetched from every exchange in one cycle, as
+// written by -record and read by -replay.
+type priceSnapshot struct {
+	TakenAt   time.Time                                     `json:"taken_at"`
+	Exchanges map[string]map[string]arbitrage.ExchangePrice `json:"exchanges"`
+}
+
+// replayExchange serves the prices of one exchange from a snapshot.
+type replayExchange struct {
+	name  string
+	pairs map[string]arbitrage.ExchangePrice
+}
+
+func (e replayExchange) Name() string {
+	return e.name
+}
+
+func (e replayExchange) Pairs(ctx context.Context) (map[string]arbitrage.ExchangePrice, error) {
+	// The comparison filters the map it is given, so hand out a copy.
+	pairs := make(map[string]arbitrage.ExchangePrice, len(e.pairs))
+	for key, price := range e.pairs {
+		pairs[key] = price
+	}
+	return pairs, nil
+}
+
+func recordSnapshot(dir string, takenAt time.Time, exchanges []arbitrage.Exchange, pairs []map[string]arbitrage.ExchangePrice) error {
+	snapshot := priceSnapshot{
+		TakenAt:   takenAt.UTC(),
+		Exchanges: make(map[string]map[string]arbitrage.ExchangePrice, len(exchanges)),
+	}
+	for i, exchange := range exchanges {
+		snapshot.Exchanges[exchange.Name()] = pairs[i]
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("error marshalling snapshot: %v", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating snapshot directory: %v", err)
+	}
+	path := filepath.Join(dir, snapshot.TakenAt.Format(snapshotTimeFormat)+".json")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing snapshot: %v", err)
+	}
+	return nil
+}
+
+func loadSnapshot(path string) (priceSnapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return priceSnapshot{}, fmt.Errorf("error reading snapshot: %v", err)
+	}
+
+	var snapshot priceSnapshot
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return priceSnapshot{}, fmt.Errorf("error unmarshalling snapshot %s: %v", path, err)
+	}
+
+	// Key by the canonical symbol regardless of how the file was written,
+	// so hand-edited snapshots compare the same way live data does.
+	for name, prices := range snapshot.Exchanges {
+		pairs := make(map[string]arbitrage.ExchangePrice, len(prices))
+		for _, price := range prices {
+			price.Base = arbitrage.CanonicalAsset(price.Base)
+			price.Quote = arbitrage.CanonicalAsset(price.Quote)
+			pairs[arbitrage.CanonicalSymbol(price.Base, price.Quote)] = price
+		}
+		snapshot.Exchanges[name] = pairs
+	}
+	return snapshot, nil
+}
+
+// replay runs one comparison cycle for every snapshot in dir, oldest first,
+// in place of fetching from the exchanges. Only exchanges that are enabled
+// and present in a snapshot are compared.
+func (s *scanner) replay(ctx context.Context, dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("error listing snapshots: %v", err)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no snapshots found in %s", dir)
+	}
+	sort.Strings(paths)
+
+	if s.cfg.TradeSize > 0 {
+		slog.Warn("Order books are not recorded, so -trade-size drops every opportunity when replaying")
+	}
+
+	if s.cfg.Confirm {
+		slog.Warn("Snapshots can't be re-fetched, so -confirm drops every opportunity when replaying")
+	}
+
+	if s.cfg.Funding {
+		slog.Warn("Funding rates are not recorded, so -funding finds no basis trades when replaying")
+	}
+
+	live := s.exchanges
+	defer func() {
+		s.exchanges = live
+		s.clock = nil
+	}()
+
+	total := 0
+	for _, path := range paths {
+		snapshot, err := loadSnapshot(path)
+		if err != nil {
+			return err
+		}
+
+		s.exchanges = nil
+		for _, exchange := range live {
+			if pairs, ok := snapshot.Exchanges[exchange.Name()]; ok {
+				s.exchanges = append(s.exchanges, replayExchange{name: exchange.Name(), pairs: pairs})
+			}
+		}
+		takenAt := snapshot.TakenAt
+		s.clock = func() time.Time { return takenAt }
+
+		slog.Info("Replaying snapshot", "file", filepath.Base(path), "taken_at", takenAt.Format(time.RFC3339))
+		opportunities, err := s.runCycle(ctx)
+		if err != nil {
+			return err
+		}
+		total += len(opportunities)
+	}
+
+	slog.Info("Replay finished", "snapshots", len(paths), "opportunities", total)
+	if s.stats != nil {
+		// The stats span the recorded session rather than the replay.
+		s.printStats(s.clock())
+	}
+	return nil
+}
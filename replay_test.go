@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+func TestRecordAndLoadSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	takenAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	exchanges := []arbitrage.Exchange{
+		replayExchange{name: arbitrage.ExchangeBybit},
+		replayExchange{name: arbitrage.ExchangeBinance},
+	}
+	pairs := []map[string]arbitrage.ExchangePrice{
+		{"BTC/USDT": {Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, "50000"), AskPrice: mustDecimal(t, "50001")}},
+		{"BTC/USDT": {Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT", BidPrice: mustDecimal(t, "50500"), AskPrice: mustDecimal(t, "50501")}},
+	}
+	if err := recordSnapshot(dir, takenAt, exchanges, pairs); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := loadSnapshot(dir + "/20240301T120000.000Z.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !snapshot.TakenAt.Equal(takenAt) {
+		t.Errorf("taken at = %s, want %s", snapshot.TakenAt, takenAt)
+	}
+	assertPrice(t, snapshot.Exchanges[arbitrage.ExchangeBybit], "BTC/USDT", "BTCUSDT", "50000", "50001")
+	assertPrice(t, snapshot.Exchanges[arbitrage.ExchangeBinance], "BTC/USDT", "BTCUSDT", "50500", "50501")
+}
+
+func TestReplayWithoutSnapshots(t *testing.T) {
+	s := &scanner{cfg: defaultConfig()}
+	if err := s.replay(context.Background(), t.TempDir()); err == nil {
+		t.Fatal("expected an error for a directory without snapshots")
+	}
+}
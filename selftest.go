@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+// selfTestSamples is how many symbols -selftest prints the prices of.
+const selfTestSamples = 2
+
+// runSelfTest fetches every exchange once and prints how many pairs each
+// returned, how many symbols they have in common and a few sample prices.
+// It reports whether the pipeline looks healthy: every exchange returned at
+// least minPairs pairs, some symbols are listed on more than one of them, and
+// nothing was logged at warning level or above while fetching, which covers
+// unparseable prices, undecodable records and retried requests.
+func runSelfTest(ctx context.Context, w io.Writer, exchanges []arbitrage.Exchange, minPairs int) bool {
+	var warnings atomic.Int64
+	defer func(old *slog.Logger) { slog.SetDefault(old) }(slog.Default())
+	slog.SetDefault(slog.New(warningCounter{Handler: slog.Default().Handler(), count: &warnings}))
+
+	var wg sync.WaitGroup
+	pairs := make([]map[string]arbitrage.ExchangePrice, len(exchanges))
+	errs := make([]error, len(exchanges))
+	durations := make([]time.Duration, len(exchanges))
+	for i, exchange := range exchanges {
+		wg.Add(1)
+		go func(i int, exchange arbitrage.Exchange) {
+			defer wg.Done()
+			start := time.Now()
+			pairs[i], errs[i] = exchange.Pairs(ctx)
+			durations[i] = time.Since(start)
+		}(i, exchange)
+	}
+	wg.Wait()
+
+	var problems []string
+	fmt.Fprintf(w, "Self-test of %d exchanges:\n", len(exchanges))
+	listings := make(map[string][]int)
+	for i, exchange := range exchanges {
+		name := exchange.Name()
+		if errs[i] != nil {
+			fmt.Fprintf(w, "  %s: failed after %s: %v\n", name, durations[i].Round(time.Millisecond), errs[i])
+			problems = append(problems, name+" failed")
+			continue
+		}
+		fmt.Fprintf(w, "  %s: %d pairs in %s\n", name, len(pairs[i]), durations[i].Round(time.Millisecond))
+		if len(pairs[i]) == 0 || len(pairs[i]) < minPairs {
+			problems = append(problems, fmt.Sprintf("%s returned %d pairs, fewer than the expected %d", name, len(pairs[i]), minPairs))
+		}
+		for symbol := range pairs[i] {
+			listings[symbol] = append(listings[symbol], i)
+		}
+	}
+
+	var shared []string
+	onAll := 0
+	for symbol, listed := range listings {
+		if len(listed) > 1 {
+			shared = append(shared, symbol)
+		}
+		if len(listed) == len(exchanges) {
+			onAll++
+		}
+	}
+	fmt.Fprintf(w, "Symbols listed on at least two exchanges: %d, on all %d: %d\n", len(shared), len(exchanges), onAll)
+	if len(shared) == 0 {
+		problems = append(problems, "no symbol is listed on more than one exchange")
+	}
+
+	// The most widely listed symbols make the best samples, since they can
+	// be checked against each other.
+	sort.Slice(shared, func(a, b int) bool {
+		if len(listings[shared[a]]) != len(listings[shared[b]]) {
+			return len(listings[shared[a]]) > len(listings[shared[b]])
+		}
+		return shared[a] < shared[b]
+	})
+	if len(shared) > selfTestSamples {
+		shared = shared[:selfTestSamples]
+	}
+	for _, symbol := range shared {
+		fmt.Fprintf(w, "Sample prices for %s:\n", symbol)
+		for _, i := range listings[symbol] {
+			price := pairs[i][symbol]
+			fmt.Fprintf(w, "  %s %s - Bid: %s, Ask: %s\n", exchanges[i].Name(), price.Symbol,
+				formatPrice(price.BidPrice, price.TickSize), formatPrice(price.AskPrice, price.TickSize))
+		}
+	}
+
+	if count := warnings.Load(); count > 0 {
+		problems = append(problems, fmt.Sprintf("%d warnings were logged while fetching", count))
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(w, "Self-test failed:\n")
+		for _, problem := range problems {
+			fmt.Fprintf(w, "  %s\n", problem)
+		}
+		return false
+	}
+	fmt.Fprintln(w, "Self-test passed")
+	return true
+}
+
+// warningCounter counts the records logged at warning level or above,
+// including any the wrapped handler's level leaves out, and passes the rest
+// through.
+type warningCounter struct {
+	slog.Handler
+	count *atomic.Int64
+}
+
+func (h warningCounter) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelWarn || h.Handler.Enabled(ctx, level)
+}
+
+func (h warningCounter) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level >= slog.LevelWarn {
+		h.count.Add(1)
+	}
+	if !h.Handler.Enabled(ctx, record.Level) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h warningCounter) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return warningCounter{Handler: h.Handler.WithAttrs(attrs), count: h.count}
+}
+
+func (h warningCounter) WithGroup(name string) slog.Handler {
+	return warningCounter{Handler: h.Handler.WithGroup(name), count: h.count}
+}
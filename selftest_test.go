@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+type failingExchange struct{ name string }
+
+func (e failingExchange) Name() string {
+	return e.name
+}
+
+func (e failingExchange) Pairs(ctx context.Context) (map[string]arbitrage.ExchangePrice, error) {
+	return nil, errors.New("connection refused")
+}
+
+// warningExchange logs a warning while fetching, as an exchange does when
+// many of its tickers fail to parse.
+type warningExchange struct{ replayExchange }
+
+func (e warningExchange) Pairs(ctx context.Context) (map[string]arbitrage.ExchangePrice, error) {
+	slog.Warn("Many tickers had unparseable prices; the API format may have changed", "exchange", e.name)
+	return e.replayExchange.Pairs(ctx)
+}
+
+func TestRunSelfTest(t *testing.T) {
+	defer func(old *slog.Logger) { slog.SetDefault(old) }(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError})))
+
+	price := func(symbol, bid, ask string) arbitrage.ExchangePrice {
+		return arbitrage.ExchangePrice{Symbol: symbol, BidPrice: mustDecimal(t, bid), AskPrice: mustDecimal(t, ask), TickSize: mustDecimal(t, "0.01")}
+	}
+	a := replayExchange{name: "A", pairs: map[string]arbitrage.ExchangePrice{
+		"BTC/USDT": price("BTCUSDT", "60000", "60000.01"),
+		"ETH/USDT": price("ETHUSDT", "3000", "3000.01"),
+	}}
+	b := replayExchange{name: "B", pairs: map[string]arbitrage.ExchangePrice{
+		"BTC/USDT": price("BTC-USDT", "60001", "60001.5"),
+		"SOL/USDT": price("SOL-USDT", "150", "150.01"),
+	}}
+
+	var out bytes.Buffer
+	if !runSelfTest(context.Background(), &out, []arbitrage.Exchange{a, b}, 2) {
+		t.Fatalf("healthy exchanges failed the self-test:\n%s", out.String())
+	}
+	for _, want := range []string{
+		"  A: 2 pairs in ",
+		"Symbols listed on at least two exchanges: 1, on all 2: 1\n",
+		"Sample prices for BTC/USDT:\n  A BTCUSDT - Bid: 60000.00, Ask: 60000.01\n  B BTC-USDT - Bid: 60001.00, Ask: 60001.50\n",
+		"Self-test passed\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output doesn't contain %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	c := replayExchange{name: "C", pairs: map[string]arbitrage.ExchangePrice{"XRP/USDT": price("XRPUSDT", "0.5", "0.51")}}
+	if runSelfTest(context.Background(), &out, []arbitrage.Exchange{warningExchange{a}, c, failingExchange{"D"}}, 2) {
+		t.Fatalf("broken exchanges passed the self-test:\n%s", out.String())
+	}
+	for _, want := range []string{
+		"  D: failed after ",
+		"  D failed\n",
+		"  C returned 1 pairs, fewer than the expected 2\n",
+		"  no symbol is listed on more than one exchange\n",
+		"  1 warnings were logged while fetching\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output doesn't contain %q:\n%s", want, out.String())
+		}
+	}
+}
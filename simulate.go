@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+	"github.com/shopspring/decimal"
+)
+
+// simulatedTrade is one line of the -simulate-trade ledger: buying Quantity
+// of Base for Amount of Quote on BuyExchange and selling it on SellExchange
+// at the same moment. Ask and Bid are the quoted prices, BuyPrice and
+// SellPrice what the legs cost and earned per unit after fees and any
+// modelled slippage, and BuyFee and SellFee the difference in quote
+// currency. CumulativePnL is the sum of every PnL in Quote in the ledger so
+// far, including this one.
+type simulatedTrade struct {
+	Time          time.Time       `json:"time"`
+	Symbol        string          `json:"symbol"`
+	Base          string          `json:"base"`
+	Quote         string          `json:"quote"`
+	BuyExchange   string          `json:"buy_exchange"`
+	SellExchange  string          `json:"sell_exchange"`
+	Amount        decimal.Decimal `json:"amount"`
+	Quantity      decimal.Decimal `json:"quantity"`
+	Ask           decimal.Decimal `json:"ask"`
+	BuyPrice      decimal.Decimal `json:"buy_price"`
+	BuyFee        decimal.Decimal `json:"buy_fee"`
+	Bid           decimal.Decimal `json:"bid"`
+	SellPrice     decimal.Decimal `json:"sell_price"`
+	SellFee       decimal.Decimal `json:"sell_fee"`
+	TransferCost  decimal.Decimal `json:"transfer_cost"`
+	Proceeds      decimal.Decimal `json:"proceeds"`
+	PnL           decimal.Decimal `json:"pnl"`
+	CumulativePnL decimal.Decimal `json:"cumulative_pnl"`
+}
+
+// tradeLedger appends simulated trades to a JSON Lines file. No order is
+// ever placed.
+type tradeLedger struct {
+	file *os.File
+	// pnl is the cumulative PnL per quote currency, carried over from the
+	// trades already in the file.
+	pnl map[string]decimal.Decimal
+}
+
+// openTradeLedger opens, or creates, the ledger at path, and resumes the
+// cumulative PnL from the trades it already holds.
+func openTradeLedger(path string) (*tradeLedger, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening trade ledger: %v", err)
+	}
+	ledger := &tradeLedger{file: file, pnl: make(map[string]decimal.Decimal)}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		var trade simulatedTrade
+		if err := json.Unmarshal(scanner.Bytes(), &trade); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("error reading trade ledger line %d: %v", line, err)
+		}
+		ledger.pnl[trade.Quote] = trade.CumulativePnL
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error reading trade ledger: %v", err)
+	}
+	return ledger, nil
+}
+
+// record simulates every opportunity with a stake, priced from the quotes
+// in pairs, and appends the trades to the ledger.
+func (l *tradeLedger) record(opportunities []arbitrage.ArbitrageOpportunity, pairs map[string]map[string]arbitrage.ExchangePrice, executedAt time.Time) error {
+	encoder := json.NewEncoder(l.file)
+	for _, opportunity := range opportunities {
+		if !opportunity.Amount.IsPositive() {
+			continue
+		}
+		ask := pairs[opportunity.BuyExchange][opportunity.Symbol].AskPrice
+		bid := pairs[opportunity.SellExchange][opportunity.Symbol].BidPrice
+		quantity := opportunity.BaseQuantity
+		l.pnl[opportunity.Quote] = l.pnl[opportunity.Quote].Add(opportunity.NetProfit)
+		trade := simulatedTrade{
+			Time:          executedAt.UTC(),
+			Symbol:        opportunity.Symbol,
+			Base:          opportunity.Base,
+			Quote:         opportunity.Quote,
+			BuyExchange:   opportunity.BuyExchange,
+			SellExchange:  opportunity.SellExchange,
+			Amount:        opportunity.Amount,
+			Quantity:      quantity,
+			Ask:           ask,
+			BuyPrice:      opportunity.BuyPrice,
+			BuyFee:        quantity.Mul(opportunity.BuyPrice.Sub(ask)),
+			Bid:           bid,
+			SellPrice:     opportunity.SellPrice,
+			SellFee:       quantity.Mul(bid.Sub(opportunity.SellPrice)),
+			TransferCost:  opportunity.TransferCost,
+			Proceeds:      opportunity.Proceeds,
+			PnL:           opportunity.NetProfit,
+			CumulativePnL: l.pnl[opportunity.Quote],
+		}
+		if err := encoder.Encode(trade); err != nil {
+			return fmt.Errorf("error writing trade ledger: %v", err)
+		}
+	}
+	return nil
+}
+
+// Close closes the ledger file. It is safe to call on a nil ledger.
+func (l *tradeLedger) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+func TestTradeLedger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	pairs := map[string]map[string]arbitrage.ExchangePrice{
+		"Binance": {"BTC/USDT": {AskPrice: mustDecimal(t, "100")}},
+		"Kraken":  {"BTC/USDT": {BidPrice: mustDecimal(t, "103")}},
+	}
+	opportunity := arbitrage.ApplyAmount(arbitrage.ArbitrageOpportunity{
+		Symbol: "BTC/USDT", Base: "BTC", Quote: "USDT", BuyExchange: "Binance", SellExchange: "Kraken",
+		BuyPrice: mustDecimal(t, "100.1"), SellPrice: mustDecimal(t, "102.897"),
+	}, mustDecimal(t, "1001"))
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	ledger, err := openTradeLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Opportunities without a stake are not traded.
+	unstaked := opportunity
+	unstaked.Amount = mustDecimal(t, "0")
+	if err := ledger.record([]arbitrage.ArbitrageOpportunity{opportunity, unstaked}, pairs, at); err != nil {
+		t.Fatal(err)
+	}
+	ledger.Close()
+
+	// Reopening resumes the cumulative PnL.
+	ledger, err = openTradeLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.record([]arbitrage.ArbitrageOpportunity{opportunity}, pairs, at.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	ledger.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("ledger has %d trades, want 2:\n%s", len(lines), data)
+	}
+	var first, second simulatedTrade
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatal(err)
+	}
+	// 10 BTC bought at 100.1 and sold at 102.897.
+	if !first.Quantity.Equal(mustDecimal(t, "10")) || !first.PnL.Equal(mustDecimal(t, "27.97")) {
+		t.Errorf("first trade: quantity %s, PnL %s; want 10 and 27.97", first.Quantity, first.PnL)
+	}
+	if !first.BuyFee.Equal(mustDecimal(t, "1")) || !first.SellFee.Equal(mustDecimal(t, "1.03")) {
+		t.Errorf("fees: buy %s, sell %s; want 1 and 1.03", first.BuyFee, first.SellFee)
+	}
+	if !second.CumulativePnL.Equal(mustDecimal(t, "55.94")) {
+		t.Errorf("cumulative PnL = %s, want 55.94", second.CumulativePnL)
+	}
+	if !second.Time.Equal(at.Add(time.Minute)) {
+		t.Errorf("second trade at %s", second.Time)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+	"github.com/shopspring/decimal"
+)
+
+// statsMaxSymbols caps how many symbols printSessionStats lists.
+const statsMaxSymbols = 20
+
+// statsLatencySamples is how many of each exchange's most recent fetch
+// durations the latency percentiles are computed from, so a long session
+// doesn't grow without bound.
+const statsLatencySamples = 1000
+
+// sessionStats accumulates the opportunities of every cycle since the
+// program started, to show which spreads recur rather than which happen to
+// be open right now.
+type sessionStats struct {
+	// start is when the first cycle's prices were taken.
+	start  time.Time
+	cycles int
+	total  int
+	// lastPrinted is when the stats were last printed, for
+	// -stats-interval.
+	lastPrinted time.Time
+
+	symbols map[string]*symbolStats
+	// routes counts opportunities per buy and sell exchange.
+	routes map[[2]string]int
+	// latencies records how long each exchange's fetches took.
+	latencies map[string]*latencyStats
+}
+
+// latencyStats is what sessionStats records of one exchange's fetches.
+type latencyStats struct {
+	fetches int
+	// recent holds the durations of up to statsLatencySamples of the most
+	// recent fetches, oldest first.
+	recent []time.Duration
+	max    time.Duration
+}
+
+// symbolStats is what sessionStats records for one symbol.
+type symbolStats struct {
+	count     int
+	profitSum decimal.Decimal
+	maxProfit decimal.Decimal
+}
+
+func newSessionStats() *sessionStats {
+	return &sessionStats{symbols: make(map[string]*symbolStats), routes: make(map[[2]string]int), latencies: make(map[string]*latencyStats)}
+}
+
+// recordFetch adds the duration of one fetch from exchange, whether it
+// succeeded or not.
+func (s *sessionStats) recordFetch(exchange string, duration time.Duration) {
+	latency, ok := s.latencies[exchange]
+	if !ok {
+		latency = &latencyStats{}
+		s.latencies[exchange] = latency
+	}
+	latency.fetches++
+	latency.recent = append(latency.recent, duration)
+	if len(latency.recent) > statsLatencySamples {
+		latency.recent = latency.recent[len(latency.recent)-statsLatencySamples:]
+	}
+	if duration > latency.max {
+		latency.max = duration
+	}
+}
+
+// percentile returns the nearest-rank p-th percentile (0.95 is p95) of
+// the sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// record adds the opportunities of a cycle whose prices were taken at
+// takenAt.
+func (s *sessionStats) record(opportunities []arbitrage.ArbitrageOpportunity, takenAt time.Time) {
+	if s.cycles == 0 {
+		s.start = takenAt
+		s.lastPrinted = takenAt
+	}
+	s.cycles++
+	s.total += len(opportunities)
+	for _, opportunity := range opportunities {
+		symbol, ok := s.symbols[opportunity.Symbol]
+		if !ok {
+			symbol = &symbolStats{maxProfit: opportunity.ProfitPercentage}
+			s.symbols[opportunity.Symbol] = symbol
+		}
+		symbol.count++
+		symbol.profitSum = symbol.profitSum.Add(opportunity.ProfitPercentage)
+		if opportunity.ProfitPercentage.GreaterThan(symbol.maxProfit) {
+			symbol.maxProfit = opportunity.ProfitPercentage
+		}
+		s.routes[[2]string{opportunity.BuyExchange, opportunity.SellExchange}]++
+	}
+}
+
+// printSessionStats writes the session's opportunity count, each
+// exchange's fetch latency, the symbols that had opportunities most often
+// with their average and best profit, and the number of opportunities per
+// pair of exchanges, most frequent first. The latency's p50 and p95 cover
+// the last statsLatencySamples fetches, its maximum the whole session.
+func printSessionStats(w io.Writer, s *sessionStats, now time.Time) {
+	fmt.Fprintf(w, "Session stats: %d opportunities in %d cycles over %s\n", s.total, s.cycles, now.Sub(s.start).Round(time.Second))
+	if len(s.latencies) > 0 {
+		exchanges := make([]string, 0, len(s.latencies))
+		for name := range s.latencies {
+			exchanges = append(exchanges, name)
+		}
+		sort.Strings(exchanges)
+		fmt.Fprintln(w, "  Fetch latency:")
+		for _, name := range exchanges {
+			latency := s.latencies[name]
+			sorted := append([]time.Duration(nil), latency.recent...)
+			sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+			fmt.Fprintf(w, "    %s: p50 %s, p95 %s, max %s over %d fetches\n", name,
+				percentile(sorted, 0.5).Round(time.Millisecond), percentile(sorted, 0.95).Round(time.Millisecond),
+				latency.max.Round(time.Millisecond), latency.fetches)
+		}
+	}
+	if s.total == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(s.symbols))
+	for name := range s.symbols {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if a, b := s.symbols[names[i]].count, s.symbols[names[j]].count; a != b {
+			return a > b
+		}
+		return names[i] < names[j]
+	})
+	fmt.Fprintln(w, "  Most frequent symbols:")
+	for i, name := range names {
+		if i == statsMaxSymbols {
+			fmt.Fprintf(w, "    ...and %d more\n", len(names)-statsMaxSymbols)
+			break
+		}
+		symbol := s.symbols[name]
+		average := symbol.profitSum.DivRound(decimal.NewFromInt(int64(symbol.count)), arbitrage.DivisionPrecision)
+		fmt.Fprintf(w, "    %s: %d, average %s%%, max %s%%\n", name, symbol.count, formatPercent(average), formatPercent(symbol.maxProfit))
+	}
+
+	routes := make([][2]string, 0, len(s.routes))
+	for route := range s.routes {
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if a, b := s.routes[routes[i]], s.routes[routes[j]]; a != b {
+			return a > b
+		}
+		if routes[i][0] != routes[j][0] {
+			return routes[i][0] < routes[j][0]
+		}
+		return routes[i][1] < routes[j][1]
+	})
+	fmt.Fprintln(w, "  Exchange pairs:")
+	for _, route := range routes {
+		fmt.Fprintf(w, "    buy %s, sell %s: %d\n", route[0], route[1], s.routes[route])
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+func TestSessionStats(t *testing.T) {
+	opportunity := func(symbol, buy, sell, profit string) arbitrage.ArbitrageOpportunity {
+		return arbitrage.ArbitrageOpportunity{Symbol: symbol, BuyExchange: buy, SellExchange: sell, ProfitPercentage: mustDecimal(t, profit)}
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	stats := newSessionStats()
+	stats.record([]arbitrage.ArbitrageOpportunity{
+		opportunity("ETH/USDT", "Binance", "Kraken", "1"),
+		opportunity("BTC/USDT", "Bybit", "Kraken", "1.5"),
+	}, start)
+	stats.record(nil, start.Add(time.Minute))
+	stats.record([]arbitrage.ArbitrageOpportunity{opportunity("ETH/USDT", "Binance", "Kraken", "2")}, start.Add(2*time.Minute))
+
+	var b strings.Builder
+	printSessionStats(&b, stats, start.Add(time.Hour))
+	want := "Session stats: 3 opportunities in 3 cycles over 1h0m0s\n" +
+		"  Most frequent symbols:\n" +
+		"    ETH/USDT: 2, average 1.50%, max 2.00%\n" +
+		"    BTC/USDT: 1, average 1.50%, max 1.50%\n" +
+		"  Exchange pairs:\n" +
+		"    buy Binance, sell Kraken: 2\n" +
+		"    buy Bybit, sell Kraken: 1\n"
+	if b.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestSessionStatsFetchLatency(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	stats := newSessionStats()
+	for i := 1; i <= 20; i++ {
+		stats.recordFetch("Kraken", time.Duration(i)*100*time.Millisecond)
+	}
+	stats.recordFetch("Binance", 80*time.Millisecond)
+	stats.record(nil, start)
+
+	var b strings.Builder
+	printSessionStats(&b, stats, start.Add(time.Minute))
+	want := "Session stats: 0 opportunities in 1 cycles over 1m0s\n" +
+		"  Fetch latency:\n" +
+		"    Binance: p50 80ms, p95 80ms, max 80ms over 1 fetches\n" +
+		"    Kraken: p50 1s, p95 1.9s, max 2s over 20 fetches\n"
+	if b.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestSessionStatsKeepsRecentLatencies(t *testing.T) {
+	stats := newSessionStats()
+	stats.recordFetch("Kraken", time.Hour)
+	for i := 0; i < statsLatencySamples; i++ {
+		stats.recordFetch("Kraken", time.Second)
+	}
+	latency := stats.latencies["Kraken"]
+	if len(latency.recent) != statsLatencySamples || latency.recent[0] != time.Second {
+		t.Errorf("kept %d samples starting at %s, want the %d most recent", len(latency.recent), latency.recent[0], statsLatencySamples)
+	}
+	if latency.max != time.Hour || latency.fetches != statsLatencySamples+1 {
+		t.Errorf("max %s over %d fetches, want 1h over %d", latency.max, latency.fetches, statsLatencySamples+1)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+// quoteSummary aggregates the opportunities of one cycle that share a quote
+// currency.
+type quoteSummary struct {
+	Quote string
+	Count int
+	Best  arbitrage.ArbitrageOpportunity
+}
+
+// summarizeByQuote groups opportunities by quote currency, busiest quote
+// first. opportunities must already be sorted so that the first one seen for
+// each quote is its best.
+func summarizeByQuote(opportunities []arbitrage.ArbitrageOpportunity) []quoteSummary {
+	index := make(map[string]int)
+	var summaries []quoteSummary
+	for _, opportunity := range opportunities {
+		i, ok := index[opportunity.Quote]
+		if !ok {
+			i = len(summaries)
+			index[opportunity.Quote] = i
+			summaries = append(summaries, quoteSummary{Quote: opportunity.Quote, Best: opportunity})
+		}
+		summaries[i].Count++
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if summaries[i].Count != summaries[j].Count {
+			return summaries[i].Count > summaries[j].Count
+		}
+		return summaries[i].Quote < summaries[j].Quote
+	})
+	return summaries
+}
+
+// printQuoteSummary writes one line per quote currency with its number of
+// opportunities and the most profitable of them.
+func printQuoteSummary(w io.Writer, summaries []quoteSummary) {
+	total := 0
+	for _, summary := range summaries {
+		total += summary.Count
+	}
+	fmt.Fprintf(w, "Summary: %d opportunities across %d quote currencies\n", total, len(summaries))
+	for _, summary := range summaries {
+		best := summary.Best
+		fmt.Fprintf(w, "  %s: %d, best %s %s%% (buy %s, sell %s)\n", summary.Quote, summary.Count,
+			best.Symbol, formatPercent(best.ProfitPercentage), best.BuyExchange, best.SellExchange)
+	}
+}
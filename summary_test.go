@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+func TestSummarizeByQuote(t *testing.T) {
+	opportunities := []arbitrage.ArbitrageOpportunity{
+		{Symbol: "ETH/BTC", Quote: "BTC", ProfitPercentage: mustDecimal(t, "4")},
+		{Symbol: "SOL/USDT", Quote: "USDT", ProfitPercentage: mustDecimal(t, "3")},
+		{Symbol: "XRP/USDT", Quote: "USDT", ProfitPercentage: mustDecimal(t, "2")},
+		{Symbol: "ADA/USDC", Quote: "USDC", ProfitPercentage: mustDecimal(t, "1.5")},
+		{Symbol: "DOT/USDT", Quote: "USDT", ProfitPercentage: mustDecimal(t, "1")},
+	}
+
+	summaries := summarizeByQuote(opportunities)
+	if len(summaries) != 3 {
+		t.Fatalf("got %d summaries, want 3: %+v", len(summaries), summaries)
+	}
+	want := []struct {
+		quote, best string
+		count       int
+	}{
+		{"USDT", "SOL/USDT", 3},
+		{"BTC", "ETH/BTC", 1},
+		{"USDC", "ADA/USDC", 1},
+	}
+	for i, w := range want {
+		got := summaries[i]
+		if got.Quote != w.quote || got.Count != w.count || got.Best.Symbol != w.best {
+			t.Errorf("summary %d = %s x%d best %s, want %s x%d best %s", i, got.Quote, got.Count, got.Best.Symbol, w.quote, w.count, w.best)
+		}
+	}
+
+	var buf bytes.Buffer
+	printQuoteSummary(&buf, summaries)
+	if !strings.HasPrefix(buf.String(), "Summary: 5 opportunities across 3 quote currencies\n") {
+		t.Errorf("unexpected summary:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "  USDT: 3, best SOL/USDT 3.00%") {
+		t.Errorf("missing USDT line:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+const telegramAPIURL = "https://api.telegram.org"
+
+// telegramNotifier sends one summary message per cycle to a Telegram chat.
+type telegramNotifier struct {
+	token  string
+	chatID string
+}
+
+// Notify sends a summary of opportunities. Nothing is sent when there are
+// none.
+func (t *telegramNotifier) Notify(ctx context.Context, opportunities []arbitrage.ArbitrageOpportunity) error {
+	if len(opportunities) == 0 {
+		return nil
+	}
+	return postJSON(ctx, "Telegram", telegramAPIURL+"/bot"+t.token+"/sendMessage", map[string]string{
+		"chat_id": t.chatID,
+		"text":    formatAlertMessage(opportunities),
+	})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+// templateFuncs are the functions available to -format-template besides the
+// text/template builtins: price rounds a price to a tick size as the default
+// output does, and percent formats a percentage with -percent-precision
+// decimals.
+var templateFuncs = template.FuncMap{
+	"price":   formatPrice,
+	"percent": formatPercent,
+}
+
+// loadFormatTemplate parses the -format-template value, which is either a
+// text/template or the path of a file holding one.
+func loadFormatTemplate(value string) (*template.Template, error) {
+	text := value
+	if info, err := os.Stat(value); err == nil && !info.IsDir() {
+		data, err := ioutil.ReadFile(value)
+		if err != nil {
+			return nil, fmt.Errorf("error reading format template: %v", err)
+		}
+		text = string(data)
+	}
+	tmpl, err := template.New("format").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing format template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// printOpportunitiesTemplate executes tmpl once per opportunity, with the
+// arbitrage.ArbitrageOpportunity as its data. Each opportunity ends on a new
+// line, whether or not the template ends with one.
+func printOpportunitiesTemplate(w io.Writer, tmpl *template.Template, opportunities []arbitrage.ArbitrageOpportunity) error {
+	var buf bytes.Buffer
+	for _, opportunity := range opportunities {
+		buf.Reset()
+		if err := tmpl.Execute(&buf, opportunity); err != nil {
+			return fmt.Errorf("error executing format template for %s: %v", opportunity.Symbol, err)
+		}
+		if !strings.HasSuffix(buf.String(), "\n") {
+			buf.WriteByte('\n')
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
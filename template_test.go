@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"text/template"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+func TestPrintOpportunitiesTemplate(t *testing.T) {
+	tmpl, err := loadFormatTemplate(`{{.Symbol}} {{.BuyExchange}}->{{.SellExchange}} {{price .BuyPrice .BuyTickSize}} {{percent .ProfitPercentage}}%`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opportunities := []arbitrage.ArbitrageOpportunity{
+		{Symbol: "BTCUSDT", BuyExchange: "Binance", SellExchange: "Bybit", BuyPrice: mustDecimal(t, "60000.123"), BuyTickSize: mustDecimal(t, "0.01"), ProfitPercentage: mustDecimal(t, "1.234")},
+		{Symbol: "ETHUSDT", BuyExchange: "Kraken", SellExchange: "OKX", BuyPrice: mustDecimal(t, "3000"), BuyTickSize: mustDecimal(t, "0.1"), ProfitPercentage: mustDecimal(t, "2")},
+	}
+
+	var buf bytes.Buffer
+	if err := printOpportunitiesTemplate(&buf, tmpl, opportunities); err != nil {
+		t.Fatal(err)
+	}
+	want := "BTCUSDT Binance->Bybit 60000.12 1.23%\nETHUSDT Kraken->OKX 3000.0 2.00%\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestLoadFormatTemplateFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "format.tmpl")
+	if err := ioutil.WriteFile(path, []byte("{{.Symbol}}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := loadFormatTemplate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := printOpportunitiesTemplate(&buf, tmpl, []arbitrage.ArbitrageOpportunity{{Symbol: "BTCUSDT"}}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "BTCUSDT\n" {
+		t.Errorf("output = %q, want a single line", buf.String())
+	}
+
+	if _, err := loadFormatTemplate("{{.Symbol"); err == nil {
+		t.Error("expected an error for an unterminated action")
+	}
+	if err := printOpportunitiesTemplate(&buf, mustTemplate(t, "{{.NoSuchField}}"), []arbitrage.ArbitrageOpportunity{{Symbol: "BTCUSDT"}}); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
+
+func mustTemplate(t *testing.T, text string) *template.Template {
+	t.Helper()
+	tmpl, err := loadFormatTemplate(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tmpl
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+	"github.com/rivo/tview"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// tuiMaxRows is how many opportunities the -tui table shows when -top
+	// isn't set.
+	tuiMaxRows = 20
+	// tuiLogLines is how many of the most recent log lines -tui shows below
+	// the table.
+	tuiLogLines = 5
+)
+
+// exchangeFetch is how fetching one exchange went in a cycle.
+type exchangeFetch struct {
+	name     string
+	pairs    int
+	duration time.Duration
+	err      error
+}
+
+// tui redraws a ranked table of the current opportunities every cycle, with
+// each exchange's fetch latency and pair count above it and the latest log
+// lines below. It is a tview application running on its own goroutine from
+// newTUI until close.
+type tui struct {
+	app      *tview.Application
+	title    *tview.TextView
+	fetches  *tview.Table
+	table    *tview.Table
+	footer   *tview.TextView
+	logView  *tview.TextView
+	layout   *tview.Flex
+	interval time.Duration
+	// minProfit is the -min-profit percentage the colors are relative to.
+	minProfit decimal.Decimal
+	rows      int
+	logs      *logTail
+	// done receives what the application's Run returned once it stops.
+	done   chan error
+	closed bool
+}
+
+// newTUI starts drawing on screen. logs is where the program's logs are
+// being written while the TUI is shown. Pressing q, Esc or Ctrl+C calls
+// quit; the terminal no longer turns Ctrl+C into SIGINT while the TUI owns
+// it.
+func newTUI(screen tcell.Screen, cfg Config, logs *logTail, quit func()) *tui {
+	rows := tuiMaxRows
+	if cfg.Top > 0 {
+		rows = cfg.Top
+	}
+	t := &tui{
+		app:       tview.NewApplication().SetScreen(screen),
+		title:     tview.NewTextView().SetDynamicColors(true),
+		fetches:   tview.NewTable(),
+		table:     tview.NewTable().SetFixed(1, 0),
+		footer:    tview.NewTextView(),
+		logView:   tview.NewTextView().SetDynamicColors(true),
+		interval:  time.Duration(cfg.Interval),
+		minProfit: decimal.NewFromFloat(cfg.MinProfit),
+		rows:      rows,
+		logs:      logs,
+		done:      make(chan error, 1),
+	}
+	t.fetches.SetSelectable(false, false)
+	t.table.SetSelectable(false, false)
+	t.layout = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(t.title, 2, 0, false).
+		AddItem(t.fetches, 0, 0, false).
+		AddItem(t.table, 0, 1, true).
+		AddItem(t.footer, 1, 0, false).
+		AddItem(t.logView, tuiLogLines+1, 0, false)
+	t.app.SetRoot(t.layout, true).SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyCtrlC || event.Key() == tcell.KeyEscape || event.Rune() == 'q' {
+			quit()
+			return nil
+		}
+		return event
+	})
+	go func() {
+		err := t.app.Run()
+		if err != nil {
+			// Without a screen there is nothing left to show, so stop
+			// the program rather than keep polling blind.
+			quit()
+		}
+		t.done <- err
+	}()
+	return t
+}
+
+// render replaces the screen with the results of one cycle. opportunities
+// must already be sorted.
+func (t *tui) render(fetches []exchangeFetch, opportunities []arbitrage.ArbitrageOpportunity, fetchedAt time.Time) {
+	if t.closed {
+		return
+	}
+	// QueueUpdateDraw waits for the application's goroutine, which never
+	// comes once Run has returned.
+	drawn := make(chan struct{})
+	go func() {
+		t.app.QueueUpdateDraw(func() { t.update(fetches, opportunities, fetchedAt) })
+		close(drawn)
+	}()
+	select {
+	case <-drawn:
+	case err := <-t.done:
+		t.done <- err
+	}
+}
+
+// update fills the widgets with the results of one cycle. It must run on
+// the application's goroutine.
+func (t *tui) update(fetches []exchangeFetch, opportunities []arbitrage.ArbitrageOpportunity, fetchedAt time.Time) {
+	t.title.SetText(fmt.Sprintf("[::b]Crypto arbitrage[::-]  updated %s, every %s, %d opportunities",
+		fetchedAt.Local().Format("15:04:05"), t.interval, len(opportunities)))
+
+	t.fetches.Clear()
+	for i, fetch := range fetches {
+		status := tview.NewTableCell(fmt.Sprintf("%d pairs", fetch.pairs))
+		if fetch.err != nil {
+			status = tview.NewTableCell("failed").SetTextColor(tcell.ColorRed)
+		}
+		t.fetches.SetCell(i, 0, tview.NewTableCell(fetch.name))
+		t.fetches.SetCell(i, 1, status)
+		t.fetches.SetCell(i, 2, tview.NewTableCell(fetch.duration.Round(time.Millisecond).String()))
+	}
+	// One blank line separates the fetches from the opportunities.
+	t.layout.ResizeItem(t.fetches, len(fetches)+1, 0)
+
+	shown := opportunities
+	if len(shown) > t.rows {
+		shown = shown[:t.rows]
+	}
+	t.table.Clear()
+	for col, heading := range []string{"#", "Symbol", "Buy on", "Ask", "Sell on", "Bid", "Profit %", ""} {
+		t.table.SetCell(0, col, tview.NewTableCell(heading).SetAttributes(tcell.AttrBold))
+	}
+	for i, opportunity := range shown {
+		note := ""
+		if opportunity.Stale {
+			note = "stale"
+		}
+		if opportunity.SellQuote != "" {
+			note = strings.TrimSpace(note + " cross-quote")
+		}
+		color, attributes := profitStyle(opportunity.ProfitPercentage, t.minProfit)
+		for col, text := range []string{strconv.Itoa(i + 1), opportunity.Symbol,
+			opportunity.BuyExchange, formatPrice(opportunity.BuyPrice, opportunity.BuyTickSize),
+			opportunity.SellExchange, formatPrice(opportunity.SellPrice, opportunity.SellTickSize),
+			formatPercent(opportunity.ProfitPercentage), note} {
+			t.table.SetCell(i+1, col, tview.NewTableCell(text).SetTextColor(color).SetAttributes(attributes))
+		}
+	}
+	t.table.ScrollToBeginning()
+	switch hidden := len(opportunities) - len(shown); {
+	case len(opportunities) == 0:
+		t.footer.SetText("  No opportunities this cycle")
+	case hidden > 0:
+		t.footer.SetText(fmt.Sprintf("  ...and %d more", hidden))
+	default:
+		t.footer.SetText("")
+	}
+
+	if t.logs != nil {
+		t.logView.SetText("\n[::d]" + tview.Escape(strings.TrimSuffix(strings.Join(t.logs.lines(), ""), "\n")))
+	}
+}
+
+// close stops the application, which restores the terminal, and sends the
+// logs to stderr again. It is safe to call more than once.
+func (t *tui) close() {
+	if t.closed {
+		return
+	}
+	t.closed = true
+	t.app.Stop()
+	err := <-t.done
+	if t.logs != nil {
+		t.logs.passThrough(os.Stderr)
+	}
+	if err != nil {
+		slog.Error("Terminal dashboard failed", "err", err)
+	}
+}
+
+// profitStyle picks a row's color by how far its profit percentage clears
+// minProfit: yellow below twice the minimum, green below three times and
+// bold green above.
+func profitStyle(profit, minProfit decimal.Decimal) (tcell.Color, tcell.AttrMask) {
+	switch {
+	case profit.GreaterThanOrEqual(minProfit.Mul(decimal.NewFromInt(3))):
+		return tcell.ColorGreen, tcell.AttrBold
+	case profit.GreaterThanOrEqual(minProfit.Mul(decimal.NewFromInt(2))):
+		return tcell.ColorGreen, tcell.AttrNone
+	default:
+		return tcell.ColorYellow, tcell.AttrNone
+	}
+}
+
+// logTail keeps the last few lines written to it, so logs can be shown
+// inside the TUI instead of scrolling it away, until passThrough sends
+// further writes elsewhere.
+type logTail struct {
+	mu   sync.Mutex
+	max  int
+	tail []string
+	out  io.Writer
+}
+
+func newLogTail(max int) *logTail {
+	return &logTail{max: max}
+}
+
+// Write records p, which slog writes one whole line at a time.
+func (l *logTail) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.out != nil {
+		return l.out.Write(p)
+	}
+	l.tail = append(l.tail, string(p))
+	if len(l.tail) > l.max {
+		l.tail = l.tail[len(l.tail)-l.max:]
+	}
+	return len(p), nil
+}
+
+func (l *logTail) lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.tail...)
+}
+
+// passThrough writes the kept lines to w, followed by everything written
+// from now on.
+func (l *logTail) passThrough(w io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range l.tail {
+		io.WriteString(w, line)
+	}
+	l.tail = nil
+	l.out = w
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
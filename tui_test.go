@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+	"github.com/rivo/tview"
+)
+
+func TestTUIRender(t *testing.T) {
+	opportunity := func(symbol, profit string) arbitrage.ArbitrageOpportunity {
+		return arbitrage.ArbitrageOpportunity{Symbol: symbol, BuyExchange: "Binance", SellExchange: "Kraken",
+			BuyPrice: mustDecimal(t, "100"), SellPrice: mustDecimal(t, "103"), ProfitPercentage: mustDecimal(t, profit)}
+	}
+	cfg := defaultConfig()
+	cfg.Interval = arbitrage.Duration(30 * time.Second)
+	cfg.Top = 2
+	logs := newLogTail(tuiLogLines)
+	logs.Write([]byte("level=WARN msg=\"slow exchange\"\n"))
+	screen := tcell.NewSimulationScreen("UTF-8")
+	screen.SetSize(100, 30)
+	quit := make(chan struct{})
+	dashboard := newTUI(screen, cfg, logs, func() { close(quit) })
+	defer dashboard.close()
+
+	dashboard.render([]exchangeFetch{
+		{name: "Binance", pairs: 1200, duration: 312 * time.Millisecond},
+		{name: "OKX", duration: 5 * time.Second, err: errors.New("timeout")},
+	}, []arbitrage.ArbitrageOpportunity{
+		opportunity("BTC/USDT", "3.5"),
+		opportunity("ETH/USDT", "1.2"),
+		opportunity("SOL/USDT", "1.1"),
+	}, time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local))
+
+	got := screenText(screen)
+	for _, want := range []string{
+		"updated 12:00:00, every 30s, 3 opportunities",
+		"Binance 1200 pairs",
+		"OKX     failed",
+		"BTC/USDT",
+		"ETH/USDT",
+		"...and 1 more",
+		"slow exchange",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("screen doesn't contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "SOL/USDT") {
+		t.Errorf("screen shows more than -top rows:\n%s", got)
+	}
+	style := func(table *tview.Table, row int) (tcell.Color, tcell.AttrMask) {
+		fg, _, attributes := table.GetCell(row, 1).Style.Decompose()
+		return fg, attributes
+	}
+	if color, attributes := style(dashboard.table, 1); color != tcell.ColorGreen || attributes&tcell.AttrBold == 0 {
+		t.Errorf("3.5%% row: color %v, attributes %v, want bold green", color, attributes)
+	}
+	if color, _ := style(dashboard.table, 2); color != tcell.ColorYellow {
+		t.Errorf("1.2%% row: color %v, want yellow", color)
+	}
+	if color, _ := style(dashboard.fetches, 1); color != tcell.ColorRed {
+		t.Errorf("failed fetch: color %v, want red", color)
+	}
+
+	screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+	select {
+	case <-quit:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pressing q did not quit")
+	}
+}
+
+// screenText returns what screen shows, one line per row with trailing
+// blanks trimmed.
+func screenText(screen tcell.SimulationScreen) string {
+	cells, width, _ := screen.GetContents()
+	var b strings.Builder
+	for start := 0; start < len(cells); start += width {
+		var line strings.Builder
+		for _, cell := range cells[start : start+width] {
+			if len(cell.Runes) == 0 {
+				line.WriteRune(' ')
+				continue
+			}
+			line.WriteString(string(cell.Runes))
+		}
+		b.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+	}
+	return b.String()
+}
+
+func TestLogTail(t *testing.T) {
+	logs := newLogTail(2)
+	for _, line := range []string{"a\n", "b\n", "c\n"} {
+		logs.Write([]byte(line))
+	}
+	if got := strings.Join(logs.lines(), ""); got != "b\nc\n" {
+		t.Errorf("kept %q, want the last 2 lines", got)
+	}
+
+	var out strings.Builder
+	logs.passThrough(&out)
+	logs.Write([]byte("d\n"))
+	if out.String() != "b\nc\nd\n" || len(logs.lines()) != 0 {
+		t.Errorf("passThrough wrote %q, kept %q", out.String(), logs.lines())
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/mirimadahmed/crypto-arbitrage-golang/arbitrage"
+)
+
+// webhookNotifier posts every cycle's opportunities to a URL in the same
+// versioned document -output json prints, for integrations that do their own
+// formatting.
+type webhookNotifier struct {
+	url string
+}
+
+// Notify posts the opportunities. Nothing is sent when there are none.
+func (w *webhookNotifier) Notify(ctx context.Context, opportunities []arbitrage.ArbitrageOpportunity) error {
+	if len(opportunities) == 0 {
+		return nil
+	}
+	return postJSON(ctx, "webhook", w.url, newJSONOutput(opportunities, nil, time.Now()))
+}
+
+// slackNotifier sends one summary message per cycle to a Slack incoming
+// webhook.
+type slackNotifier struct {
+	url string
+}
+
+// Notify sends a summary of opportunities. Nothing is sent when there are
+// none.
+func (s *slackNotifier) Notify(ctx context.Context, opportunities []arbitrage.ArbitrageOpportunity) error {
+	if len(opportunities) == 0 {
+		return nil
+	}
+	return postJSON(ctx, "Slack", s.url, map[string]string{"text": formatAlertMessage(opportunities)})
+}
+
+// discordNotifier sends one summary message per cycle to a Discord webhook.
+type discordNotifier struct {
+	url string
+}
+
+// Notify sends a summary of opportunities. Nothing is sent when there are
+// none.
+func (d *discordNotifier) Notify(ctx context.Context, opportunities []arbitrage.ArbitrageOpportunity) error {
+	if len(opportunities) == 0 {
+		return nil
+	}
+	return postJSON(ctx, "Discord", d.url, map[string]string{"content": formatAlertMessage(opportunities)})
+}